@@ -95,10 +95,19 @@ func (b Boolean) String() string {
 }
 
 /* =========== FunctionLiteral: fn <parameters> <block statement> =========== */
+// IsGenerator is set when the body yields, calling such a function returns an iterator over the yielded values.
+// Calling an `async fn` returns a promise of its result instead. IsArrow only records that the function was written
+// as `(<parameters>) => <body>`. ParameterTypes is nil unless a parameter is annotated, then it has an entry per
+// parameter, nil for the ones that are not
 type FunctionLiteral struct {
-	Token      token.Token
-	Parameters []*Identifier
-	Body       *BlockStatement
+	Token          token.Token
+	Parameters     []Expression
+	ParameterTypes []*TypeAnnotation
+	ReturnType     *TypeAnnotation
+	Body           *BlockStatement
+	IsGenerator    bool
+	IsAsync        bool
+	IsArrow        bool
 }
 
 func (f FunctionLiteral) expressionNode() {}
@@ -111,17 +120,64 @@ func (f FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	params := []string{}
+	for idx, param := range f.Parameters {
+		if param, ok := param.(*DefaultParameter); ok && f.ParameterType(idx) != nil {
+			params = append(params, annotated(param.Target.String(), f.ParameterType(idx))+" = "+param.Value.String())
+			continue
+		}
 
-	out.WriteString(f.TokenLiteral() + "(" + strings.Join(params, ", ") + ")" + f.Body.String())
+		params = append(params, annotated(param.String(), f.ParameterType(idx)))
+	}
+
+	if f.IsAsync {
+		out.WriteString("async ")
+	}
+
+	out.WriteString(annotated(f.TokenLiteral()+"("+strings.Join(params, ", ")+")", f.ReturnType) + f.Body.String())
 
 	return out.String()
 }
 
+// ParameterType returns the annotation of the parameter at idx, nil when it has none
+func (f FunctionLiteral) ParameterType(idx int) *TypeAnnotation {
+	if idx >= len(f.ParameterTypes) {
+		return nil
+	}
+
+	return f.ParameterTypes[idx]
+}
+
+/* ================== MacroLiteral: macro(<parameters>) { <body> } ================== */
+type MacroLiteral struct {
+	Token      token.Token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (m MacroLiteral) expressionNode() {}
+
+func (m MacroLiteral) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m MacroLiteral) String() string {
+	params := []string{}
+	for _, param := range m.Parameters {
+		params = append(params, param.String())
+	}
+
+	return m.TokenLiteral() + "(" + strings.Join(params, ", ") + ")" + m.Body.String()
+}
+
 /* ============================== LetStatement ============================== */
+// Name is set for plain `let x = ...` bindings, Pattern for destructuring ones. Type is the optional annotation of
+// `let x: <type> = ...`
 type LetStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token
+	Name    *Identifier
+	Pattern Expression
+	Type    *TypeAnnotation
+	Value   Expression
 }
 
 func (l LetStatement) statementNode() {}
@@ -133,11 +189,63 @@ func (l LetStatement) TokenLiteral() string {
 func (l LetStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString(l.TokenLiteral() + " " + l.Name.String() + " = " + l.Value.String() + ";")
+	target := ""
+	if l.Pattern != nil {
+		target = l.Pattern.String()
+	} else {
+		target = l.Name.String()
+	}
+
+	out.WriteString(l.TokenLiteral() + " " + annotated(target, l.Type) + " = " + l.Value.String() + ";")
 
 	return out.String()
 }
 
+/* ========= TypeAnnotation: <name>, <name>[<types>], fn(<types>): <type> ========= */
+// Parameters are the element types of parameterized types such as array[int] and hash[string, int], or the parameter
+// types of a function type, whose last one is `...<type>` when Variadic. Signature is set on function types written
+// with a parameter list, a bare `fn` stands for any function. Return is only set on function types
+type TypeAnnotation struct {
+	Token      token.Token
+	Name       string
+	Parameters []*TypeAnnotation
+	Variadic   bool
+	Signature  bool
+	Return     *TypeAnnotation
+}
+
+func (t TypeAnnotation) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t TypeAnnotation) String() string {
+	params := []string{}
+	for idx, param := range t.Parameters {
+		if t.Variadic && idx == len(t.Parameters)-1 {
+			params = append(params, "..."+param.String())
+		} else {
+			params = append(params, param.String())
+		}
+	}
+
+	switch {
+	case t.Signature:
+		return annotated("fn("+strings.Join(params, ", ")+")", t.Return)
+	case len(params) > 0:
+		return t.Name + "[" + strings.Join(params, ", ") + "]"
+	default:
+		return t.Name
+	}
+}
+
+func annotated(target string, annotation *TypeAnnotation) string {
+	if annotation == nil {
+		return target
+	}
+
+	return target + ": " + annotation.String()
+}
+
 /* ============================= ReturnStatement ============================ */
 type ReturnStatement struct {
 	Token       token.Token
@@ -158,6 +266,117 @@ func (r ReturnStatement) String() string {
 	return out.String()
 }
 
+/* ============================= ThrowStatement ============================= */
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (t ThrowStatement) statementNode() {}
+
+func (t ThrowStatement) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t ThrowStatement) String() string {
+	return t.TokenLiteral() + " " + t.Value.String() + ";"
+}
+
+/* =================== ImportStatement: import "<path>" as <alias> =================== */
+type ImportStatement struct {
+	Token token.Token
+	Path  *StringLiteral
+	Alias *Identifier
+}
+
+func (i ImportStatement) statementNode() {}
+
+func (i ImportStatement) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (i ImportStatement) String() string {
+	return i.TokenLiteral() + " \"" + i.Path.Value + "\" as " + i.Alias.String() + ";"
+}
+
+/* ==================== ExportStatement: export <let statement> ==================== */
+type ExportStatement struct {
+	Token     token.Token
+	Statement *LetStatement
+}
+
+func (e ExportStatement) statementNode() {}
+
+func (e ExportStatement) TokenLiteral() string {
+	return e.Token.Literal
+}
+
+func (e ExportStatement) String() string {
+	return e.TokenLiteral() + " " + e.Statement.String()
+}
+
+/* =============== StructStatement: struct <name> { <fields> } ============== */
+type StructStatement struct {
+	Token  token.Token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (s StructStatement) statementNode() {}
+
+func (s StructStatement) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s StructStatement) String() string {
+	fields := []string{}
+	for _, field := range s.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return s.TokenLiteral() + " " + s.Name.String() + " { " + strings.Join(fields, ", ") + " }"
+}
+
+/* ========== EnumStatement: enum <name> { <variant>(<fields>), ... } ========= */
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (e EnumVariant) String() string {
+	if len(e.Fields) == 0 {
+		return e.Name.String()
+	}
+
+	fields := []string{}
+	for _, field := range e.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return e.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+type EnumStatement struct {
+	Token    token.Token
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+func (e EnumStatement) statementNode() {}
+
+func (e EnumStatement) TokenLiteral() string {
+	return e.Token.Literal
+}
+
+func (e EnumStatement) String() string {
+	variants := []string{}
+	for _, variant := range e.Variants {
+		variants = append(variants, variant.String())
+	}
+
+	return e.TokenLiteral() + " " + e.Name.String() + " { " + strings.Join(variants, ", ") + " }"
+}
+
 /* =========================== ExpressionStatement ========================== */
 type ExpressionStatement struct {
 	Token      token.Token
@@ -179,9 +398,11 @@ func (e ExpressionStatement) String() string {
 }
 
 /* ============================= BlockStatement ============================= */
+// Rbrace is the closing brace, left zero for the bodies of arrow functions and arms that are a single expression
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	Rbrace     token.Token
 }
 
 func (b *BlockStatement) statementNode() {}
@@ -235,6 +456,23 @@ func (i InfixExpression) String() string {
 	return fmt.Sprintf("(%s %s %s)", i.Left.String(), i.Operator, i.Right.String())
 }
 
+/* ================= PipeExpression: <expression> |> <call> ================= */
+type PipeExpression struct {
+	Token token.Token
+	Left  Expression
+	Right Expression
+}
+
+func (p PipeExpression) expressionNode() {}
+
+func (p PipeExpression) TokenLiteral() string {
+	return p.Token.Literal
+}
+
+func (p PipeExpression) String() string {
+	return fmt.Sprintf("(%s |> %s)", p.Left.String(), p.Right.String())
+}
+
 /* ======= IfExpression: if (<cond>) { <IfCond> } else { <ElseCond> } ======= */
 type IfExpression struct {
 	Token         token.Token
@@ -261,6 +499,203 @@ func (i IfExpression) String() string {
 	return out.String()
 }
 
+/* === TryExpression: try { <Block> } catch (<param>) { <Catch> } finally { <Finally> } === */
+type TryExpression struct {
+	Token          token.Token
+	Block          *BlockStatement
+	CatchParameter Expression
+	CatchBlock     *BlockStatement
+	FinallyBlock   *BlockStatement
+}
+
+func (t TryExpression) expressionNode() {}
+
+func (t TryExpression) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try " + t.Block.String())
+
+	if t.CatchBlock != nil {
+		out.WriteString(" catch")
+
+		if t.CatchParameter != nil {
+			out.WriteString("(" + t.CatchParameter.String() + ")")
+		}
+
+		out.WriteString(" " + t.CatchBlock.String())
+	}
+
+	if t.FinallyBlock != nil {
+		out.WriteString(" finally " + t.FinallyBlock.String())
+	}
+
+	return out.String()
+}
+
+/* =========== MatchExpression: match (<subject>) { <pattern> => <body>, ... } ========== */
+type MatchArm struct {
+	Pattern Expression
+	Body    *BlockStatement
+}
+
+func (m MatchArm) String() string {
+	return m.Pattern.String() + " => " + m.Body.String()
+}
+
+type MatchExpression struct {
+	Token   token.Token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (m MatchExpression) expressionNode() {}
+
+func (m MatchExpression) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m MatchExpression) String() string {
+	arms := []string{}
+	for _, arm := range m.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	return "match " + m.Subject.String() + " { " + strings.Join(arms, ", ") + " }"
+}
+
+/* ============= VariantPattern: <variant>(<pattern>, ...) in a match arm ============= */
+// Variant is either an identifier (Ok) or a member expression (Result.Ok)
+type VariantPattern struct {
+	Token   token.Token
+	Variant Expression
+	Fields  []Expression
+}
+
+func (v VariantPattern) expressionNode() {}
+
+func (v VariantPattern) TokenLiteral() string {
+	return v.Token.Literal
+}
+
+func (v VariantPattern) String() string {
+	fields := []string{}
+	for _, field := range v.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return v.Variant.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+/* ========== ForExpression: for (<target> in <iterable>) { <Body> } ========= */
+type ForExpression struct {
+	Token    token.Token
+	Target   Expression
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (f ForExpression) expressionNode() {}
+
+func (f ForExpression) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f ForExpression) String() string {
+	return "for (" + f.Target.String() + " in " + f.Iterable.String() + ") " + f.Body.String()
+}
+
+/* ======================== YieldExpression: yield <value> ======================== */
+type YieldExpression struct {
+	Token token.Token
+	Value Expression
+}
+
+func (y YieldExpression) expressionNode() {}
+
+func (y YieldExpression) TokenLiteral() string {
+	return y.Token.Literal
+}
+
+func (y YieldExpression) String() string {
+	return "(" + y.TokenLiteral() + " " + y.Value.String() + ")"
+}
+
+/* ======================== AwaitExpression: await <value> ======================== */
+type AwaitExpression struct {
+	Token token.Token
+	Value Expression
+}
+
+func (a AwaitExpression) expressionNode() {}
+
+func (a AwaitExpression) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a AwaitExpression) String() string {
+	return "(" + a.TokenLiteral() + " " + a.Value.String() + ")"
+}
+
+/* =================== SpawnExpression: spawn <call expression> =================== */
+type SpawnExpression struct {
+	Token token.Token
+	Call  *CallExpression
+}
+
+func (s SpawnExpression) expressionNode() {}
+
+func (s SpawnExpression) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s SpawnExpression) String() string {
+	return "(" + s.TokenLiteral() + " " + s.Call.String() + ")"
+}
+
+/* ======= SelectExpression: select { recv(<c>) as <x> => <body>, send(<c>, <v>) => <body>, _ => <body> } ======= */
+// Operation is the `recv(...)` or `send(...)` call of the arm, nil for the default arm. Binding is only set on recv arms
+type SelectArm struct {
+	Operation *CallExpression
+	Binding   *Identifier
+	Body      *BlockStatement
+}
+
+func (s SelectArm) String() string {
+	if s.Operation == nil {
+		return "_ => " + s.Body.String()
+	}
+
+	if s.Binding != nil {
+		return s.Operation.String() + " as " + s.Binding.String() + " => " + s.Body.String()
+	}
+
+	return s.Operation.String() + " => " + s.Body.String()
+}
+
+type SelectExpression struct {
+	Token token.Token
+	Arms  []*SelectArm
+}
+
+func (s SelectExpression) expressionNode() {}
+
+func (s SelectExpression) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s SelectExpression) String() string {
+	arms := []string{}
+	for _, arm := range s.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	return "select { " + strings.Join(arms, ", ") + " }"
+}
+
 /* =========== CallExpression: <expression> (<csv of expressions>) ========== */
 type CallExpression struct {
 	Token     token.Token
@@ -346,10 +781,29 @@ func (i IndexExpression) String() string {
 	return out.String()
 }
 
+/* ================ MemberExpression: <expression>.<identifier> ============== */
+type MemberExpression struct {
+	Token    token.Token
+	Ref      Expression
+	Property *Identifier
+}
+
+func (m MemberExpression) expressionNode() {}
+
+func (m MemberExpression) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m MemberExpression) String() string {
+	return "(" + m.Ref.String() + "." + m.Property.String() + ")"
+}
+
 /* ============= HashLiteral: {<expression>: <expression>, ...} ============= */
+// Keys keeps the source order of the pairs and any spread elements, which only appear there and not in Pairs
 type HashLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+	Keys  []Expression
 }
 
 func (h HashLiteral) expressionNode() {}
@@ -362,11 +816,131 @@ func (h HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range h.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range h.Keys {
+		if spread, ok := key.(*SpreadElement); ok {
+			pairs = append(pairs, spread.String())
+		} else {
+			pairs = append(pairs, key.String()+":"+h.Pairs[key].String())
+		}
 	}
 
 	out.WriteString("{" + strings.Join(pairs, ", ") + "}")
 
 	return out.String()
 }
+
+/* ========================= RestElement: ...<target> ======================= */
+type RestElement struct {
+	Token  token.Token
+	Target *Identifier
+}
+
+func (r RestElement) expressionNode() {}
+
+func (r RestElement) TokenLiteral() string {
+	return r.Token.Literal
+}
+
+func (r RestElement) String() string {
+	return "..." + r.Target.String()
+}
+
+/* ====================== SpreadElement: ...<expression> ===================== */
+type SpreadElement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (s SpreadElement) expressionNode() {}
+
+func (s SpreadElement) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s SpreadElement) String() string {
+	return "..." + s.Value.String()
+}
+
+/* ============= DefaultParameter: <target> = <default value> ============== */
+type DefaultParameter struct {
+	Token  token.Token
+	Target Expression
+	Value  Expression
+}
+
+func (d DefaultParameter) expressionNode() {}
+
+func (d DefaultParameter) TokenLiteral() string {
+	return d.Token.Literal
+}
+
+func (d DefaultParameter) String() string {
+	return d.Target.String() + " = " + d.Value.String()
+}
+
+/* ============ ArrayPattern: [<pattern>, <pattern>, ...<target>] =========== */
+type ArrayPattern struct {
+	Token    token.Token
+	Elements []Expression
+	Rest     *RestElement
+}
+
+func (a ArrayPattern) expressionNode() {}
+
+func (a ArrayPattern) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, element := range a.Elements {
+		elements = append(elements, element.String())
+	}
+
+	if a.Rest != nil {
+		elements = append(elements, a.Rest.String())
+	}
+
+	out.WriteString("[" + strings.Join(elements, ", ") + "]")
+	return out.String()
+}
+
+/* ======== HashPattern: {<key>, <key>: <pattern>, ...<target>} ============= */
+type HashPatternProperty struct {
+	Key   *Identifier
+	Value Expression
+}
+
+type HashPattern struct {
+	Token      token.Token
+	Properties []*HashPatternProperty
+	Rest       *RestElement
+}
+
+func (h HashPattern) expressionNode() {}
+
+func (h HashPattern) TokenLiteral() string {
+	return h.Token.Literal
+}
+
+func (h HashPattern) String() string {
+	var out bytes.Buffer
+
+	properties := []string{}
+	for _, property := range h.Properties {
+		if ident, ok := property.Value.(*Identifier); ok && ident.Value == property.Key.Value {
+			properties = append(properties, property.Key.String())
+		} else {
+			properties = append(properties, property.Key.String()+": "+property.Value.String())
+		}
+	}
+
+	if h.Rest != nil {
+		properties = append(properties, h.Rest.String())
+	}
+
+	out.WriteString("{" + strings.Join(properties, ", ") + "}")
+	return out.String()
+}
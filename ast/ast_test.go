@@ -34,3 +34,221 @@ func (t *AstTestSuite) TestString() {
 
 	t.Equal("let myVar = anotherVar;", program.String())
 }
+
+func (t *AstTestSuite) TestModify() {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+	block := func(exp Expression) *BlockStatement {
+		return &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: exp}}}
+	}
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+
+		return &IntegerLiteral{Value: 2}
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}}, &Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}}},
+		{&InfixExpression{Left: one(), Operator: "+", Right: two()}, &InfixExpression{Left: two(), Operator: "+", Right: two()}},
+		{&PrefixExpression{Operator: "-", Right: one()}, &PrefixExpression{Operator: "-", Right: two()}},
+		{&IndexExpression{Ref: one(), Index: one()}, &IndexExpression{Ref: two(), Index: two()}},
+		{&IfExpression{Condition: one(), IfCondition: block(one()), ElseCondition: block(one())}, &IfExpression{Condition: two(), IfCondition: block(two()), ElseCondition: block(two())}},
+		{&ReturnStatement{ReturnValue: one()}, &ReturnStatement{ReturnValue: two()}},
+		{&LetStatement{Value: one()}, &LetStatement{Value: two()}},
+		{&FunctionLiteral{Parameters: []Expression{}, Body: block(one())}, &FunctionLiteral{Parameters: []Expression{}, Body: block(two())}},
+		{&ArrayLiteral{Elements: []Expression{one(), one()}}, &ArrayLiteral{Elements: []Expression{two(), two()}}},
+		{&CallExpression{Function: one(), Arguments: []Expression{one()}}, &CallExpression{Function: two(), Arguments: []Expression{two()}}},
+		{&MatchExpression{Subject: one(), Arms: []*MatchArm{{Pattern: one(), Body: block(one())}}}, &MatchExpression{Subject: two(), Arms: []*MatchArm{{Pattern: two(), Body: block(two())}}}},
+		{&ForExpression{Target: one(), Iterable: one(), Body: block(one())}, &ForExpression{Target: two(), Iterable: two(), Body: block(two())}},
+		{&TryExpression{Block: block(one()), CatchBlock: block(one())}, &TryExpression{Block: block(two()), CatchBlock: block(two())}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, Modify(test.input, turnOneIntoTwo))
+	}
+
+	// Hash literals keep their pairs keyed by the modified keys
+	key := one()
+	hash := &HashLiteral{Keys: []Expression{key}, Pairs: map[Expression]Expression{key: one()}}
+	modified := Modify(hash, turnOneIntoTwo).(*HashLiteral)
+
+	t.Equal(two(), modified.Keys[0])
+	t.Equal(two(), modified.Pairs[modified.Keys[0]])
+
+	// The input tree is left untouched
+	t.Equal(one(), hash.Keys[0])
+	t.Equal(one(), hash.Pairs[key])
+}
+
+func (t *AstTestSuite) TestModifyIgnoresMistypedReplacements() {
+	name := &Identifier{Value: "x"}
+	let := &LetStatement{Name: name, Value: &Identifier{Value: "x"}}
+
+	modified := Modify(let, func(node Node) Node {
+		if identifier, ok := node.(*Identifier); ok && identifier.Value == "x" {
+			return &IntegerLiteral{Value: 1}
+		}
+
+		return node
+	}).(*LetStatement)
+
+	t.Same(name, modified.Name)
+	t.Equal(&IntegerLiteral{Value: 1}, modified.Value)
+}
+
+func (t *AstTestSuite) TestInspect() {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	block := func(exp Expression) *BlockStatement {
+		return &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: exp}}}
+	}
+
+	key := one()
+	shorthand := &Identifier{Value: "a"}
+
+	tests := []struct {
+		input    Node
+		expected int
+	}{
+		{&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}, &ReturnStatement{ReturnValue: one()}}}, 2},
+		{&LetStatement{Name: &Identifier{Value: "x"}, Value: one()}, 1},
+		{&InfixExpression{Left: one(), Operator: "+", Right: one()}, 2},
+		{&PipeExpression{Left: one(), Right: one()}, 2},
+		{&IfExpression{Condition: one(), IfCondition: block(one())}, 2},
+		{&TryExpression{Block: block(one()), CatchBlock: block(one()), FinallyBlock: block(one())}, 3},
+		{&MatchExpression{Subject: one(), Arms: []*MatchArm{{Pattern: one(), Body: block(one())}}}, 3},
+		{&VariantPattern{Variant: &Identifier{Value: "Ok"}, Fields: []Expression{one()}}, 1},
+		{&ForExpression{Target: &Identifier{Value: "x"}, Iterable: one(), Body: block(one())}, 2},
+		{&YieldExpression{Value: one()}, 1},
+		{&AwaitExpression{Value: one()}, 1},
+		{&SpawnExpression{Call: &CallExpression{Function: one(), Arguments: []Expression{one()}}}, 2},
+		{&SelectExpression{Arms: []*SelectArm{{Body: block(one())}}}, 1},
+		{&FunctionLiteral{Parameters: []Expression{&DefaultParameter{Target: &Identifier{Value: "a"}, Value: one()}}, Body: block(one())}, 2},
+		{&ArrayLiteral{Elements: []Expression{one(), &SpreadElement{Value: one()}}}, 2},
+		{&IndexExpression{Ref: one(), Index: one()}, 2},
+		{&MemberExpression{Ref: one(), Property: &Identifier{Value: "p"}}, 1},
+		{&HashLiteral{Keys: []Expression{key}, Pairs: map[Expression]Expression{key: one()}}, 2},
+		{&ArrayPattern{Elements: []Expression{&DefaultParameter{Target: &Identifier{Value: "a"}, Value: one()}}}, 1},
+		{&ThrowStatement{Value: one()}, 1},
+	}
+
+	for _, test := range tests {
+		count := 0
+		Inspect(test.input, func(node Node) bool {
+			if _, ok := node.(*IntegerLiteral); ok {
+				count += 1
+			}
+
+			return true
+		})
+
+		t.Equal(test.expected, count, test.input.String())
+	}
+
+	// Identifiers in source order, shorthand hash pattern properties are only visited once
+	pattern := &HashPattern{Properties: []*HashPatternProperty{{Key: shorthand, Value: shorthand}}}
+	let := &LetStatement{Pattern: pattern, Value: &CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{&Identifier{Value: "b"}}}}
+
+	names := []string{}
+	nils := 0
+	Inspect(let, func(node Node) bool {
+		if node == nil {
+			nils += 1
+		}
+
+		if identifier, ok := node.(*Identifier); ok {
+			names = append(names, identifier.Value)
+		}
+
+		return true
+	})
+
+	t.Equal([]string{"a", "f", "b"}, names)
+	t.Equal(6, nils)
+
+	// Returning false skips the children
+	count := 0
+	Inspect(block(&InfixExpression{Left: one(), Right: one()}), func(node Node) bool {
+		if node != nil {
+			count += 1
+		}
+
+		_, isInfix := node.(*InfixExpression)
+		return !isInfix
+	})
+
+	t.Equal(3, count)
+}
+
+type countingVisitor struct {
+	counts map[string]int
+}
+
+func (v countingVisitor) Visit(node Node) Visitor {
+	if node != nil {
+		v.counts[node.TokenLiteral()] += 1
+	}
+
+	return v
+}
+
+func (t *AstTestSuite) TestWalk() {
+	tok := func(literal string) token.Token { return token.Token{Literal: literal} }
+
+	program := &Program{Statements: []Statement{
+		&LetStatement{
+			Token: tok("let"),
+			Name:  &Identifier{Token: tok("x"), Value: "x"},
+			Value: &InfixExpression{
+				Token: tok("+"),
+				Left:  &IntegerLiteral{Token: tok("1"), Value: 1},
+				Right: &IntegerLiteral{Token: tok("1"), Value: 1},
+			},
+		},
+	}}
+
+	visitor := countingVisitor{counts: map[string]int{}}
+	Walk(visitor, program)
+
+	// The program itself has no token literal of its own, it reports the one of its first statement
+	t.Equal(map[string]int{"let": 2, "x": 1, "+": 1, "1": 2}, visitor.counts)
+}
+
+func (t *AstTestSuite) TestRewrite() {
+	x := func() Expression { return &Identifier{Value: "x"} }
+
+	program := &Program{Statements: []Statement{
+		&ExpressionStatement{Expression: &InfixExpression{Left: x(), Operator: "+", Right: x()}},
+		&ExpressionStatement{Expression: &FunctionLiteral{
+			Parameters: []Expression{x()},
+			Body:       &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: x()}}},
+		}},
+		&ThrowStatement{Value: x()},
+	}}
+
+	rewritten := Rewrite(program, func(node Node) (Node, bool) {
+		switch node := node.(type) {
+		case *Identifier:
+			if node.Value == "x" {
+				return &Identifier{Value: "y"}, false
+			}
+		case *FunctionLiteral:
+			// Functions bind their own x
+			return node, false
+		case *ThrowStatement:
+			return nil, false
+		}
+
+		return node, true
+	})
+
+	t.Equal("(y + y)(x)x", rewritten.String())
+	t.Equal("(x + x)(x)x x;", program.String())
+}
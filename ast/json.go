@@ -0,0 +1,380 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"fungo/token"
+	"reflect"
+	"unicode"
+)
+
+// The JSON form of a tree is stable: every node is an object whose "kind" is the name of its type, followed by its
+// fields in declaration order, named in lower camel case. Tokens carry the positions, absent nodes are null. The pairs
+// of a hash literal are listed in source order as {"key": ..., "value": ...}, spread elements have no value
+
+// The types the JSON form of a tree may hold, by kind
+var kinds = map[string]reflect.Type{}
+
+func init() {
+	nodes := []interface{}{
+		&Program{}, &Identifier{}, &IntegerLiteral{}, &Boolean{}, &FunctionLiteral{}, &MacroLiteral{},
+		&LetStatement{}, &TypeAnnotation{}, &ReturnStatement{}, &ThrowStatement{}, &ImportStatement{},
+		&ExportStatement{}, &StructStatement{}, &EnumVariant{}, &EnumStatement{}, &ExpressionStatement{},
+		&BlockStatement{}, &PrefixExpression{}, &InfixExpression{}, &PipeExpression{}, &IfExpression{},
+		&TryExpression{}, &MatchArm{}, &MatchExpression{}, &VariantPattern{}, &ForExpression{}, &YieldExpression{},
+		&AwaitExpression{}, &SpawnExpression{}, &SelectArm{}, &SelectExpression{}, &CallExpression{},
+		&StringLiteral{}, &ArrayLiteral{}, &IndexExpression{}, &MemberExpression{}, &HashLiteral{}, &RestElement{},
+		&SpreadElement{}, &DefaultParameter{}, &ArrayPattern{}, &HashPatternProperty{}, &HashPattern{},
+	}
+
+	for _, node := range nodes {
+		kind := reflect.TypeOf(node).Elem()
+		kinds[kind.Name()] = kind
+	}
+}
+
+var tokenType = reflect.TypeOf(token.Token{})
+
+// The children a node may go without, by kind and field. The others must be present, and so must the elements of the
+// lists of nodes. A let statement binds either a name or a pattern
+var optional = map[string]bool{
+	"FunctionLiteral.parameterTypes": true,
+	"FunctionLiteral.returnType":     true,
+	"LetStatement.name":              true,
+	"LetStatement.pattern":           true,
+	"LetStatement.type":              true,
+	"TypeAnnotation.return":          true,
+	"ImportStatement.alias":          true,
+	"IfExpression.elseCondition":     true,
+	"TryExpression.catchParameter":   true,
+	"TryExpression.catchBlock":       true,
+	"TryExpression.finallyBlock":     true,
+	"SelectArm.operation":            true,
+	"SelectArm.binding":              true,
+	"ArrayPattern.rest":              true,
+	"HashPattern.rest":               true,
+}
+
+// Encodes a tree as JSON, in the form described at the top of this file
+func EncodeJSON(node Node) ([]byte, error) {
+	out := &bytes.Buffer{}
+
+	if err := encodeValue(out, reflect.ValueOf(node)); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func encodeValue(out *bytes.Buffer, value reflect.Value) error {
+	if !value.IsValid() {
+		out.WriteString("null")
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if value.IsNil() {
+			out.WriteString("null")
+			return nil
+		}
+
+		return encodeValue(out, value.Elem())
+
+	case reflect.Slice:
+		if value.IsNil() {
+			out.WriteString("null")
+			return nil
+		}
+
+		out.WriteString("[")
+		for idx := 0; idx < value.Len(); idx++ {
+			if idx > 0 {
+				out.WriteString(",")
+			}
+
+			if err := encodeValue(out, value.Index(idx)); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+
+		return nil
+
+	case reflect.Struct:
+		if value.Type() != tokenType {
+			return encodeNode(out, value)
+		}
+	}
+
+	encoded, err := json.Marshal(value.Interface())
+	if err != nil {
+		return err
+	}
+
+	out.Write(encoded)
+
+	return nil
+}
+
+func encodeNode(out *bytes.Buffer, value reflect.Value) error {
+	kind := value.Type()
+	if kinds[kind.Name()] != kind {
+		return fmt.Errorf("cannot encode %s as JSON", kind)
+	}
+
+	fmt.Fprintf(out, "{\"kind\":%q", kind.Name())
+
+	for idx := 0; idx < kind.NumField(); idx++ {
+		field := kind.Field(idx)
+		if field.Name == "Keys" && kind.Name() == "HashLiteral" {
+			continue
+		}
+
+		fmt.Fprintf(out, ",%q:", fieldName(field.Name))
+
+		if field.Name == "Pairs" && kind.Name() == "HashLiteral" {
+			if err := encodePairs(out, value.Addr().Interface().(*HashLiteral)); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := encodeValue(out, value.Field(idx)); err != nil {
+			return err
+		}
+	}
+
+	out.WriteString("}")
+
+	return nil
+}
+
+func encodePairs(out *bytes.Buffer, hash *HashLiteral) error {
+	out.WriteString("[")
+
+	for idx, key := range hash.Keys {
+		if idx > 0 {
+			out.WriteString(",")
+		}
+
+		out.WriteString("{\"key\":")
+		if err := encodeValue(out, reflect.ValueOf(key)); err != nil {
+			return err
+		}
+
+		out.WriteString(",\"value\":")
+		if err := encodeValue(out, reflect.ValueOf(hash.Pairs[key])); err != nil {
+			return err
+		}
+
+		out.WriteString("}")
+	}
+
+	out.WriteString("]")
+
+	return nil
+}
+
+// ReturnValue is written returnValue
+func fieldName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+
+	return string(runes)
+}
+
+// Decodes the JSON form of a tree back into its nodes. Trees missing a child their nodes cannot go without are
+// rejected, they could not be printed nor evaluated
+func DecodeJSON(data []byte) (Node, error) {
+	var node Node
+
+	if err := decodeValue(data, reflect.ValueOf(&node).Elem()); err != nil {
+		return nil, err
+	}
+
+	if node == nil {
+		return nil, fmt.Errorf("expected a node, got null")
+	}
+
+	return node, nil
+}
+
+// Decodes the JSON form of a program, see DecodeJSON
+func DecodeProgramJSON(data []byte) (*Program, error) {
+	program := &Program{}
+
+	if err := decodeValue(data, reflect.ValueOf(&program).Elem()); err != nil {
+		return nil, err
+	}
+
+	if program == nil {
+		return nil, fmt.Errorf("expected a Program, got null")
+	}
+
+	return program, nil
+}
+
+func decodeValue(data json.RawMessage, target reflect.Value) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		var object struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+
+		kind, ok := kinds[object.Kind]
+		if !ok {
+			return fmt.Errorf("unknown node kind %q", object.Kind)
+		}
+
+		node := reflect.New(kind)
+		if !node.Type().AssignableTo(target.Type()) {
+			return fmt.Errorf("a %s cannot be used as %s", object.Kind, target.Type())
+		}
+
+		if err := decodeNode(data, node); err != nil {
+			return err
+		}
+
+		target.Set(node)
+
+		return nil
+
+	case reflect.Slice:
+		elements := []json.RawMessage{}
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return err
+		}
+
+		slice := reflect.MakeSlice(target.Type(), len(elements), len(elements))
+		for idx, element := range elements {
+			if err := decodeValue(element, slice.Index(idx)); err != nil {
+				return err
+			}
+		}
+
+		target.Set(slice)
+
+		return nil
+	}
+
+	return json.Unmarshal(data, target.Addr().Interface())
+}
+
+func decodeNode(data json.RawMessage, node reflect.Value) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	value := node.Elem()
+	kind := value.Type()
+
+	for idx := 0; idx < kind.NumField(); idx++ {
+		name := kind.Field(idx).Name
+		if name == "Keys" && kind.Name() == "HashLiteral" {
+			continue
+		}
+
+		raw, ok := fields[fieldName(name)]
+		if !ok {
+			raw = json.RawMessage("null")
+		}
+
+		if name == "Pairs" && kind.Name() == "HashLiteral" {
+			if err := decodePairs(raw, node.Interface().(*HashLiteral)); err != nil {
+				return fmt.Errorf("%s.%s: %w", kind.Name(), fieldName(name), err)
+			}
+
+			continue
+		}
+
+		if err := decodeValue(raw, value.Field(idx)); err != nil {
+			return fmt.Errorf("%s.%s: %w", kind.Name(), fieldName(name), err)
+		}
+
+		if !optional[kind.Name()+"."+fieldName(name)] {
+			if err := checkPresent(value.Field(idx)); err != nil {
+				return fmt.Errorf("%s.%s: %w", kind.Name(), fieldName(name), err)
+			}
+		}
+	}
+
+	if let, ok := node.Interface().(*LetStatement); ok && let.Name == nil && let.Pattern == nil {
+		return fmt.Errorf("LetStatement: expected a name or a pattern, got neither")
+	}
+
+	return nil
+}
+
+// Nodes, and the nodes of lists, cannot be null. Lists themselves may be, the parser leaves those without elements so
+func checkPresent(field reflect.Value) error {
+	switch field.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if field.IsNil() {
+			return fmt.Errorf("expected a node, got null")
+		}
+
+	case reflect.Slice:
+		for idx := 0; idx < field.Len(); idx++ {
+			if err := checkPresent(field.Index(idx)); err != nil {
+				return fmt.Errorf("%d: %w", idx, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func decodePairs(data json.RawMessage, hash *HashLiteral) error {
+	pairs := []struct {
+		Key   json.RawMessage `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}{}
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return err
+	}
+
+	hash.Pairs = map[Expression]Expression{}
+	hash.Keys = nil
+
+	for idx, pair := range pairs {
+		var key, value Expression
+
+		if err := decodeValue(pair.Key, reflect.ValueOf(&key).Elem()); err != nil {
+			return err
+		}
+
+		if key == nil {
+			return fmt.Errorf("%d: expected a key, got null", idx)
+		}
+
+		if len(pair.Value) > 0 {
+			if err := decodeValue(pair.Value, reflect.ValueOf(&value).Elem()); err != nil {
+				return err
+			}
+		}
+
+		// Only spread elements go without a value
+		if _, spread := key.(*SpreadElement); value == nil && !spread {
+			return fmt.Errorf("%d: expected a value, got null", idx)
+		}
+
+		hash.Keys = append(hash.Keys, key)
+		if value != nil {
+			hash.Pairs[key] = value
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,333 @@
+package ast
+
+// ModifierFunc returns the node to use in place of the one it is given, which can be the node itself
+type ModifierFunc func(Node) Node
+
+// Modify rewrites a tree bottom up: the children of a node are modified first, then the modifier is called on the
+// node holding them. The tree passed in is left untouched, nodes are copied before their children are replaced.
+// Replacements of a type a field cannot hold, like an integer in place of a parameter name, are ignored
+func Modify(node Node, modifier ModifierFunc) Node {
+	return modifier(rewriteChildren(node, func(child Node) Node {
+		return Modify(child, modifier)
+	}))
+}
+
+// Returns a copy of node with each of its children replaced by the result of rewrite
+func rewriteChildren(node Node, rewrite func(Node) Node) Node {
+	switch node := node.(type) {
+	case *Program:
+		clone := *node
+		clone.Statements = rewriteStatements(node.Statements, rewrite)
+		return &clone
+
+	case *ExpressionStatement:
+		clone := *node
+		clone.Expression = rewriteExpression(node.Expression, rewrite)
+		return &clone
+
+	case *BlockStatement:
+		clone := *node
+		clone.Statements = rewriteStatements(node.Statements, rewrite)
+		return &clone
+
+	case *LetStatement:
+		clone := *node
+		clone.Name = rewriteAs(node.Name, rewrite)
+		clone.Pattern = rewriteExpression(node.Pattern, rewrite)
+		clone.Value = rewriteExpression(node.Value, rewrite)
+		return &clone
+
+	case *ReturnStatement:
+		clone := *node
+		clone.ReturnValue = rewriteExpression(node.ReturnValue, rewrite)
+		return &clone
+
+	case *ThrowStatement:
+		clone := *node
+		clone.Value = rewriteExpression(node.Value, rewrite)
+		return &clone
+
+	case *ImportStatement:
+		clone := *node
+		clone.Path = rewriteAs(node.Path, rewrite)
+		clone.Alias = rewriteAs(node.Alias, rewrite)
+		return &clone
+
+	case *ExportStatement:
+		clone := *node
+		clone.Statement = rewriteAs(node.Statement, rewrite)
+		return &clone
+
+	case *StructStatement:
+		clone := *node
+		clone.Name = rewriteAs(node.Name, rewrite)
+		clone.Fields = rewriteAll(node.Fields, rewrite)
+		return &clone
+
+	case *EnumStatement:
+		clone := *node
+		clone.Name = rewriteAs(node.Name, rewrite)
+		clone.Variants = make([]*EnumVariant, len(node.Variants))
+		for idx, variant := range node.Variants {
+			clone.Variants[idx] = &EnumVariant{
+				Name:   rewriteAs(variant.Name, rewrite),
+				Fields: rewriteAll(variant.Fields, rewrite),
+			}
+		}
+		return &clone
+
+	case *PrefixExpression:
+		clone := *node
+		clone.Right = rewriteExpression(node.Right, rewrite)
+		return &clone
+
+	case *InfixExpression:
+		clone := *node
+		clone.Left = rewriteExpression(node.Left, rewrite)
+		clone.Right = rewriteExpression(node.Right, rewrite)
+		return &clone
+
+	case *PipeExpression:
+		clone := *node
+		clone.Left = rewriteExpression(node.Left, rewrite)
+		clone.Right = rewriteExpression(node.Right, rewrite)
+		return &clone
+
+	case *IfExpression:
+		clone := *node
+		clone.Condition = rewriteExpression(node.Condition, rewrite)
+		clone.IfCondition = rewriteAs(node.IfCondition, rewrite)
+		clone.ElseCondition = rewriteAs(node.ElseCondition, rewrite)
+		return &clone
+
+	case *TryExpression:
+		clone := *node
+		clone.Block = rewriteAs(node.Block, rewrite)
+		clone.CatchParameter = rewriteExpression(node.CatchParameter, rewrite)
+		clone.CatchBlock = rewriteAs(node.CatchBlock, rewrite)
+		clone.FinallyBlock = rewriteAs(node.FinallyBlock, rewrite)
+		return &clone
+
+	case *MatchExpression:
+		clone := *node
+		clone.Subject = rewriteExpression(node.Subject, rewrite)
+		clone.Arms = make([]*MatchArm, len(node.Arms))
+		for idx, arm := range node.Arms {
+			clone.Arms[idx] = &MatchArm{
+				Pattern: rewriteExpression(arm.Pattern, rewrite),
+				Body:    rewriteAs(arm.Body, rewrite),
+			}
+		}
+		return &clone
+
+	case *VariantPattern:
+		clone := *node
+		clone.Variant = rewriteExpression(node.Variant, rewrite)
+		clone.Fields = rewriteExpressions(node.Fields, rewrite)
+		return &clone
+
+	case *ForExpression:
+		clone := *node
+		clone.Target = rewriteExpression(node.Target, rewrite)
+		clone.Iterable = rewriteExpression(node.Iterable, rewrite)
+		clone.Body = rewriteAs(node.Body, rewrite)
+		return &clone
+
+	case *YieldExpression:
+		clone := *node
+		clone.Value = rewriteExpression(node.Value, rewrite)
+		return &clone
+
+	case *AwaitExpression:
+		clone := *node
+		clone.Value = rewriteExpression(node.Value, rewrite)
+		return &clone
+
+	case *SpawnExpression:
+		clone := *node
+		clone.Call = rewriteAs(node.Call, rewrite)
+		return &clone
+
+	case *SelectExpression:
+		clone := *node
+		clone.Arms = make([]*SelectArm, len(node.Arms))
+		for idx, arm := range node.Arms {
+			clone.Arms[idx] = &SelectArm{
+				Operation: rewriteAs(arm.Operation, rewrite),
+				Binding:   rewriteAs(arm.Binding, rewrite),
+				Body:      rewriteAs(arm.Body, rewrite),
+			}
+		}
+		return &clone
+
+	case *FunctionLiteral:
+		clone := *node
+		clone.Parameters = rewriteExpressions(node.Parameters, rewrite)
+		clone.Body = rewriteAs(node.Body, rewrite)
+		return &clone
+
+	case *MacroLiteral:
+		clone := *node
+		clone.Parameters = rewriteAll(node.Parameters, rewrite)
+		clone.Body = rewriteAs(node.Body, rewrite)
+		return &clone
+
+	case *CallExpression:
+		clone := *node
+		clone.Function = rewriteExpression(node.Function, rewrite)
+		clone.Arguments = rewriteExpressions(node.Arguments, rewrite)
+		return &clone
+
+	case *ArrayLiteral:
+		clone := *node
+		clone.Elements = rewriteExpressions(node.Elements, rewrite)
+		return &clone
+
+	case *IndexExpression:
+		clone := *node
+		clone.Ref = rewriteExpression(node.Ref, rewrite)
+		clone.Index = rewriteExpression(node.Index, rewrite)
+		return &clone
+
+	case *MemberExpression:
+		clone := *node
+		clone.Ref = rewriteExpression(node.Ref, rewrite)
+		clone.Property = rewriteAs(node.Property, rewrite)
+		return &clone
+
+	case *HashLiteral:
+		clone := *node
+		clone.Keys = make([]Expression, len(node.Keys))
+		clone.Pairs = make(map[Expression]Expression, len(node.Pairs))
+		for idx, key := range node.Keys {
+			value, isPair := node.Pairs[key]
+
+			clone.Keys[idx] = rewriteExpression(key, rewrite)
+			if isPair {
+				clone.Pairs[clone.Keys[idx]] = rewriteExpression(value, rewrite)
+			}
+		}
+		return &clone
+
+	case *RestElement:
+		clone := *node
+		clone.Target = rewriteAs(node.Target, rewrite)
+		return &clone
+
+	case *SpreadElement:
+		clone := *node
+		clone.Value = rewriteExpression(node.Value, rewrite)
+		return &clone
+
+	case *DefaultParameter:
+		clone := *node
+		clone.Target = rewriteExpression(node.Target, rewrite)
+		clone.Value = rewriteExpression(node.Value, rewrite)
+		return &clone
+
+	case *ArrayPattern:
+		clone := *node
+		clone.Elements = rewriteExpressions(node.Elements, rewrite)
+		clone.Rest = rewriteAs(node.Rest, rewrite)
+		return &clone
+
+	case *HashPattern:
+		clone := *node
+		clone.Properties = make([]*HashPatternProperty, len(node.Properties))
+		for idx, property := range node.Properties {
+			clone.Properties[idx] = &HashPatternProperty{
+				Key:   rewriteAs(property.Key, rewrite),
+				Value: rewriteExpression(property.Value, rewrite),
+			}
+		}
+		clone.Rest = rewriteAs(node.Rest, rewrite)
+		return &clone
+
+	default:
+		// Leaves: identifiers and literals
+		return node
+	}
+}
+
+func rewriteExpression(expression Expression, rewrite func(Node) Node) Expression {
+	if expression == nil {
+		return nil
+	}
+
+	modified, ok := rewrite(expression).(Expression)
+	if !ok {
+		return expression
+	}
+
+	return modified
+}
+
+func rewriteExpressions(expressions []Expression, rewrite func(Node) Node) []Expression {
+	if expressions == nil {
+		return nil
+	}
+
+	modified := make([]Expression, len(expressions))
+	for idx, expression := range expressions {
+		modified[idx] = rewriteExpression(expression, rewrite)
+	}
+
+	return modified
+}
+
+func rewriteStatements(statements []Statement, rewrite func(Node) Node) []Statement {
+	if statements == nil {
+		return nil
+	}
+
+	// Statements replaced by nil are dropped
+	modified := make([]Statement, 0, len(statements))
+	for _, statement := range statements {
+		replacement := rewrite(statement)
+		if replacement == nil {
+			continue
+		}
+
+		if replacement, ok := replacement.(Statement); ok {
+			modified = append(modified, replacement)
+		} else {
+			modified = append(modified, statement)
+		}
+	}
+
+	return modified
+}
+
+// Rewrites a field holding a concrete node type, a nil field stays nil
+func rewriteAs[T interface {
+	comparable
+	Node
+}](node T, rewrite func(Node) Node) T {
+	var zero T
+	if node == zero {
+		return node
+	}
+
+	modified, ok := rewrite(node).(T)
+	if !ok {
+		return node
+	}
+
+	return modified
+}
+
+func rewriteAll[T interface {
+	comparable
+	Node
+}](nodes []T, rewrite func(Node) Node) []T {
+	if nodes == nil {
+		return nil
+	}
+
+	modified := make([]T, len(nodes))
+	for idx, node := range nodes {
+		modified[idx] = rewriteAs(node, rewrite)
+	}
+
+	return modified
+}
@@ -0,0 +1,218 @@
+package ast
+
+import "reflect"
+
+// A Visitor's Visit method is called for every node encountered by Walk. When the visitor w it returns is not nil,
+// Walk visits each of the children of the node with w, followed by a call of w.Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses a tree depth first, in source order: it starts by calling v.Visit(node)
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		walkAll(v, node.Statements)
+
+	case *ExpressionStatement:
+		walkNode(v, node.Expression)
+
+	case *BlockStatement:
+		walkAll(v, node.Statements)
+
+	case *LetStatement:
+		walkNode(v, node.Name)
+		walkNode(v, node.Pattern)
+		walkNode(v, node.Value)
+
+	case *ReturnStatement:
+		walkNode(v, node.ReturnValue)
+
+	case *ThrowStatement:
+		walkNode(v, node.Value)
+
+	case *ImportStatement:
+		walkNode(v, node.Path)
+		walkNode(v, node.Alias)
+
+	case *ExportStatement:
+		walkNode(v, node.Statement)
+
+	case *StructStatement:
+		walkNode(v, node.Name)
+		walkAll(v, node.Fields)
+
+	case *EnumStatement:
+		walkNode(v, node.Name)
+		for _, variant := range node.Variants {
+			walkNode(v, variant.Name)
+			walkAll(v, variant.Fields)
+		}
+
+	case *PrefixExpression:
+		walkNode(v, node.Right)
+
+	case *InfixExpression:
+		walkNode(v, node.Left)
+		walkNode(v, node.Right)
+
+	case *PipeExpression:
+		walkNode(v, node.Left)
+		walkNode(v, node.Right)
+
+	case *IfExpression:
+		walkNode(v, node.Condition)
+		walkNode(v, node.IfCondition)
+		walkNode(v, node.ElseCondition)
+
+	case *TryExpression:
+		walkNode(v, node.Block)
+		walkNode(v, node.CatchParameter)
+		walkNode(v, node.CatchBlock)
+		walkNode(v, node.FinallyBlock)
+
+	case *MatchExpression:
+		walkNode(v, node.Subject)
+		for _, arm := range node.Arms {
+			walkNode(v, arm.Pattern)
+			walkNode(v, arm.Body)
+		}
+
+	case *VariantPattern:
+		walkNode(v, node.Variant)
+		walkAll(v, node.Fields)
+
+	case *ForExpression:
+		walkNode(v, node.Target)
+		walkNode(v, node.Iterable)
+		walkNode(v, node.Body)
+
+	case *YieldExpression:
+		walkNode(v, node.Value)
+
+	case *AwaitExpression:
+		walkNode(v, node.Value)
+
+	case *SpawnExpression:
+		walkNode(v, node.Call)
+
+	case *SelectExpression:
+		for _, arm := range node.Arms {
+			walkNode(v, arm.Operation)
+			walkNode(v, arm.Binding)
+			walkNode(v, arm.Body)
+		}
+
+	case *FunctionLiteral:
+		walkAll(v, node.Parameters)
+		walkNode(v, node.Body)
+
+	case *MacroLiteral:
+		walkAll(v, node.Parameters)
+		walkNode(v, node.Body)
+
+	case *CallExpression:
+		walkNode(v, node.Function)
+		walkAll(v, node.Arguments)
+
+	case *ArrayLiteral:
+		walkAll(v, node.Elements)
+
+	case *IndexExpression:
+		walkNode(v, node.Ref)
+		walkNode(v, node.Index)
+
+	case *MemberExpression:
+		walkNode(v, node.Ref)
+		walkNode(v, node.Property)
+
+	case *HashLiteral:
+		for _, key := range node.Keys {
+			walkNode(v, key)
+			walkNode(v, node.Pairs[key])
+		}
+
+	case *RestElement:
+		walkNode(v, node.Target)
+
+	case *SpreadElement:
+		walkNode(v, node.Value)
+
+	case *DefaultParameter:
+		walkNode(v, node.Target)
+		walkNode(v, node.Value)
+
+	case *ArrayPattern:
+		walkAll(v, node.Elements)
+		walkNode(v, node.Rest)
+
+	case *HashPattern:
+		for _, property := range node.Properties {
+			walkNode(v, property.Key)
+
+			// Shorthand properties bind the key itself, it is only visited once
+			if property.Value != Expression(property.Key) {
+				walkNode(v, property.Value)
+			}
+		}
+		walkNode(v, node.Rest)
+	}
+
+	v.Visit(nil)
+}
+
+// Walks a child, skipping nil interfaces and nil pointers alike
+func walkNode(v Visitor, node Node) {
+	if node == nil {
+		return
+	}
+
+	if value := reflect.ValueOf(node); value.Kind() == reflect.Pointer && value.IsNil() {
+		return
+	}
+
+	Walk(v, node)
+}
+
+func walkAll[T Node](v Visitor, nodes []T) {
+	for _, node := range nodes {
+		walkNode(v, node)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverses a tree in the same order as Walk, calling f(node) for each node and skipping the children of
+// the nodes it returns false for. Once all the children of a node were inspected, f(nil) is called
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// RewriteFunc is called on a node before its children. It returns the node to use in its place, nil to remove a
+// statement, and whether the children of the returned node should be rewritten too
+type RewriteFunc func(Node) (replacement Node, descend bool)
+
+// Rewrite rewrites a tree top down, which lets rewrites skip whole subtrees, where Modify works bottom up. Like
+// Modify it copies the nodes it changes instead of changing the tree passed in
+func Rewrite(node Node, rewrite RewriteFunc) Node {
+	replacement, descend := rewrite(node)
+	if !descend || replacement == nil {
+		return replacement
+	}
+
+	return rewriteChildren(replacement, func(child Node) Node {
+		return Rewrite(child, rewrite)
+	})
+}
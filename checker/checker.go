@@ -0,0 +1,982 @@
+package checker
+
+import (
+	"fmt"
+	"fungo/ast"
+	"fungo/evaluator"
+	"fungo/lexer"
+	"fungo/parser"
+	"fungo/token"
+	"reflect"
+	"sort"
+)
+
+// Error is a type error at the position of the expression it is about. Parse errors carry no position. Warnings of
+// the parser, such as a match missing variants of an enum, do not stop the program from running
+type Error struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+	Warning bool   `json:"warning,omitempty"`
+}
+
+func (e Error) Error() string {
+	message := e.Message
+	if e.Warning {
+		message = "warning: " + message
+	}
+
+	if e.Line == 0 {
+		return message
+	}
+
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, message)
+}
+
+// Names of the types annotations can use besides the structs and enums a program declares
+var basicTypes = map[string]bool{
+	"any":      true,
+	"int":      true,
+	"string":   true,
+	"bool":     true,
+	"null":     true,
+	"array":    true,
+	"hash":     true,
+	"fn":       true,
+	"iterator": true,
+	"channel":  true,
+	"promise":  true,
+	"module":   true,
+}
+
+var anyType = basic("any")
+
+// The element type of literals whose elements have different known types. It reads any but unlike any it only fits
+// any, so [1, "a"] is not an array[int]. Reading an element widens it to any
+var mixedType = basic("any")
+
+func basic(name string, parameters ...*ast.TypeAnnotation) *ast.TypeAnnotation {
+	return &ast.TypeAnnotation{Name: name, Parameters: parameters}
+}
+
+// Check infers the types of a program from its literals, its annotations and the signatures of the built in functions,
+// and reports the operations, calls, lets and returns whose types do not fit, sorted by position. Whatever cannot be
+// inferred is any, which fits everywhere, so a program without annotations only gets errors for mistakes such as
+// `1 + "a"` or calling a function with the wrong number of arguments
+func Check(program *ast.Program) []Error {
+	c := &checker{
+		errors:     []Error{},
+		types:      make(map[string]bool),
+		builtins:   make(map[string]*ast.TypeAnnotation),
+		signatures: make(map[*ast.FunctionLiteral]*ast.TypeAnnotation),
+		results:    make(map[*ast.FunctionLiteral]*ast.TypeAnnotation),
+		minimum:    make(map[*ast.TypeAnnotation]int),
+		scope:      newScope(nil),
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.StructStatement:
+			c.types[node.Name.Value] = true
+		case *ast.EnumStatement:
+			c.types[node.Name.Value] = true
+		}
+
+		return true
+	})
+
+	c.statements(program.Statements)
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i], c.errors[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+
+	return c.errors
+}
+
+// Parses and checks source, the warnings of the parser come along with the type errors. When it does not parse, its
+// parse errors are the only errors
+func Source(source []byte) []Error {
+	p := parser.NewParser(lexer.NewLexer(string(source)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		errors := []Error{}
+		for _, msg := range p.Errors() {
+			errors = append(errors, Error{Message: msg})
+		}
+
+		return errors
+	}
+
+	errors := Check(program)
+	for _, warning := range p.Warnings() {
+		errors = append(errors, Error{Line: warning.Token.Line, Column: warning.Token.Column, Message: warning.Message, Warning: true})
+	}
+
+	sort.SliceStable(errors, func(i, j int) bool {
+		a, b := errors[i], errors[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+
+	return errors
+}
+
+type scope struct {
+	names  map[string]*ast.TypeAnnotation
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{names: make(map[string]*ast.TypeAnnotation), parent: parent}
+}
+
+func (s *scope) lookup(name string) (*ast.TypeAnnotation, bool) {
+	for ; s != nil; s = s.parent {
+		if t, ok := s.names[name]; ok {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
+
+// The function whose body is being checked, result is its declared result type, nil when it has none
+type function struct {
+	result  *ast.TypeAnnotation
+	returns []*ast.TypeAnnotation
+}
+
+type checker struct {
+	errors []Error
+	scope  *scope
+
+	// Structs and enums declared anywhere in the program
+	types map[string]bool
+
+	// Parsed signatures of the built in functions used so far
+	builtins map[string]*ast.TypeAnnotation
+
+	// Signature of each function literal, computed once so its annotation errors are only reported once, and the
+	// declared result of the literals whose result annotation is valid
+	signatures map[*ast.FunctionLiteral]*ast.TypeAnnotation
+	results    map[*ast.FunctionLiteral]*ast.TypeAnnotation
+
+	// Fewest arguments of the signatures of functions that have default parameters
+	minimum map[*ast.TypeAnnotation]int
+
+	// Functions whose bodies are being checked, innermost last
+	functions []*function
+}
+
+func (c *checker) report(at token.Token, format string, args ...any) {
+	c.errors = append(c.errors, Error{Line: at.Line, Column: at.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// Binds the functions of a list of statements before checking it, so calls to the functions it binds further down get
+// their signature. Returns the type of the last statement, any unless it is an expression
+func (c *checker) statements(statements []ast.Statement) *ast.TypeAnnotation {
+	for _, statement := range statements {
+		if export, ok := statement.(*ast.ExportStatement); ok {
+			statement = export.Statement
+		}
+
+		let, ok := statement.(*ast.LetStatement)
+		if !ok || let.Name == nil {
+			continue
+		}
+
+		if function, ok := let.Value.(*ast.FunctionLiteral); ok && let.Type == nil {
+			c.scope.names[let.Name.Value] = c.signature(function)
+		}
+	}
+
+	result := anyType
+	for _, statement := range statements {
+		result = c.statement(statement)
+	}
+
+	return result
+}
+
+func (c *checker) statement(statement ast.Statement) *ast.TypeAnnotation {
+	switch statement := statement.(type) {
+	case *ast.ExpressionStatement:
+		return c.expression(statement.Expression)
+
+	case *ast.LetStatement:
+		c.let(statement)
+
+	case *ast.ReturnStatement:
+		c.returns(statement)
+
+	case *ast.ThrowStatement:
+		c.expression(statement.Value)
+
+	case *ast.ExportStatement:
+		c.statement(statement.Statement)
+
+	case *ast.ImportStatement:
+		c.scope.names[statement.Alias.Value] = basic("module")
+
+	case *ast.StructStatement:
+		c.scope.names[statement.Name.Value] = constructor(len(statement.Fields), statement.Name.Value)
+
+	case *ast.EnumStatement:
+		c.scope.names[statement.Name.Value] = anyType
+
+		for _, variant := range statement.Variants {
+			if len(variant.Fields) == 0 {
+				c.scope.names[variant.Name.Value] = basic(statement.Name.Value)
+			} else {
+				c.scope.names[variant.Name.Value] = constructor(len(variant.Fields), statement.Name.Value)
+			}
+		}
+
+	case *ast.BlockStatement:
+		return c.statements(statement.Statements)
+	}
+
+	return anyType
+}
+
+// The signature of calling a struct definition or an enum variant
+func constructor(fields int, result string) *ast.TypeAnnotation {
+	signature := &ast.TypeAnnotation{Name: "fn", Signature: true, Return: basic(result)}
+	for idx := 0; idx < fields; idx++ {
+		signature.Parameters = append(signature.Parameters, anyType)
+	}
+
+	return signature
+}
+
+func (c *checker) let(statement *ast.LetStatement) {
+	value := c.expression(statement.Value)
+
+	if statement.Type != nil && c.valid(statement.Type) {
+		target := ast.Expression(statement.Name)
+		if statement.Pattern != nil {
+			target = statement.Pattern
+		}
+
+		if !assignable(value, statement.Type) {
+			c.report(start(statement.Value), "cannot use %s as %s in let %s", value, statement.Type, target.String())
+		}
+
+		value = statement.Type
+	}
+
+	if statement.Pattern != nil {
+		c.bind(statement.Pattern, value)
+		return
+	}
+
+	c.scope.names[statement.Name.Value] = value
+}
+
+func (c *checker) returns(statement *ast.ReturnStatement) {
+	value := anyType
+	if statement.ReturnValue != nil {
+		value = c.expression(statement.ReturnValue)
+	}
+
+	if len(c.functions) == 0 {
+		return
+	}
+
+	function := c.functions[len(c.functions)-1]
+	function.returns = append(function.returns, value)
+
+	if function.result != nil && !assignable(value, function.result) {
+		c.report(start(statement.ReturnValue), "cannot use %s as %s in return", value, function.result)
+	}
+}
+
+// Binds the names of a let, parameter or match pattern, destructured values get the element type when it is known
+func (c *checker) bind(target ast.Expression, value *ast.TypeAnnotation) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		c.scope.names[target.Value] = value
+
+	case *ast.ArrayPattern:
+		element := anyType
+		if value.Name == "array" && len(value.Parameters) == 1 {
+			element = widen(value.Parameters[0])
+		}
+
+		for _, pattern := range target.Elements {
+			c.bind(pattern, element)
+		}
+
+		if target.Rest != nil {
+			c.bind(target.Rest.Target, basic("array", element))
+		}
+
+	case *ast.HashPattern:
+		element := anyType
+		if value.Name == "hash" && len(value.Parameters) == 2 {
+			element = widen(value.Parameters[1])
+		}
+
+		for _, property := range target.Properties {
+			c.bind(property.Value, element)
+		}
+
+		if target.Rest != nil {
+			c.bind(target.Rest.Target, basic("hash"))
+		}
+
+	case *ast.DefaultParameter:
+		c.bind(target.Target, value)
+
+	case *ast.VariantPattern:
+		for _, field := range target.Fields {
+			c.bind(field, anyType)
+		}
+	}
+}
+
+func (c *checker) expression(expression ast.Expression) *ast.TypeAnnotation {
+	switch expression := expression.(type) {
+	case *ast.IntegerLiteral:
+		return basic("int")
+
+	case *ast.StringLiteral:
+		return basic("string")
+
+	case *ast.Boolean:
+		return basic("bool")
+
+	case *ast.Identifier:
+		return c.identifier(expression.Value)
+
+	case *ast.PrefixExpression:
+		right := c.expression(expression.Right)
+
+		if expression.Operator == "!" {
+			return basic("bool")
+		}
+
+		if known(right) && right.Name != "int" {
+			c.report(expression.Token, "invalid operation: %s%s", expression.Operator, right)
+		}
+
+		return basic("int")
+
+	case *ast.InfixExpression:
+		return c.infix(expression)
+
+	case *ast.PipeExpression:
+		if call, ok := expression.Right.(*ast.CallExpression); ok {
+			return c.call(call.Function, call.Arguments, expression.Left)
+		}
+
+		return c.call(expression.Right, nil, expression.Left)
+
+	case *ast.CallExpression:
+		// Quoted code is not evaluated, it is checked once expanded
+		if expression.Function.String() == "quote" {
+			return anyType
+		}
+
+		return c.call(expression.Function, expression.Arguments, nil)
+
+	case *ast.IndexExpression:
+		return c.index(expression)
+
+	case *ast.MemberExpression:
+		c.expression(expression.Ref)
+
+	case *ast.ArrayLiteral:
+		element := c.elements(expression.Elements)
+		if !known(element) && element != mixedType {
+			return basic("array")
+		}
+
+		return basic("array", element)
+
+	case *ast.HashLiteral:
+		return c.hash(expression)
+
+	case *ast.SpreadElement:
+		c.expression(expression.Value)
+
+	case *ast.IfExpression:
+		c.expression(expression.Condition)
+
+		consequence := c.statements(expression.IfCondition.Statements)
+		if expression.ElseCondition == nil {
+			return anyType
+		}
+
+		return join(consequence, c.statements(expression.ElseCondition.Statements))
+
+	case *ast.TryExpression:
+		c.statements(expression.Block.Statements)
+
+		if expression.CatchBlock != nil {
+			c.scoped(func() {
+				if expression.CatchParameter != nil {
+					c.bind(expression.CatchParameter, basic("hash"))
+				}
+
+				c.statements(expression.CatchBlock.Statements)
+			})
+		}
+
+		if expression.FinallyBlock != nil {
+			c.statements(expression.FinallyBlock.Statements)
+		}
+
+	case *ast.MatchExpression:
+		c.expression(expression.Subject)
+
+		for _, arm := range expression.Arms {
+			c.scoped(func() {
+				c.bind(arm.Pattern, anyType)
+				c.statements(arm.Body.Statements)
+			})
+		}
+
+	case *ast.ForExpression:
+		c.expression(expression.Iterable)
+
+		c.scoped(func() {
+			c.bind(expression.Target, anyType)
+			c.statements(expression.Body.Statements)
+		})
+
+	case *ast.SelectExpression:
+		for _, arm := range expression.Arms {
+			if arm.Operation != nil {
+				c.expression(arm.Operation)
+			}
+
+			c.scoped(func() {
+				if arm.Binding != nil {
+					c.bind(arm.Binding, anyType)
+				}
+
+				c.statements(arm.Body.Statements)
+			})
+		}
+
+	case *ast.YieldExpression:
+		c.expression(expression.Value)
+
+	case *ast.AwaitExpression:
+		c.expression(expression.Value)
+
+	case *ast.SpawnExpression:
+		c.expression(expression.Call)
+
+	case *ast.FunctionLiteral:
+		return c.function(expression)
+	}
+
+	return anyType
+}
+
+func (c *checker) scoped(check func()) {
+	c.scope = newScope(c.scope)
+	defer func() { c.scope = c.scope.parent }()
+
+	check()
+}
+
+// Names that are not bound anywhere are left to the linter, they are any here
+func (c *checker) identifier(name string) *ast.TypeAnnotation {
+	if t, ok := c.scope.lookup(name); ok {
+		return t
+	}
+
+	if t, ok := c.builtins[name]; ok {
+		return t
+	}
+
+	if signature, ok := evaluator.BuiltInSignature(name); ok {
+		if t, err := parser.ParseType(signature); err == nil {
+			c.builtins[name] = t
+			return t
+		}
+	}
+
+	return anyType
+}
+
+// Mirrors the evaluator: integers support every operator and strings only +, other values can be compared with ==
+func (c *checker) infix(expression *ast.InfixExpression) *ast.TypeAnnotation {
+	left, right := c.expression(expression.Left), c.expression(expression.Right)
+
+	if expression.Operator == "==" || expression.Operator == "!=" {
+		if left.Name == "string" && right.Name == "string" {
+			c.report(expression.Token, "invalid operation: %s %s %s", left, expression.Operator, right)
+		}
+
+		return basic("bool")
+	}
+
+	operand := "int"
+	if expression.Operator == "+" {
+		if !known(left) && !known(right) {
+			return anyType
+		}
+
+		if left.Name == "string" || right.Name == "string" {
+			operand = "string"
+		}
+	}
+
+	for _, t := range []*ast.TypeAnnotation{left, right} {
+		if known(t) && t.Name != operand {
+			c.report(expression.Token, "invalid operation: %s %s %s", left, expression.Operator, right)
+			return anyType
+		}
+	}
+
+	if expression.Operator == "<" || expression.Operator == ">" {
+		return basic("bool")
+	}
+
+	return basic(operand)
+}
+
+// Checks a call against the signature of the function, piped is the left side of `<piped> |> <function>`, which
+// becomes the first argument
+func (c *checker) call(function ast.Expression, arguments []ast.Expression, piped ast.Expression) *ast.TypeAnnotation {
+	callee := c.expression(function)
+
+	if piped != nil {
+		arguments = append([]ast.Expression{piped}, arguments...)
+	}
+
+	args := make([]*ast.TypeAnnotation, len(arguments))
+	spread := false
+	for idx, argument := range arguments {
+		args[idx] = c.expression(argument)
+		_, isSpread := argument.(*ast.SpreadElement)
+		spread = spread || isSpread
+	}
+
+	switch {
+	case !known(callee):
+		return anyType
+	case callee.Name != "fn":
+		c.report(start(function), "cannot call %s of type %s", function.String(), callee)
+		return anyType
+	case !callee.Signature:
+		return anyType
+	}
+
+	name := function.String()
+	if _, ok := function.(*ast.FunctionLiteral); ok {
+		name = "function"
+	}
+
+	if min, max := c.arity(callee); !spread && (len(args) < min || (max != -1 && len(args) > max)) {
+		c.report(start(function), "wrong number of arguments to %s. got=%d, want=%s", name, len(args), wanted(min, max))
+	}
+
+	for idx, arg := range args {
+		if _, ok := arguments[idx].(*ast.SpreadElement); ok {
+			break
+		}
+
+		param := parameter(callee, idx)
+		if param != nil && !assignable(arg, param) {
+			c.report(start(arguments[idx]), "cannot use %s as %s in argument %d to %s", arg, param, idx+1, name)
+		}
+	}
+
+	if callee.Return == nil {
+		return anyType
+	}
+
+	return callee.Return
+}
+
+// Returns the fewest and most arguments a signature accepts, max is -1 when it is variadic
+func (c *checker) arity(signature *ast.TypeAnnotation) (int, int) {
+	min, max := len(signature.Parameters), len(signature.Parameters)
+	if signature.Variadic {
+		min, max = min-1, -1
+	}
+
+	if minimum, ok := c.minimum[signature]; ok {
+		min = minimum
+	}
+
+	return min, max
+}
+
+func wanted(min, max int) string {
+	switch {
+	case max == -1:
+		return fmt.Sprintf(">=%d", min)
+	case min == max:
+		return fmt.Sprint(min)
+	default:
+		return fmt.Sprintf("%d..%d", min, max)
+	}
+}
+
+// The type of the parameter the argument at idx is passed to, nil when there is none
+func parameter(signature *ast.TypeAnnotation, idx int) *ast.TypeAnnotation {
+	count := len(signature.Parameters)
+
+	switch {
+	case signature.Variadic && idx >= count-1:
+		return signature.Parameters[count-1]
+	case idx < count:
+		return signature.Parameters[idx]
+	default:
+		return nil
+	}
+}
+
+func (c *checker) index(expression *ast.IndexExpression) *ast.TypeAnnotation {
+	ref, index := c.expression(expression.Ref), c.expression(expression.Index)
+
+	switch ref.Name {
+	case "any":
+		return anyType
+
+	case "array":
+		if known(index) && index.Name != "int" {
+			c.report(start(expression.Index), "cannot index %s with %s", ref, index)
+		}
+
+		if len(ref.Parameters) == 1 {
+			return widen(ref.Parameters[0])
+		}
+
+	case "hash":
+		if len(ref.Parameters) == 2 {
+			if !assignable(index, ref.Parameters[0]) {
+				c.report(start(expression.Index), "cannot index %s with %s", ref, index)
+			}
+
+			return widen(ref.Parameters[1])
+		}
+
+	default:
+		c.report(expression.Token, "index operator not supported: %s", ref)
+	}
+
+	return anyType
+}
+
+// The type shared by all elements, mixed when their known types differ. Spread arrays contribute their element type,
+// spread strings their one byte strings
+func (c *checker) elements(elements []ast.Expression) *ast.TypeAnnotation {
+	var shared *ast.TypeAnnotation
+
+	for _, element := range elements {
+		var t *ast.TypeAnnotation
+
+		if spread, ok := element.(*ast.SpreadElement); ok {
+			t = anyType
+			switch value := c.expression(spread.Value); {
+			case value.Name == "array" && len(value.Parameters) == 1:
+				t = value.Parameters[0]
+			case value.Name == "string":
+				t = value
+			}
+		} else {
+			t = c.expression(element)
+		}
+
+		switch {
+		case shared == nil:
+			shared = t
+		case shared == mixedType || t == mixedType || known(shared) && known(t) && shared.String() != t.String():
+			shared = mixedType
+		default:
+			shared = join(shared, t)
+		}
+	}
+
+	if shared == nil {
+		return anyType
+	}
+
+	return shared
+}
+
+func (c *checker) hash(literal *ast.HashLiteral) *ast.TypeAnnotation {
+	keys := []ast.Expression{}
+	values := []ast.Expression{}
+
+	for _, key := range literal.Keys {
+		if spread, ok := key.(*ast.SpreadElement); ok {
+			c.expression(spread.Value)
+			return basic("hash")
+		}
+
+		keys = append(keys, key)
+		values = append(values, literal.Pairs[key])
+	}
+
+	key, value := c.elements(keys), c.elements(values)
+	if !known(key) && !known(value) && key != mixedType && value != mixedType {
+		return basic("hash")
+	}
+
+	return basic("hash", key, value)
+}
+
+// The signature of a function literal, from its annotations. Results that are not annotated are inferred once the
+// body is checked
+func (c *checker) signature(literal *ast.FunctionLiteral) *ast.TypeAnnotation {
+	if signature, ok := c.signatures[literal]; ok {
+		return signature
+	}
+
+	signature := &ast.TypeAnnotation{Token: literal.Token, Name: "fn", Signature: true}
+	c.signatures[literal] = signature
+
+	defaults := false
+	for idx, param := range literal.Parameters {
+		t := literal.ParameterType(idx)
+		if t == nil || !c.valid(t) {
+			t = anyType
+		}
+
+		switch param.(type) {
+		case *ast.RestElement:
+			signature.Variadic = true
+
+			switch {
+			case t.Name == "array" && len(t.Parameters) == 1:
+				t = t.Parameters[0]
+			case t.Name == "array" || t.Name == "any":
+				t = anyType
+			default:
+				c.report(t.Token, "rest parameter %s must be an array, got %s", param.String(), t)
+				t = anyType
+			}
+
+		case *ast.DefaultParameter:
+			if !defaults {
+				c.minimum[signature] = idx
+			}
+			defaults = true
+		}
+
+		signature.Parameters = append(signature.Parameters, t)
+	}
+
+	if literal.ReturnType != nil && c.valid(literal.ReturnType) {
+		c.results[literal] = literal.ReturnType
+		signature.Return = literal.ReturnType
+	}
+
+	// The result annotation of async functions is the value their promise resolves with
+	switch {
+	case literal.IsGenerator:
+		signature.Return = basic("iterator")
+	case literal.IsAsync:
+		signature.Return = basic("promise")
+	}
+
+	return signature
+}
+
+// Checks the body of a function literal. Unless annotated, the result is the type shared by its returns and its last
+// expression
+func (c *checker) function(literal *ast.FunctionLiteral) *ast.TypeAnnotation {
+	signature := c.signature(literal)
+
+	function := &function{result: c.results[literal]}
+
+	c.functions = append(c.functions, function)
+	defer func() { c.functions = c.functions[:len(c.functions)-1] }()
+
+	var result *ast.TypeAnnotation
+	c.scoped(func() {
+		for idx, param := range literal.Parameters {
+			switch param := param.(type) {
+			case *ast.RestElement:
+				c.bind(param.Target, basic("array", parameter(signature, idx)))
+
+			case *ast.DefaultParameter:
+				value := c.expression(param.Value)
+				if !assignable(value, signature.Parameters[idx]) {
+					c.report(start(param.Value), "cannot use %s as %s in default of %s", value, signature.Parameters[idx], param.Target.String())
+				}
+
+				c.bind(param.Target, signature.Parameters[idx])
+
+			default:
+				c.bind(param, signature.Parameters[idx])
+			}
+		}
+
+		result = c.statements(literal.Body.Statements)
+	})
+
+	last, ok := lastExpression(literal.Body)
+	if function.result != nil && ok && !literal.IsGenerator && !assignable(result, function.result) {
+		c.report(start(last), "cannot use %s as %s in return", result, function.result)
+	}
+
+	if signature.Return == nil {
+		inferred := function.returns
+		if ok {
+			inferred = append(inferred, result)
+		}
+
+		signature.Return = anyType
+		for idx, t := range inferred {
+			if idx == 0 {
+				signature.Return = t
+			} else {
+				signature.Return = join(signature.Return, t)
+			}
+		}
+	}
+
+	return signature
+}
+
+func lastExpression(body *ast.BlockStatement) (ast.Expression, bool) {
+	if len(body.Statements) == 0 {
+		return nil, false
+	}
+
+	statement, ok := body.Statements[len(body.Statements)-1].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+
+	return statement.Expression, true
+}
+
+// Reports the annotations naming types that do not exist or giving the wrong number of type parameters
+func (c *checker) valid(annotation *ast.TypeAnnotation) bool {
+	count := len(annotation.Parameters)
+
+	switch {
+	case annotation.Signature:
+	case !basicTypes[annotation.Name] && !c.types[annotation.Name]:
+		c.report(annotation.Token, "unknown type %s", annotation.Name)
+		return false
+	case annotation.Name == "array" && count > 1:
+		c.report(annotation.Token, "array takes one element type, got %s", annotation)
+		return false
+	case annotation.Name == "hash" && count != 0 && count != 2:
+		c.report(annotation.Token, "hash takes a key and a value type, got %s", annotation)
+		return false
+	case annotation.Name != "array" && annotation.Name != "hash" && count > 0:
+		c.report(annotation.Token, "%s takes no type parameters, got %s", annotation.Name, annotation)
+		return false
+	}
+
+	valid := true
+	for _, param := range annotation.Parameters {
+		valid = c.valid(param) && valid
+	}
+
+	if annotation.Return != nil {
+		valid = c.valid(annotation.Return) && valid
+	}
+
+	return valid
+}
+
+func known(t *ast.TypeAnnotation) bool {
+	return t.Name != "any"
+}
+
+// any fits everywhere and everything fits any, other types fit when their names and known parameters match. A
+// function fits a signature when it accepts its parameters and its result fits. Mixed elements only fit any
+func assignable(from, to *ast.TypeAnnotation) bool {
+	if from == mixedType {
+		return !known(to)
+	}
+
+	if !known(from) || !known(to) {
+		return true
+	}
+
+	if from.Name != to.Name {
+		return false
+	}
+
+	if from.Signature && to.Signature {
+		for idx, param := range to.Parameters {
+			if accepted := parameter(from, idx); accepted != nil && !assignable(param, accepted) {
+				return false
+			}
+		}
+
+		return from.Return == nil || to.Return == nil || assignable(from.Return, to.Return)
+	}
+
+	if from.Signature || to.Signature || len(from.Parameters) != len(to.Parameters) {
+		return true
+	}
+
+	for idx := range to.Parameters {
+		if !assignable(from.Parameters[idx], to.Parameters[idx]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func widen(t *ast.TypeAnnotation) *ast.TypeAnnotation {
+	if t == mixedType {
+		return anyType
+	}
+
+	return t
+}
+
+// The type of a value that is either a or b
+func join(a, b *ast.TypeAnnotation) *ast.TypeAnnotation {
+	if a.String() == b.String() {
+		return a
+	}
+
+	return anyType
+}
+
+// The position an expression starts at, its Token is the operator for infix expressions and calls
+func start(expression ast.Expression) token.Token {
+	switch expression := expression.(type) {
+	case *ast.InfixExpression:
+		return start(expression.Left)
+	case *ast.PipeExpression:
+		return start(expression.Left)
+	case *ast.CallExpression:
+		return start(expression.Function)
+	case *ast.IndexExpression:
+		return start(expression.Ref)
+	case *ast.MemberExpression:
+		return start(expression.Ref)
+	}
+
+	return tokenOf(expression)
+}
+
+// Every node has its position in a Token field
+func tokenOf(node ast.Node) token.Token {
+	value := reflect.ValueOf(node)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return token.Token{}
+	}
+
+	if field := value.FieldByName("Token"); field.IsValid() {
+		tok, _ := field.Interface().(token.Token)
+		return tok
+	}
+
+	return token.Token{}
+}
@@ -0,0 +1,166 @@
+package checker
+
+import (
+	"fungo/lexer"
+	"fungo/parser"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CheckerTestSuite struct {
+	suite.Suite
+}
+
+func TestCheckerTestSuite(t *testing.T) {
+	suite.Run(t, &CheckerTestSuite{})
+}
+
+func (t *CheckerTestSuite) check(input string) []string {
+	p := parser.NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	t.Empty(p.Errors(), input)
+
+	errors := []string{}
+	for _, err := range Check(program) {
+		errors = append(errors, err.Error())
+	}
+
+	return errors
+}
+
+func (t *CheckerTestSuite) TestOperators() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`1 + 2 * 3; "a" + "b"; 1 < 2; "a" == 1; [1] != [2]; !5`, []string{}},
+		{`"a" == "b"`, []string{`1:5: invalid operation: string == string`}},
+		{`1 + "a"`, []string{`1:3: invalid operation: int + string`}},
+		{`"a" - "b"`, []string{`1:5: invalid operation: string - string`}},
+		{`"a" < "b"`, []string{`1:5: invalid operation: string < string`}},
+		{`(1 < 2) + 1`, []string{`1:9: invalid operation: bool + int`}},
+		{`-"a"`, []string{`1:1: invalid operation: -string`}},
+		{`let f = fn(x) { x }; f(1) + "a"; x + 1`, []string{}},
+		{`let x = 1; let y = x + 1; y + "a"`, []string{`1:29: invalid operation: int + string`}},
+		{`let f = fn() { 1 }; f() + "a"`, []string{`1:25: invalid operation: int + string`}},
+		{`len("a") + "a"`, []string{`1:10: invalid operation: int + string`}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, t.check(test.input), test.input)
+	}
+}
+
+func (t *CheckerTestSuite) TestCalls() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let add = fn(a: int, b: int): int { a + b }; add(1, 2) + 3`, []string{}},
+		{`let add = fn(a: int, b: int) { a + b }; add(1, "2")`, []string{`1:48: cannot use string as int in argument 2 to add`}},
+		{`let add = fn(a, b) { a + b }; add(1)`, []string{`1:31: wrong number of arguments to add. got=1, want=2`}},
+		{`let f = fn(a, b = 1) { a }; f(1); f(1, 2); f(1, 2, 3)`, []string{`1:44: wrong number of arguments to f. got=3, want=1..2`}},
+		{`let f = fn(a, ...rest: array[int]) { a }; f(); f(1, 2, "3")`, []string{
+			`1:43: wrong number of arguments to f. got=0, want=>=1`,
+			`1:56: cannot use string as int in argument 3 to f`,
+		}},
+		{`let f = fn(a: int) { a }; f(...xs); f(...xs, 1, 2)`, []string{}},
+		{`len([1], [2]); push(1, 2); print(1, 2, 3)`, []string{
+			`1:1: wrong number of arguments to len. got=2, want=1`,
+			`1:21: cannot use int as array in argument 1 to push`,
+		}},
+		{`let f = fn(s: string) { s }; "a" |> f; 1 |> f`, []string{`1:40: cannot use int as string in argument 1 to f`}},
+		{`range(10) |> take(5) |> map(fn(x) { x })`, []string{}},
+		{`5(1); "a"()`, []string{`1:1: cannot call 5 of type int`, `1:7: cannot call a of type string`}},
+		{`let apply = fn(f: fn(int): int, x: int) { f(x) }; apply(fn(x: string) { x }, 1)`, []string{
+			`1:57: cannot use fn(string): string as fn(int): int in argument 1 to apply`,
+		}},
+		{`let apply = fn(f: fn(int): int) { f(1) }; apply(fn(x) { x }); apply(len)`, []string{}},
+		{`struct Point { x, y }; let p: Point = Point(1, 2); Point(1)`, []string{`1:52: wrong number of arguments to Point. got=1, want=2`}},
+		{`enum Shape { Dot, Circle(r) }; let a: Shape = Dot; let b: Shape = Circle(1); let c: int = Dot`, []string{
+			`1:91: cannot use Shape as int in let c`,
+		}},
+		// Functions are called before they are bound, the call is checked against their signature
+		{`let f = fn() { g("a") }; let g = fn(x: int) { x }`, []string{`1:18: cannot use string as int in argument 1 to g`}},
+		{`let m = macro(a) { quote(unquote(a) + "a") }; m(1)`, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, t.check(test.input), test.input)
+	}
+}
+
+func (t *CheckerTestSuite) TestLetsAndReturns() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let x: int = 1; let s: string = "a"; let a: array[int] = [1, 2]; let h: hash[string, int] = {"a": 1}`, []string{}},
+		{`let x: int = "a"`, []string{`1:14: cannot use string as int in let x`}},
+		{`let a: array[string] = [1, 2]`, []string{`1:24: cannot use array[int] as array[string] in let a`}},
+		{`let a: array[int] = [1, "a"]; let b: array[int] = []`, []string{`1:21: cannot use array[any] as array[int] in let a`}},
+		{`let a: array[any] = [1, "a"]; let b: array[int] = [1, f()]; let c = [1, "a"]; c[0] + 1`, []string{}},
+		{`let a: array[string] = [..."ab", "c"]; let b: array[int] = [..."ab"]`, []string{`1:60: cannot use array[string] as array[int] in let b`}},
+		{`let h: hash[string, int] = {"a": 1, "b": "c"}`, []string{`1:28: cannot use hash[string, any] as hash[string, int] in let h`}},
+		{`let f = fn(xs: array[array[int]]) { xs }; f([[1], ["a"]]); f([[1], [2]])`, []string{
+			`1:45: cannot use array[any] as array[array[int]] in argument 1 to f`,
+		}},
+		{`let x: int = y; let z: any = 1; let w: string = z`, []string{}},
+		{`let [a, b]: array[int] = [1, 2]; a + "x"`, []string{`1:36: invalid operation: int + string`}},
+		{`let f = fn(): int { "a" }`, []string{`1:21: cannot use string as int in return`}},
+		{`let f = fn(x): string { if (x) { return 1 } "a" }`, []string{`1:41: cannot use int as string in return`}},
+		{`let f = fn(): int { let x = 1; return x }`, []string{}},
+		{`let f = fn(x: int = "a") { x }`, []string{`1:21: cannot use string as int in default of x`}},
+		{`let f = async fn(): int { "a" }; let p: promise = f()`, []string{`1:27: cannot use string as int in return`}},
+		{`let g = fn(): int { yield "a" }; let i: iterator = g()`, []string{}},
+		{`let xs = [1, 2]; xs[0] + "a"; xs["a"]`, []string{
+			`1:24: invalid operation: int + string`,
+			`1:34: cannot index array[int] with string`,
+		}},
+		{`let h = {"a": 1}; h["a"] + 1; h[1]; 5[0]`, []string{
+			`1:33: cannot index hash[string, int] with int`,
+			`1:38: index operator not supported: int`,
+		}},
+		{`let f = fn(x) { if (x) { 1 } else { 2 } }; f(true) + "a"`, []string{`1:52: invalid operation: int + string`}},
+		{`let f = fn(x) { if (x) { 1 } else { "a" } }; f(true) + "a"`, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, t.check(test.input), test.input)
+	}
+}
+
+func (t *CheckerTestSuite) TestAnnotations() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`struct Point { x }; enum Color { Red }; let f = fn(p: Point, c: Color, g: fn, i: iterator) { p }`, []string{}},
+		{`let x: Nope = 1`, []string{`1:8: unknown type Nope`}},
+		{`let f = fn(x: array[int, int]): Nope { x }`, []string{
+			`1:15: array takes one element type, got array[int, int]`,
+			`1:33: unknown type Nope`,
+		}},
+		{`let f = fn(h: hash[int], x: int[string]) { h }`, []string{
+			`1:15: hash takes a key and a value type, got hash[int]`,
+			`1:29: int takes no type parameters, got int[string]`,
+		}},
+		{`let f = fn(...xs: int) { xs }`, []string{`1:19: rest parameter ...xs must be an array, got int`}},
+		{`let f = fn(g: fn(Nope): int) { g }; f(len)`, []string{`1:18: unknown type Nope`}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, t.check(test.input), test.input)
+	}
+}
+
+func (t *CheckerTestSuite) TestSource() {
+	t.Equal(Error{Message: `expected next token to be "IDENT", got "=" instead`}, Source([]byte("let = 1"))[0])
+	t.Equal([]Error{{Line: 1, Column: 3, Message: "invalid operation: int + string"}}, Source([]byte(`1 + "a"`)))
+	t.Empty(Source([]byte(`let x: int = 1`)))
+
+	warning := Error{Line: 2, Column: 1, Message: "non-exhaustive match on R, missing B", Warning: true}
+	t.Equal([]Error{warning}, Source([]byte("enum R { A, B }\nmatch (A) { A => 1 }")))
+	t.Equal("2:1: warning: non-exhaustive match on R, missing B", warning.Error())
+}
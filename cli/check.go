@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"fungo/checker"
+	"os"
+)
+
+// fungo check files...
+func runCheck(args []string, streams streams) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(streams.err, "usage: fungo check files...")
+		return 2
+	}
+
+	status := 0
+
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(streams.err, "%s: %s\n", path, err)
+			return 1
+		}
+
+		for _, err := range checker.Source(source) {
+			separator := ":"
+			if err.Line == 0 {
+				separator = ": "
+			}
+
+			fmt.Fprintf(streams.out, "%s%s%s\n", path, separator, err)
+			if !err.Warning {
+				status = 1
+			}
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"fungo/repl"
+	"io"
+	"sort"
+)
+
+// The standard streams a command reads from and writes to, so commands can be run and tested in process
+type streams struct {
+	in  io.Reader
+	out io.Writer
+	err io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(args []string, streams streams) int
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"check":  {usage: "check files...             report type errors without running the files", run: runCheck},
+		"debug":  {usage: "debug [-dap address] file  debug a file from the console or a debug adapter client", run: runDebug},
+		"doc":    {usage: "doc [flags] [paths...]     document the functions of source files, the builtins when no paths are given", run: runDoc},
+		"fmt":    {usage: "fmt [-w] [files...]        format source files, standard input when none are given", run: runFmt},
+		"lint":   {usage: "lint [-json] files...      report likely mistakes without running the files", run: runLint},
+		"lsp":    {usage: "lsp                        serve the language server protocol over standard input and output", run: runLsp},
+		"parse":  {usage: "parse [-json] [file]       print the syntax tree of a file, standard input when none is given", run: runParse},
+		"run":    {usage: "run [flags] file           run a file, see fungo run -h for its flags", run: runRun},
+		"test":   {usage: "test [flags] [paths...]    run the tests of the *_test.fg files, see fungo test -h for its flags", run: runTest},
+		"tokens": {usage: "tokens [-json] [file]      print the tokens of a file, standard input when none is given", run: runTokens},
+	}
+}
+
+// Runs the fungo command line and returns its exit status. Without arguments it starts the REPL, otherwise the first
+// argument names the command to run
+func Run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		repl.Start(stdin, stdout)
+		return 0
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return 0
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "fungo: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	return command.run(args[1:], streams{in: stdin, out: stdout, err: stderr})
+}
+
+func usage(out io.Writer) {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(out, "usage: fungo [command] [arguments]")
+	fmt.Fprintln(out, "\nwithout a command, fungo starts the REPL. commands:")
+
+	for _, name := range names {
+		fmt.Fprintln(out, "  "+commands[name].usage)
+	}
+}
@@ -0,0 +1,395 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"fungo/ast"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CliTestSuite struct {
+	suite.Suite
+}
+
+func TestCliTestSuite(t *testing.T) {
+	suite.Run(t, &CliTestSuite{})
+}
+
+// Runs the command line in process and returns its exit status, standard output and standard error
+func run(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+
+	status := Run(args, strings.NewReader(stdin), &stdout, &stderr)
+
+	return status, stdout.String(), stderr.String()
+}
+
+func writeFile(t *CliTestSuite, name string, content string) string {
+	path := filepath.Join(t.T().TempDir(), name)
+	t.Require().NoError(os.WriteFile(path, []byte(content), 0644))
+
+	return path
+}
+
+func (t *CliTestSuite) TestUnknownCommand() {
+	status, _, stderr := run("", "nope")
+
+	t.Equal(2, status)
+	t.Contains(stderr, `unknown command "nope"`)
+	t.Contains(stderr, "fmt [-w]")
+}
+
+func (t *CliTestSuite) TestFmt() {
+	path := writeFile(t, "main.fg", "let x=1\nx")
+
+	status, stdout, _ := run("", "fmt", path)
+	t.Equal(0, status)
+	t.Equal("let x = 1;\nx\n", stdout)
+
+	content, _ := os.ReadFile(path)
+	t.Equal("let x=1\nx", string(content))
+
+	status, stdout, _ = run("", "fmt", "-w", path)
+	t.Equal(0, status)
+	t.Equal("", stdout)
+
+	content, _ = os.ReadFile(path)
+	t.Equal("let x = 1;\nx\n", string(content))
+}
+
+func (t *CliTestSuite) TestFmtStdin() {
+	status, stdout, _ := run("1+2", "fmt")
+
+	t.Equal(0, status)
+	t.Equal("1 + 2\n", stdout)
+}
+
+func (t *CliTestSuite) TestFmtErrors() {
+	path := writeFile(t, "broken.fg", "let = 1")
+
+	status, _, stderr := run("", "fmt", "-w", path)
+	t.Equal(1, status)
+	t.Contains(stderr, path+": ")
+
+	content, _ := os.ReadFile(path)
+	t.Equal("let = 1", string(content))
+
+	status, _, stderr = run("", "fmt", filepath.Join(t.T().TempDir(), "missing.fg"))
+	t.Equal(1, status)
+	t.Contains(stderr, "no such file")
+}
+
+func (t *CliTestSuite) TestLint() {
+	path := writeFile(t, "main.fg", "let x = 1;\nprint(y)")
+
+	status, stdout, _ := run("", "lint", path)
+	t.Equal(1, status)
+	t.Equal(path+":1:5: warning: let x is never used (unused)\n"+path+":2:7: error: undefined: y (undefined)\n", stdout)
+
+	status, stdout, _ = run("", "lint", writeFile(t, "match.fg", "enum R { A, B }\nmatch (A) { A => 1 }"))
+	t.Equal(1, status)
+	t.Contains(stdout, "match.fg:2:1: warning: non-exhaustive match on R, missing B (non-exhaustive)\n")
+
+	status, stdout, _ = run("", "lint", writeFile(t, "clean.fg", "print(1)"))
+	t.Equal(0, status)
+	t.Equal("", stdout)
+}
+
+func (t *CliTestSuite) TestLintJSON() {
+	path := writeFile(t, "main.fg", "print(y)")
+
+	status, stdout, _ := run("", "lint", "-json", path)
+	t.Equal(1, status)
+
+	diagnostics := []map[string]any{}
+	t.Require().NoError(json.Unmarshal([]byte(stdout), &diagnostics))
+	t.Equal([]map[string]any{{
+		"file":     path,
+		"line":     float64(1),
+		"column":   float64(7),
+		"severity": "error",
+		"rule":     "undefined",
+		"message":  "undefined: y",
+	}}, diagnostics)
+
+	status, stdout, _ = run("", "lint", "-json", writeFile(t, "broken.fg", "let = 1"))
+	t.Equal(1, status)
+	t.Contains(stdout, `"rule": "syntax"`)
+}
+
+func (t *CliTestSuite) TestCheck() {
+	path := writeFile(t, "main.fg", "let add = fn(a: int, b: int): int { a + b };\nadd(1, \"2\")")
+
+	status, stdout, _ := run("", "check", path)
+	t.Equal(1, status)
+	t.Equal(path+":2:8: cannot use string as int in argument 2 to add\n", stdout)
+
+	status, stdout, _ = run("", "check", writeFile(t, "clean.fg", "let x: int = 1 + 2"))
+	t.Equal(0, status)
+	t.Equal("", stdout)
+
+	// Warnings are reported without failing the check
+	status, stdout, _ = run("", "check", writeFile(t, "match.fg", "enum R { A, B }\nmatch (A) { A => 1 }"))
+	t.Equal(0, status)
+	t.Contains(stdout, "match.fg:2:1: warning: non-exhaustive match on R, missing B\n")
+
+	status, stdout, _ = run("", "check", writeFile(t, "broken.fg", "let = 1"))
+	t.Equal(1, status)
+	t.Contains(stdout, `broken.fg: expected next token to be "IDENT"`)
+}
+
+func (t *CliTestSuite) TestRun() {
+	path := writeFile(t, "main.fg", "let f = fn(x: int): int { x };\nlet y = f(\"a\");\nthrow y")
+
+	status, _, stderr := run("", "run", path)
+	t.Equal(1, status)
+	t.Equal(path+": Error: a\n", stderr)
+
+	status, _, stderr = run("", "run", "-types", path)
+	t.Equal(1, status)
+	t.Equal(path+": TypeError: argument x of f must be int, got string\n\tat f (1:25)\n", stderr)
+
+	status, _, stderr = run("", "run", writeFile(t, "ok.fg", "let x = 1"))
+	t.Equal(0, status)
+	t.Equal("", stderr)
+
+	status, _, _ = run("", "run")
+	t.Equal(2, status)
+}
+
+func (t *CliTestSuite) TestRunWarnings() {
+	path := writeFile(t, "main.fg", "enum R { A, B }\nmatch (A) { A => 1 }")
+
+	status, _, stderr := run("", "run", path)
+	t.Equal(0, status)
+	t.Equal(path+":2:1: warning: non-exhaustive match on R, missing B\n", stderr)
+}
+
+func (t *CliTestSuite) TestRunInputOutput() {
+	path := writeFile(t, "main.fg", "let name = input(\"name? \");\nprint(\"hi \" + name, readline(), readline())")
+
+	status, stdout, _ := run("Ada\nLovelace\n", "run", path)
+	t.Equal(0, status)
+	t.Equal("name? hi Ada\nLovelace\nnull\n", stdout)
+}
+
+// What macros print while they expand goes to the command's output as well
+func (t *CliTestSuite) TestMacroOutput() {
+	source := `let trace = macro(x) { print("expanding"); quote(unquote(x)) }; print(trace(1 + 1))`
+
+	status, stdout, _ := run("", "run", writeFile(t, "main.fg", source))
+	t.Equal(0, status)
+	t.Equal("expanding\n2\n", stdout)
+
+	status, stdout, _ = run(source + "\n")
+	t.Equal(0, status)
+	t.Equal("#: expanding\n2\nnull\n#: ", stdout)
+}
+
+func (t *CliTestSuite) TestRunProfile() {
+	path := writeFile(t, "main.fg", "let f = fn(n) { if (n < 1) { 0 } else { f(n - 1) } };\nf(3)")
+	output := filepath.Join(t.T().TempDir(), "main.pprof")
+
+	status, _, stderr := run("", "run", "-profile", output, path)
+	t.Equal(0, status)
+	t.Regexp(`^total \S+, 2 functions\n`, stderr)
+	t.Regexp(`\n\s+4\s.* f \(1:15\)\n`, stderr)
+
+	content, err := os.ReadFile(output)
+	t.Require().NoError(err)
+	t.Equal([]byte{0x1f, 0x8b}, content[:2])
+}
+
+func (t *CliTestSuite) TestRunCover() {
+	path := writeFile(t, "main.fg", "let sign = fn(n) {\n  if (n < 0) { -1 } else { 1 }\n};\nsign(1)")
+
+	status, _, stderr := run("", "run", "-cover", path)
+	t.Equal(0, status)
+	t.Equal(path+": 80.0% of statements, 50.0% of branches\ntotal: 80.0% of statements, 50.0% of branches\n", stderr)
+
+	output := filepath.Join(t.T().TempDir(), "main.lcov")
+	status, _, _ = run("", "run", "-coverprofile", output, path)
+	t.Equal(0, status)
+
+	content, err := os.ReadFile(output)
+	t.Require().NoError(err)
+	t.Contains(string(content), "SF:"+path+"\nBRDA:2,0,0,0\nBRDA:2,0,1,1\n")
+
+	output = filepath.Join(t.T().TempDir(), "main.html")
+	status, _, _ = run("", "run", "-coverprofile", output, path)
+	t.Equal(0, status)
+
+	content, err = os.ReadFile(output)
+	t.Require().NoError(err)
+	t.Contains(string(content), `<span class="line partial" title="if at column 3: then taken 0 times, else 1 times"><span class="number">2</span>  if (n &lt; 0) { -1 } else { 1 }</span>`)
+}
+
+func (t *CliTestSuite) TestTest() {
+	dir := t.T().TempDir()
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "lib.fg"), []byte("export let sign = fn(n) { if (n < 0) { -1 } else { 1 } };"), 0644))
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "lib_test.fg"), []byte("import \"./lib\" as lib;\nlet test_sign = fn() { assert_eq(lib.sign(2), 1) };\nlet test_fails = fn() { assert(false) };"), 0644))
+	path := filepath.Join(dir, "lib_test.fg")
+
+	status, stdout, _ := run("", "test", "-run", "sign", "--cover", dir)
+	t.Equal(0, status)
+	t.Regexp(`^ok   `+regexp.QuoteMeta(path)+` \(1 tests, \S+\)\n1 passed, 0 failed\n`, stdout)
+	t.Contains(stdout, filepath.Join(dir, "lib.fg")+": 75.0% of statements, 50.0% of branches\n")
+
+	status, stdout, _ = run("", "test", "-format", "tap", dir)
+	t.Equal(1, status)
+	t.Contains(stdout, "1..2\nok 1 - "+path+": test_sign\nnot ok 2 - "+path+": test_fails\n")
+
+	status, stdout, _ = run("", "test", "-format", "junit", path)
+	t.Equal(1, status)
+	t.Contains(stdout, `<testsuites tests="2" failures="1" errors="0"`)
+
+	status, _, stderr := run("", "test", t.T().TempDir())
+	t.Equal(0, status)
+	t.Equal("test: no test files\n", stderr)
+
+	// What tests print only goes to standard output along with the text results
+	printing := writeFile(t, "print_test.fg", "let test_print = fn() { print(\"printed\") };")
+
+	status, stdout, _ = run("", "test", printing)
+	t.Equal(0, status)
+	t.True(strings.HasPrefix(stdout, "printed\nok   "))
+
+	status, stdout, stderr = run("", "test", "-format", "tap", printing)
+	t.Equal(0, status)
+	t.NotContains(stdout, "printed")
+	t.Equal("printed\n", stderr)
+
+	status, _, _ = run("", "test", "-format", "yaml")
+	t.Equal(2, status)
+}
+
+func (t *CliTestSuite) TestDoc() {
+	dir := t.T().TempDir()
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "lib.fg"), []byte("// Halves n.\nexport let half = fn(n: int): int { n / 2 };"), 0644))
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "lib_test.fg"), []byte("let test_half = fn() { 1 };"), 0644))
+
+	status, stdout, _ := run("", "doc", dir)
+	t.Equal(0, status)
+	t.Equal("# "+filepath.Join(dir, "lib.fg")+"\n\n## half\n\n```fungo\nexport let half = fn(n: int): int\n```\n\nParameters:\n\n- `n: int`\n\nHalves n.\n", stdout)
+
+	status, stdout, _ = run("", "doc")
+	t.Equal(0, status)
+	t.True(strings.HasPrefix(stdout, "# Builtins\n"))
+	t.Contains(stdout, "## assert_eq\n")
+
+	status, stdout, _ = run("", "doc", "-html", "-builtins", filepath.Join(dir, "lib.fg"))
+	t.Equal(0, status)
+	t.Contains(stdout, "<h3 id=\"file1.half\">half</h3>")
+	t.Contains(stdout, "<h3 id=\"builtins.len\">len</h3>")
+
+	status, _, stderr := run("", "doc", writeFile(t, "broken.fg", "let = 1"))
+	t.Equal(1, status)
+	t.Contains(stderr, "broken.fg: ")
+}
+
+func (t *CliTestSuite) TestParse() {
+	status, stdout, _ := run("let x = 1 + 2 * 3;", "parse")
+	t.Equal(0, status)
+	t.Equal("let x = (1 + (2 * 3));\n", stdout)
+
+	status, stdout, _ = run("", "parse", "--json", writeFile(t, "main.fg", "let x = f(1);"))
+	t.Equal(0, status)
+	t.Contains(stdout, "\"kind\": \"CallExpression\"")
+
+	program, err := ast.DecodeProgramJSON([]byte(stdout))
+	t.Require().NoError(err)
+	t.Equal("let x = f(1);", program.String())
+
+	call := program.Statements[0].(*ast.LetStatement).Value.(*ast.CallExpression)
+	t.Equal(1, call.Token.Line)
+	t.Equal(10, call.Token.Column)
+
+	status, _, stderr := run("let = 1", "parse")
+	t.Equal(1, status)
+	t.Contains(stderr, "<stdin>: ")
+
+	status, _, _ = run("", "parse", "a.fg", "b.fg")
+	t.Equal(2, status)
+}
+
+func (t *CliTestSuite) TestTokens() {
+	status, stdout, _ := run("let x = 1;", "tokens")
+	t.Equal(0, status)
+	t.Equal("1:1 LET \"let\"\n1:5 IDENT \"x\"\n1:7 = \"=\"\n1:9 INT \"1\"\n1:10 ; \";\"\n1:11 EOF \"\"\n", stdout)
+
+	status, stdout, _ = run("x", "tokens", "-json")
+	t.Equal(0, status)
+
+	tokens := []map[string]interface{}{}
+	t.Require().NoError(json.Unmarshal([]byte(stdout), &tokens))
+	t.Equal([]map[string]interface{}{
+		{"type": "IDENT", "literal": "x", "line": 1.0, "column": 1.0},
+		{"type": "EOF", "literal": "", "line": 1.0, "column": 2.0},
+	}, tokens)
+
+	status, _, _ = run("x @ y", "tokens")
+	t.Equal(1, status)
+}
+
+func (t *CliTestSuite) TestLsp() {
+	message := func(content string) string {
+		return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(content), content)
+	}
+
+	input := message(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`) +
+		message(`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`) +
+		message(`{"jsonrpc":"2.0","method":"exit"}`)
+
+	status, stdout, _ := run(input, "lsp")
+	t.Equal(0, status)
+	t.Contains(stdout, `"definitionProvider":true`)
+	t.Contains(stdout, `{"jsonrpc":"2.0","id":2,"result":null}`)
+
+	status, _, stderr := run(message(`{"jsonrpc":"2.0","method":"exit"}`), "lsp")
+	t.Equal(1, status)
+	t.Equal("lsp: exit before shutdown\n", stderr)
+}
+
+func (t *CliTestSuite) TestDebug() {
+	path := writeFile(t, "main.fg", "let double = fn(x) {\n  let y = x * 2;\n  y\n};\nlet a = double(1);\na")
+
+	status, stdout, _ := run("b 2\nc\nbt\np x + 1\nenv\nn\nn\nbreakpoints\nnope\n", "debug", path)
+	t.Equal(0, status)
+	t.Equal(strings.Join([]string{
+		"stopped at " + path + ":1:1 (entry)",
+		"=>    1 | let double = fn(x) {",
+		"(debug) (debug) stopped at " + path + ":2:3 (breakpoint)",
+		"=>    2 |   let y = x * 2;",
+		"(debug) #0 double at " + path + ":2:3",
+		"#1 <main> at " + path + ":5:1",
+		"(debug) 2",
+		"(debug) scope 0:",
+		"  x = 1",
+		"globals:",
+		"  double = fn(x)",
+		"(debug) stopped at " + path + ":3:3 (step)",
+		"=>    3 |   y",
+		"(debug) stopped at " + path + ":6:1 (step)",
+		"=>    6 | a",
+		"(debug) " + path + ":2",
+		`(debug) unknown command "nope", try help`,
+		"(debug) ",
+		"program finished",
+		"",
+	}, "\n"), stdout)
+
+	status, stdout, _ = run("q\n", "debug", path)
+	t.Equal(0, status)
+	t.NotContains(stdout, "program finished")
+
+	status, _, stderr := run("c\n", "debug", writeFile(t, "fail.fg", "throw \"boom\""))
+	t.Equal(1, status)
+	t.Contains(stderr, "fail.fg: Error: boom")
+}
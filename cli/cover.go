@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"fmt"
+	"fungo/evaluator"
+	"io"
+	"os"
+	"strings"
+)
+
+// Writes the coverage summary to summary and, when output is set, the coverage to that file
+func writeCoverage(coverage *evaluator.Coverage, output string, summary io.Writer, streams streams) {
+	coverage.WriteSummary(summary)
+
+	if output == "" {
+		return
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		fmt.Fprintf(streams.err, "coverage: %s\n", err)
+		return
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(output, ".html") {
+		err = coverage.WriteHTML(file)
+	} else {
+		err = coverage.WriteLCOV(file)
+	}
+
+	if err != nil {
+		fmt.Fprintf(streams.err, "coverage: %s\n", err)
+	}
+}
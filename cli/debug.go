@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"fungo/dap"
+	"fungo/evaluator"
+	"fungo/object"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const debugHelp = `commands:
+  break <line>, b <line>  set a breakpoint
+  clear <line>            remove a breakpoint
+  breakpoints             list the breakpoints
+  continue, c             run until the next breakpoint
+  step, s                 step to the next statement, into calls
+  next, n                 step to the next statement, over calls
+  out, o                  step out of the current function
+  print <expr>, p <expr>  evaluate an expression where the program stopped
+  env                     show the bindings of each enclosing scope
+  stack, bt               show the call stack
+  list, l                 show the source around the current line
+  quit, q                 end the program
+`
+
+// fungo debug [-dap address] file
+func runDebug(args []string, streams streams) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+	address := flags.String("dap", "", "serve the debug adapter protocol on `address` instead of the console")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(streams.err, "usage: fungo debug [-dap address] file")
+		return 2
+	}
+
+	path := flags.Arg(0)
+
+	if *address != "" {
+		return serveDap(*address, path, streams)
+	}
+
+	source, _ := os.ReadFile(path)
+	console := &console{streams: streams, path: path, lines: strings.Split(string(source), "\n"), input: bufio.NewReader(streams.in)}
+
+	// The program reads the lines that follow the command resuming it
+	env, ctx := programEnv(path, streams)
+	ctx.Stdin = console.input
+
+	program, ok := load(path, ctx, streams)
+	if !ok {
+		return 1
+	}
+
+	// Attached once macros are expanded, which do not stop
+	debugger := evaluator.NewDebugger(console.stop)
+	debugger.StopOnEntry = true
+	console.debugger = debugger
+	ctx.Debugger = debugger
+
+	if err, ok := evaluator.Eval(program, env).(*object.Error); ok {
+		if debugger.Terminated() {
+			return 0
+		}
+
+		return printRuntimeError(streams, path, err)
+	}
+
+	evaluator.RunEventLoop(env)
+	fmt.Fprintln(streams.out, "program finished")
+
+	return 0
+}
+
+// Accepts a single client and debugs the file for it
+func serveDap(address string, path string, streams streams) int {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		fmt.Fprintf(streams.err, "debug: %s\n", err)
+		return 1
+	}
+	defer listener.Close()
+
+	fmt.Fprintf(streams.out, "debug adapter listening on %s\n", listener.Addr())
+
+	conn, err := listener.Accept()
+	if err != nil {
+		fmt.Fprintf(streams.err, "debug: %s\n", err)
+		return 1
+	}
+	defer conn.Close()
+
+	if err := dap.NewSession(conn, conn, path).Serve(); err != nil {
+		fmt.Fprintf(streams.err, "debug: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// Reads commands from standard input whenever the program stops
+type console struct {
+	streams  streams
+	path     string
+	lines    []string
+	input    *bufio.Reader
+	debugger *evaluator.Debugger
+}
+
+func (c *console) stop(stop *evaluator.Stop) evaluator.DebugCommand {
+	fmt.Fprintf(c.streams.out, "stopped at %s:%d:%d (%s)\n", c.path, stop.Line, stop.Column, stop.Reason)
+	c.list(stop.Line, 0)
+
+	for {
+		fmt.Fprint(c.streams.out, "(debug) ")
+
+		// Once the input is exhausted the program runs to the end
+		line, err := c.input.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(c.streams.out)
+			return evaluator.Continue
+		}
+
+		command, argument, _ := strings.Cut(strings.TrimSpace(line), " ")
+		argument = strings.TrimSpace(argument)
+
+		switch command {
+		case "":
+		case "continue", "c":
+			return evaluator.Continue
+		case "step", "s":
+			return evaluator.StepIn
+		case "next", "n":
+			return evaluator.StepOver
+		case "out", "o":
+			return evaluator.StepOut
+		case "quit", "q":
+			return evaluator.Terminate
+
+		case "break", "b", "clear":
+			line, err := strconv.Atoi(argument)
+			if err != nil || line < 1 {
+				fmt.Fprintf(c.streams.out, "%s: expected a line number, got %q\n", command, argument)
+				continue
+			}
+
+			if command == "clear" {
+				c.debugger.ClearBreakpoint(line)
+			} else {
+				c.debugger.SetBreakpoint(line)
+			}
+
+		case "breakpoints":
+			for _, line := range c.debugger.Breakpoints() {
+				fmt.Fprintf(c.streams.out, "%s:%d\n", c.path, line)
+			}
+
+		case "print", "p":
+			result := stop.Evaluate(argument)
+			if err, ok := result.(*object.Error); ok {
+				fmt.Fprintf(c.streams.out, "%s: %s\n", err.Kind, err.Message)
+			} else if result != nil {
+				fmt.Fprintln(c.streams.out, evaluator.DebugString(result))
+			}
+
+		case "env":
+			c.env(stop.Env)
+
+		case "stack", "bt":
+			for idx, frame := range stop.Frames {
+				fmt.Fprintf(c.streams.out, "#%d %s at %s:%d:%d\n", idx, frame.Name, c.path, frame.Line, frame.Column)
+			}
+
+		case "list", "l":
+			c.list(stop.Line, 3)
+
+		case "help", "h":
+			fmt.Fprint(c.streams.out, debugHelp)
+
+		default:
+			fmt.Fprintf(c.streams.out, "unknown command %q, try help\n", command)
+		}
+	}
+}
+
+// Prints the lines around line, marking it
+func (c *console) list(line int, around int) {
+	for idx := line - around; idx <= line+around; idx++ {
+		if idx < 1 || idx > len(c.lines) {
+			continue
+		}
+
+		marker := "  "
+		if idx == line {
+			marker = "=>"
+		}
+
+		fmt.Fprintf(c.streams.out, "%s %4d | %s\n", marker, idx, c.lines[idx-1])
+	}
+}
+
+// Prints the bindings of env and of every environment enclosing it, innermost first
+func (c *console) env(env *object.Environment) {
+	for depth := 0; env != nil; depth, env = depth+1, env.Outer() {
+		if env.Outer() == nil {
+			fmt.Fprintln(c.streams.out, "globals:")
+		} else {
+			fmt.Fprintf(c.streams.out, "scope %d:\n", depth)
+		}
+
+		for _, name := range env.Names() {
+			value, _ := env.Get(name)
+			fmt.Fprintf(c.streams.out, "  %s = %s\n", name, evaluator.DebugString(value))
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"fungo/doc"
+	"fungo/evaluator"
+	"fungo/tester"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fungo doc [-html] [-builtins] [paths...]
+func runDoc(args []string, streams streams) int {
+	flags := flag.NewFlagSet("doc", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+	asHTML := flags.Bool("html", false, "write a standalone HTML page instead of Markdown")
+	withBuiltins := flags.Bool("builtins", false, "document the builtin functions too, they are when no paths are given")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	paths, err := sourceFiles(flags.Args())
+	if err != nil {
+		fmt.Fprintf(streams.err, "doc: %s\n", err)
+		return 1
+	}
+
+	files := []*doc.File{}
+
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(streams.err, "%s: %s\n", path, err)
+			return 1
+		}
+
+		file, err := doc.Source(path, source)
+		if err != nil {
+			fmt.Fprintf(streams.err, "%s: %s\n", path, err)
+			return 1
+		}
+
+		files = append(files, file)
+	}
+
+	builtins := []doc.Function{}
+	if *withBuiltins || flags.NArg() == 0 {
+		builtins = doc.Builtins()
+	}
+
+	if *asHTML {
+		err = doc.WriteHTML(streams.out, files, builtins)
+	} else {
+		err = doc.WriteMarkdown(streams.out, files, builtins)
+	}
+
+	if err != nil {
+		fmt.Fprintf(streams.err, "doc: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// The source files among paths: directories are searched recursively for modules, leaving out hidden directories and
+// test files, files are taken as they are
+func sourceFiles(paths []string) ([]string, error) {
+	files := []string{}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		found := []string{}
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if entry.IsDir() && file != path && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			if !entry.IsDir() && strings.HasSuffix(file, evaluator.MODULE_EXT) && !strings.HasSuffix(file, tester.TEST_SUFFIX) {
+				found = append(found, file)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"fungo/printer"
+	"io"
+	"os"
+)
+
+// fungo fmt [-w] [files...]
+func runFmt(args []string, streams streams) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+	write := flags.Bool("w", false, "write the result back to the files instead of standard output")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		source, err := io.ReadAll(streams.in)
+		if err != nil {
+			fmt.Fprintf(streams.err, "fmt: %s\n", err)
+			return 1
+		}
+
+		formatted, err := printer.Format(source)
+		if err != nil {
+			fmt.Fprintf(streams.err, "<stdin>: %s\n", err)
+			return 1
+		}
+
+		streams.out.Write(formatted)
+
+		return 0
+	}
+
+	status := 0
+
+	for _, path := range flags.Args() {
+		if err := formatFile(path, *write, streams.out); err != nil {
+			fmt.Fprintf(streams.err, "%s: %s\n", path, err)
+			status = 1
+		}
+	}
+
+	return status
+}
+
+func formatFile(path string, write bool, out io.Writer) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	formatted, err := printer.Format(source)
+	if err != nil {
+		return err
+	}
+
+	if !write {
+		_, err := out.Write(formatted)
+		return err
+	}
+
+	if bytes.Equal(source, formatted) {
+		return nil
+	}
+
+	return os.WriteFile(path, formatted, info.Mode().Perm())
+}
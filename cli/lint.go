@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"fungo/lint"
+	"os"
+)
+
+type fileDiagnostic struct {
+	File string `json:"file"`
+	lint.Diagnostic
+}
+
+// fungo lint [-json] files...
+func runLint(args []string, streams streams) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+	asJSON := flags.Bool("json", false, "print the diagnostics as a JSON array")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(streams.err, "usage: fungo lint [-json] files...")
+		return 2
+	}
+
+	diagnostics := []fileDiagnostic{}
+
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(streams.err, "%s: %s\n", path, err)
+			return 1
+		}
+
+		for _, diagnostic := range lint.Source(source) {
+			diagnostics = append(diagnostics, fileDiagnostic{File: path, Diagnostic: diagnostic})
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(streams.out)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(diagnostics)
+	} else {
+		for _, diagnostic := range diagnostics {
+			separator := ":"
+			if diagnostic.Line == 0 {
+				separator = ": "
+			}
+
+			fmt.Fprintf(streams.out, "%s%s%s\n", diagnostic.File, separator, diagnostic.Diagnostic)
+		}
+	}
+
+	if len(diagnostics) > 0 {
+		return 1
+	}
+
+	return 0
+}
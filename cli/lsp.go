@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"fmt"
+	"fungo/lsp"
+)
+
+// fungo lsp
+func runLsp(args []string, streams streams) int {
+	if len(args) != 0 {
+		fmt.Fprintln(streams.err, "usage: fungo lsp")
+		return 2
+	}
+
+	if err := lsp.NewServer(streams.in, streams.out).Serve(); err != nil {
+		fmt.Fprintf(streams.err, "lsp: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
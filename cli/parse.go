@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"fungo/ast"
+	"fungo/lexer"
+	"fungo/parser"
+	"fungo/token"
+	"io"
+	"os"
+	"strings"
+)
+
+// fungo parse [-json] [file]
+func runParse(args []string, streams streams) int {
+	flags := flag.NewFlagSet("parse", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+	asJSON := flags.Bool("json", false, "print the syntax tree as JSON, with the kind, fields and position of every node")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	path, source, status := readSource("parse", flags, streams)
+	if status != 0 {
+		return status
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(source)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		fmt.Fprintf(streams.err, "%s: %s\n", path, strings.Join(p.Errors(), "\n"))
+		return 1
+	}
+
+	if !*asJSON {
+		fmt.Fprintln(streams.out, program.String())
+		return 0
+	}
+
+	encoded, err := ast.EncodeJSON(program)
+	if err != nil {
+		fmt.Fprintf(streams.err, "%s: %s\n", path, err)
+		return 1
+	}
+
+	indented := &bytes.Buffer{}
+	json.Indent(indented, encoded, "", "  ")
+	indented.WriteString("\n")
+	indented.WriteTo(streams.out)
+
+	return 0
+}
+
+// fungo tokens [-json] [file]
+func runTokens(args []string, streams streams) int {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+	asJSON := flags.Bool("json", false, "print the tokens as a JSON array of their type, literal, line and column")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	_, source, status := readSource("tokens", flags, streams)
+	if status != 0 {
+		return status
+	}
+
+	l := lexer.NewLexer(string(source))
+	tokens := []token.Token{}
+
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(streams.out)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(tokens)
+	} else {
+		for _, tok := range tokens {
+			fmt.Fprintf(streams.out, "%d:%d %s %q\n", tok.Line, tok.Column, tok.Type, tok.Literal)
+		}
+	}
+
+	for _, tok := range tokens {
+		if tok.Type == token.ILLEGAL {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// Reads the only file argument, or standard input when there is none. The status is not 0 when it could not be read
+func readSource(name string, flags *flag.FlagSet, streams streams) (string, []byte, int) {
+	if flags.NArg() > 1 {
+		fmt.Fprintf(streams.err, "usage: fungo %s [-json] [file]\n", name)
+		return "", nil, 2
+	}
+
+	path, reader := "<stdin>", streams.in
+	if flags.NArg() == 1 {
+		path = flags.Arg(0)
+
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(streams.err, "%s: %s\n", name, err)
+			return path, nil, 1
+		}
+		defer file.Close()
+
+		reader = file
+	}
+
+	source, err := io.ReadAll(reader)
+	if err != nil {
+		fmt.Fprintf(streams.err, "%s: %s\n", path, err)
+		return path, nil, 1
+	}
+
+	return path, source, 0
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"fungo/ast"
+	"fungo/evaluator"
+	"fungo/lexer"
+	"fungo/object"
+	"fungo/parser"
+	"os"
+	"path/filepath"
+)
+
+// fungo run [-types] [-profile file] [-cover] [-coverprofile file] file
+func runRun(args []string, streams streams) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+	checkTypes := flags.Bool("types", false, "enforce type annotations when functions are called and lets bound")
+	profile := flags.String("profile", "", "write a pprof profile to `file` and a report of the calls to standard error")
+	cover := flags.Bool("cover", false, "report the statements and branches covered to standard error")
+	coverProfile := flags.String("coverprofile", "", "write the coverage to `file`, as LCOV or as HTML when it ends in .html")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(streams.err, "usage: fungo run [-types] [-profile file] [-cover] [-coverprofile file] file")
+		return 2
+	}
+
+	path := flags.Arg(0)
+	env, ctx := programEnv(path, streams)
+	program, ok := load(path, ctx, streams)
+	if !ok {
+		return 1
+	}
+
+	ctx.CheckTypes = *checkTypes
+
+	if *profile != "" {
+		ctx.Profiler = evaluator.NewProfiler()
+		defer writeProfile(ctx.Profiler, *profile, path, streams)
+	}
+
+	if *cover || *coverProfile != "" {
+		ctx.Coverage = evaluator.NewCoverage()
+		ctx.Coverage.Add(path, program)
+		defer writeCoverage(ctx.Coverage, *coverProfile, streams.err, streams)
+	}
+
+	if err, ok := evaluator.Eval(program, env).(*object.Error); ok {
+		return printRuntimeError(streams, path, err)
+	}
+
+	evaluator.RunEventLoop(env)
+
+	return 0
+}
+
+// Profiles are written whether the program succeeded or failed
+func writeProfile(profiler *evaluator.Profiler, output string, path string, streams streams) {
+	profiler.Stop()
+	profiler.WriteReport(streams.err)
+
+	file, err := os.Create(output)
+	if err != nil {
+		fmt.Fprintf(streams.err, "profile: %s\n", err)
+		return
+	}
+	defer file.Close()
+
+	if err := profiler.WritePprof(file, path); err != nil {
+		fmt.Fprintf(streams.err, "profile: %s\n", err)
+	}
+}
+
+// Reads and parses a file and expands its macros in ctx, reporting what went wrong when it fails
+func load(path string, ctx *evaluator.Context, streams streams) (ast.Node, bool) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(streams.err, "%s: %s\n", path, err)
+		return nil, false
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(source)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintf(streams.err, "%s: %s\n", path, msg)
+		}
+
+		return nil, false
+	}
+
+	for _, warning := range p.Warnings() {
+		fmt.Fprintf(streams.err, "%s:%d:%d: warning: %s\n", path, warning.Token.Line, warning.Token.Column, warning.Message)
+	}
+
+	macroEnv := object.NewEnvironment()
+	evaluator.AttachContext(macroEnv, ctx)
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, expandErr := evaluator.ExpandMacros(program, macroEnv)
+	if expandErr != nil {
+		printRuntimeError(streams, path, expandErr)
+		return nil, false
+	}
+
+	return expanded, true
+}
+
+// The environment a file runs in, relative imports are resolved against its directory. The program reads and writes
+// the command's streams
+func programEnv(path string, streams streams) (*object.Environment, *evaluator.Context) {
+	env := object.NewEnvironment()
+	ctx := evaluator.NewContext()
+	ctx.Dir = filepath.Dir(path)
+	ctx.Stdin = bufio.NewReader(streams.in)
+	ctx.Stdout = streams.out
+	ctx.Stderr = streams.err
+	evaluator.AttachContext(env, ctx)
+
+	return env, ctx
+}
+
+func printRuntimeError(streams streams, path string, err *object.Error) int {
+	fmt.Fprintf(streams.err, "%s: %s: %s\n", path, err.Kind, err.Message)
+
+	for _, frame := range err.Trace {
+		fmt.Fprintln(streams.err, "\t"+frame)
+	}
+
+	return 1
+}
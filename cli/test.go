@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"fungo/evaluator"
+	"fungo/tester"
+	"regexp"
+)
+
+// fungo test [-run regexp] [-v] [-format text|tap|junit] [-types] [-cover] [-coverprofile file] [paths...]
+func runTest(args []string, streams streams) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(streams.err)
+	pattern := flags.String("run", "", "only run the tests whose name matches `regexp`")
+	verbose := flags.Bool("v", false, "list the tests that passed too")
+	format := flags.String("format", "text", "print the results as `text`, tap or junit")
+	checkTypes := flags.Bool("types", false, "enforce type annotations when functions are called and lets bound")
+	cover := flags.Bool("cover", false, "report the statements and branches of the imported modules the tests covered")
+	coverProfile := flags.String("coverprofile", "", "write the coverage to `file`, as LCOV or as HTML when it ends in .html")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *format != "text" && *format != "tap" && *format != "junit" {
+		fmt.Fprintf(streams.err, "test: unknown format %q\n", *format)
+		return 2
+	}
+
+	options := tester.Options{CheckTypes: *checkTypes}
+
+	if *pattern != "" {
+		run, err := regexp.Compile(*pattern)
+		if err != nil {
+			fmt.Fprintf(streams.err, "test: %s\n", err)
+			return 2
+		}
+
+		options.Run = run
+	}
+
+	if *cover || *coverProfile != "" {
+		options.Coverage = evaluator.NewCoverage()
+	}
+
+	// What the tests print comes before the results, the other formats are meant for tools that would not expect it
+	options.Output = streams.out
+	if *format != "text" {
+		options.Output = streams.err
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := tester.Discover(paths)
+	if err != nil {
+		fmt.Fprintf(streams.err, "test: %s\n", err)
+		return 1
+	}
+
+	if len(files) == 0 {
+		fmt.Fprintln(streams.err, "test: no test files")
+		return 0
+	}
+
+	results := []tester.FileResult{}
+	failed := 0
+
+	for _, file := range files {
+		result := tester.RunFile(file, options)
+		results = append(results, result)
+		failed += result.Failed()
+	}
+
+	switch *format {
+	case "tap":
+		tester.WriteTAP(streams.out, results)
+	case "junit":
+		tester.WriteJUnit(streams.out, results)
+	default:
+		tester.WriteText(streams.out, results, *verbose)
+	}
+
+	if options.Coverage != nil {
+		writeCoverage(options.Coverage, *coverProfile, options.Output, streams)
+	}
+
+	if failed > 0 {
+		return 1
+	}
+
+	return 0
+}
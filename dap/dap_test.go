@@ -0,0 +1,239 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DapTestSuite struct {
+	suite.Suite
+	in   *bufio.Reader
+	out  io.WriteCloser
+	seq  int
+	done chan error
+}
+
+func TestDapTestSuite(t *testing.T) {
+	suite.Run(t, &DapTestSuite{})
+}
+
+// A message from the session, either a response or an event
+type received struct {
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+const program = `let double = fn(x) {
+  let y = x * 2;
+  y
+};
+let a = double(1);
+a`
+
+func (t *DapTestSuite) SetupTest() {
+	path := filepath.Join(t.T().TempDir(), "main.fg")
+	t.Require().NoError(os.WriteFile(path, []byte(program), 0644))
+
+	sessionIn, clientOut := io.Pipe()
+	clientIn, sessionOut := io.Pipe()
+
+	t.in, t.out, t.seq, t.done = bufio.NewReader(clientIn), clientOut, 0, make(chan error, 1)
+
+	go func() {
+		err := NewSession(sessionIn, sessionOut, path).Serve()
+		sessionOut.Close()
+		t.done <- err
+	}()
+}
+
+func (t *DapTestSuite) send(command string, arguments any) {
+	t.seq += 1
+
+	content, err := json.Marshal(arguments)
+	t.Require().NoError(err)
+	t.Require().NoError(writeMessage(t.out, request{Seq: t.seq, Type: "request", Command: command, Arguments: content}))
+}
+
+func (t *DapTestSuite) receive() received {
+	content, err := readMessage(t.in)
+	t.Require().NoError(err)
+
+	msg := received{}
+	t.Require().NoError(json.Unmarshal(content, &msg))
+
+	return msg
+}
+
+// Sends a request and decodes the body of its response
+func (t *DapTestSuite) request(command string, arguments any, body any) received {
+	t.send(command, arguments)
+
+	msg := t.receive()
+	t.Require().Equal("response", msg.Type)
+	t.Require().Equal(command, msg.Command)
+	t.Require().Equal(t.seq, msg.RequestSeq)
+
+	if body != nil && msg.Success {
+		t.Require().NoError(json.Unmarshal(msg.Body, body))
+	}
+
+	return msg
+}
+
+func (t *DapTestSuite) expectEvent(name string, body any) {
+	msg := t.receive()
+	t.Require().Equal("event", msg.Type)
+	t.Require().Equal(name, msg.Event)
+
+	if body != nil {
+		t.Require().NoError(json.Unmarshal(msg.Body, body))
+	}
+}
+
+func (t *DapTestSuite) launch(stopOnEntry bool, breakpoints ...int) {
+	capabilities := Capabilities{}
+	t.True(t.request("initialize", map[string]any{"adapterID": "fungo"}, &capabilities).Success)
+	t.True(capabilities.SupportsConfigurationDoneRequest)
+	t.expectEvent("initialized", nil)
+
+	t.True(t.request("launch", LaunchArguments{StopOnEntry: stopOnEntry}, nil).Success)
+
+	lines := []SourceBreakpoint{}
+	for _, line := range breakpoints {
+		lines = append(lines, SourceBreakpoint{Line: line})
+	}
+
+	verified := struct{ Breakpoints []Breakpoint }{}
+	t.True(t.request("setBreakpoints", SetBreakpointsArguments{Breakpoints: lines}, &verified).Success)
+	t.Len(verified.Breakpoints, len(breakpoints))
+
+	t.True(t.request("configurationDone", nil, nil).Success)
+}
+
+func (t *DapTestSuite) TestBreakpointsAndInspection() {
+	t.launch(false, 2)
+
+	stopped := StoppedEvent{}
+	t.expectEvent("stopped", &stopped)
+	t.Equal(StoppedEvent{Reason: "breakpoint", ThreadID: 1, AllThreadsStopped: true}, stopped)
+
+	trace := struct{ StackFrames []StackFrame }{}
+	t.request("stackTrace", map[string]any{"threadId": 1}, &trace)
+	t.Require().Len(trace.StackFrames, 2)
+	t.Equal("double", trace.StackFrames[0].Name)
+	t.Equal(2, trace.StackFrames[0].Line)
+	t.Equal("<main>", trace.StackFrames[1].Name)
+	t.Equal(5, trace.StackFrames[1].Line)
+	t.Equal("main.fg", trace.StackFrames[0].Source.Name)
+
+	scopes := struct{ Scopes []Scope }{}
+	t.request("scopes", ScopesArguments{FrameID: 1}, &scopes)
+	t.Require().Len(scopes.Scopes, 2)
+	t.Equal("Locals", scopes.Scopes[0].Name)
+	t.Equal("Globals", scopes.Scopes[1].Name)
+
+	variables := struct{ Variables []Variable }{}
+	t.request("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}, &variables)
+	t.Equal([]Variable{{Name: "x", Value: "1", Type: "INTEGER"}}, variables.Variables)
+
+	t.request("variables", VariablesArguments{VariablesReference: scopes.Scopes[1].VariablesReference}, &variables)
+	t.Equal([]Variable{{Name: "double", Value: "fn(x)", Type: "FUNCTION"}}, variables.Variables)
+
+	result := struct{ Result string }{}
+	t.request("evaluate", EvaluateArguments{Expression: "x + 10"}, &result)
+	t.Equal("11", result.Result)
+
+	failed := t.request("evaluate", EvaluateArguments{Expression: "x + 10", FrameID: 2}, nil)
+	t.False(failed.Success)
+	t.Equal("RuntimeError: identifier not found: x", failed.Message)
+
+	t.True(t.request("next", map[string]any{"threadId": 1}, nil).Success)
+	t.expectEvent("stopped", &stopped)
+	t.Equal("step", stopped.Reason)
+
+	t.request("stackTrace", map[string]any{"threadId": 1}, &trace)
+	t.Equal(3, trace.StackFrames[0].Line)
+
+	t.True(t.request("continue", map[string]any{"threadId": 1}, nil).Success)
+
+	exited := ExitedEvent{}
+	t.expectEvent("exited", &exited)
+	t.Equal(0, exited.ExitCode)
+	t.expectEvent("terminated", nil)
+
+	t.False(t.request("continue", map[string]any{"threadId": 1}, nil).Success)
+	t.True(t.request("disconnect", nil, nil).Success)
+	t.NoError(<-t.done)
+}
+
+func (t *DapTestSuite) TestStopOnEntryAndDisconnect() {
+	t.launch(true)
+
+	stopped := StoppedEvent{}
+	t.expectEvent("stopped", &stopped)
+	t.Equal("entry", stopped.Reason)
+
+	// Disconnecting ends the program, which reports it exited
+	t.send("disconnect", nil)
+	t.expectEvent("exited", nil)
+	t.expectEvent("terminated", nil)
+
+	msg := t.receive()
+	t.Equal("disconnect", msg.Command)
+	t.True(msg.Success)
+	t.NoError(<-t.done)
+}
+
+func (t *DapTestSuite) TestProgramOutput() {
+	path := filepath.Join(t.T().TempDir(), "print.fg")
+	t.Require().NoError(os.WriteFile(path, []byte(`print("hello"); input()`), 0644))
+
+	t.request("initialize", nil, nil)
+	t.expectEvent("initialized", nil)
+	t.True(t.request("launch", LaunchArguments{Program: path}, nil).Success)
+	t.True(t.request("configurationDone", nil, nil).Success)
+
+	// The program has no input, so input() fails once it printed
+	output := OutputEvent{}
+	t.expectEvent("output", &output)
+	t.Equal(OutputEvent{Category: "stdout", Output: "hello\n"}, output)
+
+	t.expectEvent("output", &output)
+	t.Equal("stderr", output.Category)
+	t.True(strings.HasPrefix(output.Output, "RuntimeError: input: end of input\n"))
+
+	t.expectEvent("exited", nil)
+	t.expectEvent("terminated", nil)
+	t.True(t.request("disconnect", nil, nil).Success)
+	t.NoError(<-t.done)
+}
+
+func (t *DapTestSuite) TestLaunchErrors() {
+	t.request("initialize", nil, nil)
+	t.expectEvent("initialized", nil)
+
+	msg := t.request("launch", LaunchArguments{Program: "missing.fg"}, nil)
+	t.False(msg.Success)
+	t.Contains(msg.Message, "missing.fg")
+
+	msg = t.request("configurationDone", nil, nil)
+	t.False(msg.Success)
+
+	msg = t.request("restartFrame", nil, nil)
+	t.Equal("unsupported command restartFrame", msg.Message)
+
+	t.out.Close()
+	t.NoError(<-t.done)
+}
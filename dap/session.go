@@ -0,0 +1,490 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fungo/ast"
+	"fungo/evaluator"
+	"fungo/lexer"
+	"fungo/object"
+	"fungo/parser"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The program is the only thread
+const threadID = 1
+
+// A debugging session for one client, which launches the program and controls it. Requests are read on the goroutine
+// calling Serve while the program runs on another, blocked on the client whenever it stops
+type Session struct {
+	in   *bufio.Reader
+	out  io.Writer
+	path string
+
+	writing sync.Mutex
+	seq     int
+
+	debugger *evaluator.Debugger
+	program  ast.Node
+	env      *object.Environment
+	resume   chan evaluator.DebugCommand
+	done     chan struct{}
+	started  bool
+
+	mu         sync.Mutex
+	stop       *evaluator.Stop
+	references map[int]*object.Environment
+}
+
+// Creates a session debugging the file at path, unless the launch request names another program
+func NewSession(in io.Reader, out io.Writer, path string) *Session {
+	return &Session{
+		in:     bufio.NewReader(in),
+		out:    out,
+		path:   path,
+		resume: make(chan evaluator.DebugCommand),
+		done:   make(chan struct{}),
+	}
+}
+
+// Serves requests until the client disconnects, ending the program if it is still running
+func (s *Session) Serve() error {
+	defer s.terminate()
+
+	for {
+		content, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		req := request{}
+		if err := json.Unmarshal(content, &req); err != nil {
+			return err
+		}
+
+		if req.Type != "request" {
+			continue
+		}
+
+		body, failed := s.handle(req)
+		if err := s.respond(req, body, failed); err != nil {
+			return err
+		}
+
+		if failed != nil {
+			continue
+		}
+
+		switch req.Command {
+		case "initialize":
+			if err := s.event("initialized", nil); err != nil {
+				return err
+			}
+
+		case "configurationDone":
+			s.start()
+
+		case "continue", "next", "stepIn", "stepOut":
+			// Resumed after responding, so the response comes before the next stopped event
+			s.resume <- commands[req.Command]
+
+		case "disconnect", "terminate":
+			return nil
+		}
+	}
+}
+
+var commands = map[string]evaluator.DebugCommand{
+	"continue": evaluator.Continue,
+	"next":     evaluator.StepOver,
+	"stepIn":   evaluator.StepIn,
+	"stepOut":  evaluator.StepOut,
+}
+
+func (s *Session) handle(req request) (any, error) {
+	switch req.Command {
+	case "initialize":
+		return Capabilities{SupportsConfigurationDoneRequest: true, SupportsEvaluateForHovers: true, SupportsTerminateRequest: true}, nil
+
+	case "launch":
+		args := LaunchArguments{}
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		return nil, s.launch(args)
+
+	case "setBreakpoints":
+		args := SetBreakpointsArguments{}
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		return s.setBreakpoints(args)
+
+	case "setExceptionBreakpoints":
+		return nil, nil
+
+	case "configurationDone":
+		if s.debugger == nil {
+			return nil, errors.New("no program launched")
+		}
+
+		return nil, nil
+
+	case "threads":
+		return map[string]any{"threads": []Thread{{ID: threadID, Name: "main"}}}, nil
+
+	case "stackTrace":
+		return s.stackTrace(), nil
+
+	case "scopes":
+		args := ScopesArguments{}
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		return s.scopes(args.FrameID)
+
+	case "variables":
+		args := VariablesArguments{}
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		return s.variables(args.VariablesReference)
+
+	case "evaluate":
+		args := EvaluateArguments{}
+		if err := decode(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		return s.evaluate(args)
+
+	case "continue", "next", "stepIn", "stepOut":
+		if s.current() == nil {
+			return nil, errors.New("the program is not stopped")
+		}
+
+		if req.Command == "continue" {
+			return map[string]any{"allThreadsContinued": true}, nil
+		}
+
+		return nil, nil
+
+	case "disconnect", "terminate":
+		s.terminate()
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unsupported command %s", req.Command)
+}
+
+func decode(arguments json.RawMessage, value any) error {
+	if len(arguments) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(arguments, value)
+}
+
+func (s *Session) respond(req request, body any, err error) error {
+	resp := response{Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+
+	s.writing.Lock()
+	defer s.writing.Unlock()
+
+	s.seq += 1
+	resp.Seq = s.seq
+
+	return writeMessage(s.out, resp)
+}
+
+func (s *Session) event(name string, body any) error {
+	s.writing.Lock()
+	defer s.writing.Unlock()
+
+	s.seq += 1
+
+	return writeMessage(s.out, event{Seq: s.seq, Type: "event", Event: name, Body: body})
+}
+
+/* ================================ Running ================================ */
+
+func (s *Session) launch(args LaunchArguments) error {
+	if s.debugger != nil {
+		return errors.New("a program was already launched")
+	}
+
+	if args.Program != "" {
+		s.path = args.Program
+	}
+
+	ctx := evaluator.NewContext()
+	ctx.Dir = filepath.Dir(s.path)
+	ctx.Stdin = strings.NewReader("")
+	ctx.Stdout = output{session: s, category: "stdout"}
+	ctx.Stderr = output{session: s, category: "stderr"}
+
+	program, err := load(s.path, ctx)
+	if err != nil {
+		return err
+	}
+
+	// Attached once macros are expanded, which do not stop
+	s.program = program
+	s.debugger = evaluator.NewDebugger(s.onStop)
+	s.debugger.StopOnEntry = args.StopOnEntry
+	ctx.Debugger = s.debugger
+
+	s.env = object.NewEnvironment()
+	evaluator.AttachContext(s.env, ctx)
+
+	return nil
+}
+
+// What the program prints is sent to the client in output events, it has no input as the client does not provide any
+type output struct {
+	session  *Session
+	category string
+}
+
+func (o output) Write(p []byte) (int, error) {
+	if err := o.session.event("output", OutputEvent{Category: o.category, Output: string(p)}); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Reads and parses a file and expands its macros in ctx
+func load(path string, ctx *evaluator.Context) (ast.Node, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(source)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s: %s", path, strings.Join(p.Errors(), "\n"))
+	}
+
+	macroEnv := object.NewEnvironment()
+	evaluator.AttachContext(macroEnv, ctx)
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, expandErr := evaluator.ExpandMacros(program, macroEnv)
+	if expandErr != nil {
+		return nil, fmt.Errorf("%s: %s", path, expandErr.Message)
+	}
+
+	return expanded, nil
+}
+
+// Runs the program once the client is done configuring breakpoints, then reports how it exited
+func (s *Session) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+
+	go func() {
+		defer close(s.done)
+
+		exitCode := 0
+
+		if err, ok := evaluator.Eval(s.program, s.env).(*object.Error); ok {
+			if !s.debugger.Terminated() {
+				output := fmt.Sprintf("%s: %s\n", err.Kind, err.Message)
+				for _, frame := range err.Trace {
+					output += "\t" + frame + "\n"
+				}
+
+				s.event("output", OutputEvent{Category: "stderr", Output: output})
+			}
+
+			exitCode = 1
+		} else {
+			evaluator.RunEventLoop(s.env)
+		}
+
+		s.event("exited", ExitedEvent{ExitCode: exitCode})
+		s.event("terminated", nil)
+	}()
+}
+
+// Called on the program's goroutine when it stops, waits for the client to resume it
+func (s *Session) onStop(stop *evaluator.Stop) evaluator.DebugCommand {
+	s.mu.Lock()
+	s.stop = stop
+	s.references = make(map[int]*object.Environment)
+	s.mu.Unlock()
+
+	s.event("stopped", StoppedEvent{Reason: stop.Reason, ThreadID: threadID, AllThreadsStopped: true})
+
+	command := <-s.resume
+
+	s.mu.Lock()
+	s.stop = nil
+	s.mu.Unlock()
+
+	return command
+}
+
+func (s *Session) current() *evaluator.Stop {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stop
+}
+
+// Ends the program if it is running and waits for it to be done
+func (s *Session) terminate() {
+	if !s.started {
+		return
+	}
+
+	s.debugger.Terminate()
+
+	select {
+	case s.resume <- evaluator.Terminate:
+		<-s.done
+	case <-s.done:
+	}
+}
+
+/* =============================== Inspection ============================== */
+
+func (s *Session) setBreakpoints(args SetBreakpointsArguments) (any, error) {
+	if s.debugger == nil {
+		return nil, errors.New("no program launched")
+	}
+
+	lines := []int{}
+	breakpoints := []Breakpoint{}
+
+	for _, breakpoint := range args.Breakpoints {
+		lines = append(lines, breakpoint.Line)
+		breakpoints = append(breakpoints, Breakpoint{Verified: true, Line: breakpoint.Line})
+	}
+
+	s.debugger.SetBreakpoints(lines)
+
+	return map[string]any{"breakpoints": breakpoints}, nil
+}
+
+// Frame ids are 1-based indices in the frames of the current stop, innermost first
+func (s *Session) frame(id int) (*evaluator.Frame, error) {
+	stop := s.current()
+	if stop == nil {
+		return nil, errors.New("the program is not stopped")
+	}
+
+	if id < 1 || id > len(stop.Frames) {
+		return nil, fmt.Errorf("unknown frame %d", id)
+	}
+
+	return &stop.Frames[id-1], nil
+}
+
+func (s *Session) stackTrace() any {
+	frames := []StackFrame{}
+
+	if stop := s.current(); stop != nil {
+		source := Source{Name: filepath.Base(s.path), Path: s.path}
+
+		for idx, frame := range stop.Frames {
+			frames = append(frames, StackFrame{ID: idx + 1, Name: frame.Name, Source: source, Line: frame.Line, Column: frame.Column})
+		}
+	}
+
+	return map[string]any{"stackFrames": frames, "totalFrames": len(frames)}
+}
+
+// A scope for the environment of the frame and for each one enclosing it
+func (s *Session) scopes(id int) (any, error) {
+	frame, err := s.frame(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scopes := []Scope{}
+	for env := frame.Env; env != nil; env = env.Outer() {
+		reference := len(s.references) + 1
+		s.references[reference] = env
+
+		name := "Closure"
+		switch {
+		case env.Outer() == nil:
+			name = "Globals"
+		case env == frame.Env:
+			name = "Locals"
+		}
+
+		scopes = append(scopes, Scope{Name: name, VariablesReference: reference})
+	}
+
+	return map[string]any{"scopes": scopes}, nil
+}
+
+func (s *Session) variables(reference int) (any, error) {
+	s.mu.Lock()
+	env, ok := s.references[reference]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown variables reference %d", reference)
+	}
+
+	variables := []Variable{}
+	for _, name := range env.Names() {
+		value, _ := env.Get(name)
+		variables = append(variables, Variable{Name: name, Value: evaluator.DebugString(value), Type: string(value.Type())})
+	}
+
+	return map[string]any{"variables": variables}, nil
+}
+
+// Evaluates an expression in the environment of a frame, the innermost one when no frame is given
+func (s *Session) evaluate(args EvaluateArguments) (any, error) {
+	if args.FrameID == 0 {
+		args.FrameID = 1
+	}
+
+	frame, err := s.frame(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := *s.current()
+	stop.Env = frame.Env
+
+	result := stop.Evaluate(args.Expression)
+	if err, ok := result.(*object.Error); ok {
+		return nil, fmt.Errorf("%s: %s", err.Kind, err.Message)
+	}
+
+	value := ""
+	if result != nil {
+		value = evaluator.DebugString(result)
+	}
+
+	return map[string]any{"result": value, "variablesReference": 0}, nil
+}
@@ -0,0 +1,218 @@
+package doc
+
+import (
+	"errors"
+	"fungo/ast"
+	"fungo/evaluator"
+	"fungo/lexer"
+	"fungo/parser"
+	"fungo/token"
+	"strings"
+)
+
+// The documentation of a file: the comment opening it, when a blank line separates it from the first statement, and
+// the functions bound by its top level lets
+type File struct {
+	Path      string
+	Doc       string
+	Functions []Function
+}
+
+// The documentation of a function. Doc holds the `//` comment lines right above its let, without the slashes
+type Function struct {
+	Name string
+	Doc  string
+	Line int
+
+	// The head of the function literal, such as `fn(a: int, b = 1): int`, or the signature of a built in
+	Signature  string
+	Parameters []Parameter
+	Return     string
+
+	// The type annotation of the let binding the function, if any
+	Annotation string
+
+	Exported bool
+	Async    bool
+}
+
+// A parameter as written: the name or destructuring pattern, the type annotation and the default value if any
+type Parameter struct {
+	Name    string
+	Type    string
+	Default string
+	Rest    bool
+}
+
+// Parses a file and documents its top level functions, in source order
+func Source(path string, source []byte) (*File, error) {
+	l := lexer.NewLexer(string(source))
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	lines := strings.Split(string(source), "\n")
+	comments := ownLineComments(l.Comments(), lines)
+	file := &File{Path: path, Functions: []Function{}}
+
+	for _, statement := range program.Statements {
+		exported := false
+		line := 0
+
+		if export, ok := statement.(*ast.ExportStatement); ok {
+			exported, line = true, export.Token.Line
+			statement = export.Statement
+		}
+
+		let, ok := statement.(*ast.LetStatement)
+		if !ok || let.Name == nil {
+			continue
+		}
+
+		literal, ok := let.Value.(*ast.FunctionLiteral)
+		if !ok {
+			continue
+		}
+
+		if line == 0 {
+			line = let.Token.Line
+		}
+
+		function := describe(literal)
+		function.Name = let.Name.Value
+		function.Doc = docAbove(comments, line)
+		function.Line = line
+		function.Exported = exported
+
+		if let.Type != nil {
+			function.Annotation = let.Type.String()
+		}
+
+		file.Functions = append(file.Functions, function)
+	}
+
+	// Like in Go, the comment opening the file documents it when a blank line follows
+	end := 1
+	for comments[end] != "" {
+		end += 1
+	}
+
+	if end > 1 && (end > len(lines) || strings.TrimSpace(lines[end-1]) == "") {
+		file.Doc = docAbove(comments, end)
+	}
+
+	return file, nil
+}
+
+// Documents every built in function, sorted by name. Their signatures are type annotations, their parameters have
+// no names
+func Builtins() []Function {
+	functions := []Function{}
+
+	for _, name := range evaluator.BuiltInNames() {
+		function := Function{Name: name}
+		function.Doc, _ = evaluator.BuiltInDoc(name)
+		function.Signature, _ = evaluator.BuiltInSignature(name)
+
+		functions = append(functions, function)
+	}
+
+	return functions
+}
+
+// The comments alone on their line by line number, their text without the slashes
+func ownLineComments(comments []token.Token, lines []string) map[int]string {
+	text := map[int]string{}
+
+	for _, comment := range comments {
+		if strings.TrimSpace(lines[comment.Line-1][:comment.Column-1]) != "" {
+			continue
+		}
+
+		content := strings.TrimPrefix(comment.Literal, "//")
+		content = strings.TrimPrefix(content, " ")
+
+		// Empty comment lines separate paragraphs, they still need to be told apart from no comment at all
+		if content == "" {
+			content = "\n"
+		}
+
+		text[comment.Line] = content
+	}
+
+	return text
+}
+
+// The block of comment lines ending right above line
+func docAbove(comments map[int]string, line int) string {
+	lines := []string{}
+
+	for idx := line - 1; comments[idx] != ""; idx-- {
+		lines = append([]string{strings.TrimSuffix(comments[idx], "\n")}, lines...)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// The signature and parameters of a function literal
+func describe(literal *ast.FunctionLiteral) Function {
+	function := Function{Async: literal.IsAsync, Parameters: []Parameter{}}
+	params := []string{}
+
+	for idx, param := range literal.Parameters {
+		parameter := Parameter{}
+
+		switch param := param.(type) {
+		case *ast.DefaultParameter:
+			parameter.Name = param.Target.String()
+			parameter.Default = param.Value.String()
+		case *ast.RestElement:
+			parameter.Name = param.Target.String()
+			parameter.Rest = true
+		default:
+			parameter.Name = param.String()
+		}
+
+		if annotation := literal.ParameterType(idx); annotation != nil {
+			parameter.Type = annotation.String()
+		}
+
+		function.Parameters = append(function.Parameters, parameter)
+		params = append(params, parameter.String())
+	}
+
+	if literal.ReturnType != nil {
+		function.Return = literal.ReturnType.String()
+	}
+
+	function.Signature = "fn(" + strings.Join(params, ", ") + ")"
+	if function.Return != "" {
+		function.Signature += ": " + function.Return
+	}
+	if function.Async {
+		function.Signature = "async " + function.Signature
+	}
+
+	return function
+}
+
+// The parameter as written in a function head
+func (p Parameter) String() string {
+	text := p.Name
+	if p.Rest {
+		text = "..." + text
+	}
+
+	if p.Type != "" {
+		text += ": " + p.Type
+	}
+
+	if p.Default != "" {
+		text += " = " + p.Default
+	}
+
+	return text
+}
@@ -0,0 +1,164 @@
+package doc
+
+import (
+	"fungo/evaluator"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DocTestSuite struct {
+	suite.Suite
+}
+
+func TestDocTestSuite(t *testing.T) {
+	suite.Run(t, &DocTestSuite{})
+}
+
+const source = `// Small math helpers.
+
+// Adds two integers.
+//
+// Overflows wrap around.
+export let add = fn(a: int, b: int = 0): int { a + b };
+
+let pick = fn([x, y], ...rest) { x }; // not its doc
+
+// Not attached
+
+let typed: fn(int): int = fn(n) { n };
+// Waits a bit.
+let later = async fn() { await sleep(1) };
+let value = 1;`
+
+func (t *DocTestSuite) TestSource() {
+	file, err := Source("math.fg", []byte(source))
+	t.Require().NoError(err)
+
+	t.Equal("math.fg", file.Path)
+	t.Equal("Small math helpers.", file.Doc)
+	t.Equal([]Function{
+		{
+			Name:       "add",
+			Doc:        "Adds two integers.\n\nOverflows wrap around.",
+			Line:       6,
+			Signature:  "fn(a: int, b: int = 0): int",
+			Parameters: []Parameter{{Name: "a", Type: "int"}, {Name: "b", Type: "int", Default: "0"}},
+			Return:     "int",
+			Exported:   true,
+		},
+		{
+			Name:       "pick",
+			Line:       8,
+			Signature:  "fn([x, y], ...rest)",
+			Parameters: []Parameter{{Name: "[x, y]"}, {Name: "rest", Rest: true}},
+		},
+		{
+			Name:       "typed",
+			Line:       12,
+			Signature:  "fn(n)",
+			Parameters: []Parameter{{Name: "n"}},
+			Annotation: "fn(int): int",
+		},
+		{
+			Name:       "later",
+			Doc:        "Waits a bit.",
+			Line:       14,
+			Signature:  "async fn()",
+			Parameters: []Parameter{},
+			Async:      true,
+		},
+	}, file.Functions)
+
+	// Without a blank line the opening comment documents the first function
+	file, err = Source("one.fg", []byte("// Doubles.\nlet double = fn(x) { x * 2 }"))
+	t.Require().NoError(err)
+	t.Equal("", file.Doc)
+	t.Equal("Doubles.", file.Functions[0].Doc)
+
+	_, err = Source("broken.fg", []byte("let = 1"))
+	t.Error(err)
+}
+
+func (t *DocTestSuite) TestBuiltins() {
+	builtins := Builtins()
+	t.Len(builtins, len(evaluator.BuiltInNames()))
+
+	declarations := map[string]string{}
+	for _, builtin := range builtins {
+		t.NotEmpty(builtin.Doc, builtin.Name)
+		t.NotEmpty(builtin.Signature, builtin.Name)
+		declarations[builtin.Name] = builtin.Declaration()
+	}
+
+	t.Equal("len: fn(any): int", declarations["len"])
+}
+
+func (t *DocTestSuite) TestWriteMarkdown() {
+	file, err := Source("math.fg", []byte(source))
+	t.Require().NoError(err)
+
+	out := &strings.Builder{}
+	t.NoError(WriteMarkdown(out, []*File{{Path: "math.fg", Doc: file.Doc, Functions: file.Functions[:2]}}, []Function{{Name: "len", Signature: "fn(any): int", Doc: "Counts."}}))
+
+	t.Equal(strings.Join([]string{
+		"# math.fg",
+		"",
+		"Small math helpers.",
+		"",
+		"## add",
+		"",
+		"```fungo",
+		"export let add = fn(a: int, b: int = 0): int",
+		"```",
+		"",
+		"Parameters:",
+		"",
+		"- `a: int`",
+		"- `b: int = 0`",
+		"",
+		"Adds two integers.",
+		"",
+		"Overflows wrap around.",
+		"",
+		"## pick",
+		"",
+		"```fungo",
+		"let pick = fn([x, y], ...rest)",
+		"```",
+		"",
+		"Parameters:",
+		"",
+		"- `[x, y]`",
+		"- `...rest`",
+		"",
+		"# Builtins",
+		"",
+		"Functions available to every program.",
+		"",
+		"## len",
+		"",
+		"```fungo",
+		"len: fn(any): int",
+		"```",
+		"",
+		"Counts.",
+		"",
+	}, "\n"), out.String())
+}
+
+func (t *DocTestSuite) TestWriteHTML() {
+	file, err := Source("math.fg", []byte(source))
+	t.Require().NoError(err)
+
+	out := &strings.Builder{}
+	t.NoError(WriteHTML(out, []*File{file}, nil))
+
+	html := out.String()
+	t.Contains(html, `<li><a href="#file1.add">add</a></li>`)
+	t.Contains(html, `<h3 id="file1.typed">typed</h3>`+"\n"+`<pre>let typed: fn(int): int = fn(n)</pre>`)
+	t.Contains(html, "<li><code>b: int = 0</code></li>")
+	t.Contains(html, "<p>Adds two integers.</p>\n<p>Overflows wrap around.</p>")
+	t.NotContains(html, "Builtins")
+}
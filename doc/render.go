@@ -0,0 +1,156 @@
+package doc
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// What a function binding reads like in a heading or code block, such as `export let add = fn(a: int, b: int): int`
+func (f Function) Declaration() string {
+	if f.Line == 0 {
+		return f.Name + ": " + f.Signature
+	}
+
+	declaration := "let " + f.Name
+	if f.Annotation != "" {
+		declaration += ": " + f.Annotation
+	}
+
+	declaration += " = " + f.Signature
+
+	if f.Exported {
+		declaration = "export " + declaration
+	}
+
+	return declaration
+}
+
+// Writes the documentation of the files, then of the built in functions when builtins is given, as Markdown. Doc
+// comments are copied as they are, so they may use Markdown themselves
+func WriteMarkdown(w io.Writer, files []*File, builtins []Function) error {
+	out := &strings.Builder{}
+
+	section := func(heading string, doc string, functions []Function) {
+		fmt.Fprintf(out, "# %s\n\n", heading)
+		if doc != "" {
+			fmt.Fprintf(out, "%s\n\n", doc)
+		}
+
+		for _, function := range functions {
+			fmt.Fprintf(out, "## %s\n\n```fungo\n%s\n```\n\n", function.Name, function.Declaration())
+
+			if len(function.Parameters) > 0 {
+				out.WriteString("Parameters:\n\n")
+				for _, param := range function.Parameters {
+					fmt.Fprintf(out, "- `%s`\n", param)
+				}
+				out.WriteString("\n")
+			}
+
+			if function.Doc != "" {
+				fmt.Fprintf(out, "%s\n\n", function.Doc)
+			}
+		}
+	}
+
+	for _, file := range files {
+		section(file.Path, file.Doc, file.Functions)
+	}
+
+	if len(builtins) > 0 {
+		section("Builtins", "Functions available to every program.", builtins)
+	}
+
+	_, err := io.WriteString(w, strings.TrimSuffix(out.String(), "\n"))
+
+	return err
+}
+
+var htmlTemplate = template.Must(template.New("doc").Funcs(template.FuncMap{"paragraphs": paragraphs}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: auto; }
+pre { background: #f4f4f4; padding: 0.5em; }
+nav ul { columns: 3; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- range .Sections}}
+<nav>
+<h2><a href="#{{.ID}}">{{.Heading}}</a></h2>
+<ul>
+{{- $section := .}}
+{{- range .Functions}}
+<li><a href="#{{$section.ID}}.{{.Name}}">{{.Name}}</a></li>
+{{- end}}
+</ul>
+</nav>
+{{- end}}
+{{- range .Sections}}
+{{- $section := .}}
+<section id="{{.ID}}">
+<h2>{{.Heading}}</h2>
+{{- range paragraphs .Doc}}
+<p>{{.}}</p>
+{{- end}}
+{{- range .Functions}}
+<h3 id="{{$section.ID}}.{{.Name}}">{{.Name}}</h3>
+<pre>{{.Declaration}}</pre>
+{{- if .Parameters}}
+<ul>
+{{- range .Parameters}}
+<li><code>{{.}}</code></li>
+{{- end}}
+</ul>
+{{- end}}
+{{- range paragraphs .Doc}}
+<p>{{.}}</p>
+{{- end}}
+{{- end}}
+</section>
+{{- end}}
+</body>
+</html>
+`))
+
+type htmlSection struct {
+	ID        string
+	Heading   string
+	Doc       string
+	Functions []Function
+}
+
+// Splits a doc comment on its blank lines
+func paragraphs(doc string) []string {
+	found := []string{}
+
+	for _, paragraph := range strings.Split(doc, "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			found = append(found, paragraph)
+		}
+	}
+
+	return found
+}
+
+// Writes the documentation as a standalone HTML page, with an index of the functions of each file followed by their
+// documentation. Doc comments are shown as plain text paragraphs
+func WriteHTML(w io.Writer, files []*File, builtins []Function) error {
+	sections := []htmlSection{}
+
+	for idx, file := range files {
+		sections = append(sections, htmlSection{ID: fmt.Sprintf("file%d", idx+1), Heading: file.Path, Doc: file.Doc, Functions: file.Functions})
+	}
+
+	if len(builtins) > 0 {
+		sections = append(sections, htmlSection{ID: "builtins", Heading: "Builtins", Doc: "Functions available to every program.", Functions: builtins})
+	}
+
+	return htmlTemplate.Execute(w, map[string]any{"Title": "Documentation", "Sections": sections})
+}
@@ -0,0 +1,238 @@
+package evaluator
+
+import (
+	"fmt"
+	"fungo/object"
+	"strconv"
+	"strings"
+)
+
+// Differences listed by a failed assert_eq, the others are counted
+const MAX_DIFFERENCES = 10
+
+// assert(condition, message?) fails unless the condition is truthy
+func builtIn_assert(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	message, err := assertionMessage("assert", args[1:])
+	if err != nil {
+		return err
+	}
+
+	if isTruthy(args[0]) {
+		return NULL
+	}
+
+	if message == "" {
+		message = "assertion failed"
+	}
+
+	return newAssertionError("%s", message)
+}
+
+// assert_eq(actual, expected, message?) fails unless both values are deeply equal, listing where they differ
+func builtIn_assert_eq(args ...object.Object) object.Object {
+	if len(args) < 2 || len(args) > 3 {
+		return newArgumentError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+
+	message, err := assertionMessage("assert_eq", args[2:])
+	if err != nil {
+		return err
+	}
+
+	actual, expected := args[0], args[1]
+	if objectsEqual(actual, expected) {
+		return NULL
+	}
+
+	if message == "" {
+		message = "values are not equal"
+	}
+
+	lines := []string{
+		message,
+		"  actual:   " + formatValue(actual),
+		"  expected: " + formatValue(expected),
+	}
+
+	// Values that differ as a whole are told apart by the lines above already
+	differences := diffValues("", actual, expected, nil)
+	if len(differences) == 1 && strings.HasPrefix(differences[0], ": ") {
+		differences = nil
+	}
+
+	if len(differences) > 0 {
+		lines = append(lines, "  differences:")
+
+		for idx, difference := range differences {
+			if idx == MAX_DIFFERENCES {
+				lines = append(lines, fmt.Sprintf("    ... and %d more", len(differences)-MAX_DIFFERENCES))
+				break
+			}
+
+			lines = append(lines, "    "+difference)
+		}
+	}
+
+	return newAssertionError("%s", strings.Join(lines, "\n"))
+}
+
+// assert_error(fn, message?) calls fn and fails unless it raises an error, whose message must contain the given one.
+// Returns the error as `catch` would bind it. Failed assertions within fn are not the error expected and fail the test
+func builtIn_assert_error(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	message, err := assertionMessage("assert_error", args[1:])
+	if err != nil {
+		return err
+	}
+
+	result := applyFunction(args[0], []object.Object{})
+
+	raised, ok := result.(*object.Error)
+	if !ok {
+		return newAssertionError("expected an error, got %s", formatValue(result))
+	}
+
+	if raised.Kind == object.ASSERTION_ERROR {
+		return raised
+	}
+
+	if !strings.Contains(raised.Message, message) {
+		return newAssertionError("expected an error containing %s, got %s: %s", strconv.Quote(message), raised.Kind, raised.Message)
+	}
+
+	return errorToHash(raised)
+}
+
+func assertionMessage(name string, args []object.Object) (string, *object.Error) {
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	message, ok := args[0].(*object.String)
+	if !ok {
+		return "", newArgumentError("message of `%s` must be `STRING`, got=`%s`", name, args[0].Type())
+	}
+
+	return message.Value, nil
+}
+
+func newAssertionError(format string, args ...interface{}) *object.Error {
+	err := newError(format, args...)
+	err.Kind = object.ASSERTION_ERROR
+
+	return err
+}
+
+// Renders a value for assertion failures: strings are quoted and hash keys sorted, so that equal values always read
+// the same
+func formatValue(value object.Object) string {
+	switch value := value.(type) {
+	case nil:
+		return "nothing"
+
+	case *object.String:
+		return strconv.Quote(value.Value)
+
+	case *object.Array:
+		elements := []string{}
+		for _, element := range value.Elements {
+			elements = append(elements, formatValue(element))
+		}
+
+		return "[" + strings.Join(elements, ", ") + "]"
+
+	case *object.Hash:
+		pairs := []string{}
+		for _, pair := range sortedPairs(value) {
+			pairs = append(pairs, formatValue(pair.Key)+": "+formatValue(pair.Value))
+		}
+
+		return "{" + strings.Join(pairs, ", ") + "}"
+
+	case *object.Struct:
+		fields := []string{}
+		for idx, field := range value.Definition.Fields {
+			fields = append(fields, field+": "+formatValue(value.Values[idx]))
+		}
+
+		return value.Definition.Name + " { " + strings.Join(fields, ", ") + " }"
+	}
+
+	return DebugString(value)
+}
+
+// Lists where two values differ as `path: actual != expected`, descending into arrays, hashes and structs of the same
+// definition. A difference of the values themselves has an empty path
+func diffValues(path string, actual object.Object, expected object.Object, differences []string) []string {
+	switch actual := actual.(type) {
+	case *object.Array:
+		expected, ok := expected.(*object.Array)
+		if !ok {
+			break
+		}
+
+		for idx := 0; idx < len(actual.Elements) || idx < len(expected.Elements); idx++ {
+			elementPath := fmt.Sprintf("%s[%d]", path, idx)
+
+			switch {
+			case idx >= len(expected.Elements):
+				differences = append(differences, fmt.Sprintf("%s: unexpected %s", elementPath, formatValue(actual.Elements[idx])))
+			case idx >= len(actual.Elements):
+				differences = append(differences, fmt.Sprintf("%s: missing %s", elementPath, formatValue(expected.Elements[idx])))
+			default:
+				differences = diffValues(elementPath, actual.Elements[idx], expected.Elements[idx], differences)
+			}
+		}
+
+		return differences
+
+	case *object.Hash:
+		expected, ok := expected.(*object.Hash)
+		if !ok {
+			break
+		}
+
+		for _, pair := range sortedPairs(actual) {
+			keyPath := fmt.Sprintf("%s[%s]", path, formatValue(pair.Key))
+
+			if other, ok := expected.Pairs[pair.Key.(object.Hashable).HashKey()]; ok {
+				differences = diffValues(keyPath, pair.Value, other.Value, differences)
+			} else {
+				differences = append(differences, fmt.Sprintf("%s: unexpected %s", keyPath, formatValue(pair.Value)))
+			}
+		}
+
+		for _, pair := range sortedPairs(expected) {
+			if _, ok := actual.Pairs[pair.Key.(object.Hashable).HashKey()]; !ok {
+				differences = append(differences, fmt.Sprintf("%s[%s]: missing %s", path, formatValue(pair.Key), formatValue(pair.Value)))
+			}
+		}
+
+		return differences
+
+	case *object.Struct:
+		expected, ok := expected.(*object.Struct)
+		if !ok || actual.Definition != expected.Definition {
+			break
+		}
+
+		for idx, field := range actual.Definition.Fields {
+			differences = diffValues(path+"."+field, actual.Values[idx], expected.Values[idx], differences)
+		}
+
+		return differences
+	}
+
+	if objectsEqual(actual, expected) {
+		return differences
+	}
+
+	return append(differences, fmt.Sprintf("%s: %s != %s", path, formatValue(actual), formatValue(expected)))
+}
@@ -0,0 +1,239 @@
+package evaluator
+
+import (
+	"fungo/ast"
+	"fungo/object"
+	"sync"
+	"time"
+)
+
+// EventLoop runs tasks one at a time on whichever goroutine drives it, either RunEventLoop or an `await` outside of
+// async functions. Tasks can be posted from any goroutine, which is how host functions settle their promises
+type EventLoop struct {
+	mu      sync.Mutex
+	queue   []func()
+	wake    chan struct{}
+	pending int // promises created with NewPromise that are not settled yet, the loop keeps running while there are some
+}
+
+func NewEventLoop() *EventLoop {
+	return &EventLoop{wake: make(chan struct{}, 1)}
+}
+
+// NewPromise returns a pending promise and the function settling it, which may be called from any goroutine and only
+// takes effect the first time. The promise is settled on the event loop, it is rejected when the value is an error
+func (l *EventLoop) NewPromise() (*object.Promise, func(object.Object)) {
+	promise := &object.Promise{}
+
+	l.mu.Lock()
+	l.pending += 1
+	l.mu.Unlock()
+
+	var once sync.Once
+	settle := func(value object.Object) {
+		once.Do(func() {
+			l.post(func() {
+				l.mu.Lock()
+				l.pending -= 1
+				l.mu.Unlock()
+
+				promise.Settle(value)
+			})
+		})
+	}
+
+	return promise, settle
+}
+
+// Runs tasks until the queue is empty and no promise is pending
+func (l *EventLoop) Run() {
+	for task := l.next(); task != nil; task = l.next() {
+		task()
+	}
+}
+
+func (l *EventLoop) runUntil(promise *object.Promise) object.Object {
+	for {
+		if value, settled := promise.Result(); settled {
+			return value
+		}
+
+		task := l.next()
+		if task == nil {
+			return newError("await on a promise that can never settle")
+		}
+
+		task()
+	}
+}
+
+func (l *EventLoop) post(task func()) {
+	l.mu.Lock()
+	l.queue = append(l.queue, task)
+	l.mu.Unlock()
+
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Blocks until a task is available, returns nil when there is none and nothing pending could post one
+func (l *EventLoop) next() func() {
+	for {
+		l.mu.Lock()
+		if len(l.queue) > 0 {
+			task := l.queue[0]
+			l.queue = l.queue[1:]
+			l.mu.Unlock()
+
+			return task
+		}
+
+		idle := l.pending == 0
+		l.mu.Unlock()
+
+		if idle {
+			return nil
+		}
+
+		<-l.wake
+	}
+}
+
+// coroutine runs the body of an async function on its own goroutine. Control is handed back and forth so that the
+// body only runs while whoever started or resumed it waits, the same way generators do
+type coroutine struct {
+	resume chan struct{}
+	yield  chan struct{}
+}
+
+// The body runs right away until its first await of a pending promise, the returned promise settles with its result
+func callAsyncFunction(fn *object.Function, env *object.Environment) *object.Promise {
+	promise := &object.Promise{}
+	co := &coroutine{resume: make(chan struct{}), yield: make(chan struct{})}
+
+	ctx := *contextOf(env)
+	ctx.coroutine = co
+	AttachContext(env, &ctx)
+
+	go func() {
+		result := unwrapReturnValue(Eval(fn.Body, env))
+		if err, ok := result.(*object.Error); ok {
+			addTraceFrame(err, functionFrame(fn))
+		}
+
+		promise.Settle(result)
+		co.yield <- struct{}{}
+	}()
+
+	<-co.yield
+
+	return promise
+}
+
+// Awaiting anything but a promise evaluates to that value. Outside of async functions, awaiting a pending promise runs
+// the event loop until it settles
+func evalAwaitExpression(exp *ast.AwaitExpression, env *object.Environment) object.Object {
+	value := Eval(exp.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	promise, ok := value.(*object.Promise)
+	if !ok {
+		return value
+	}
+
+	if result, settled := promise.Result(); settled {
+		return result
+	}
+
+	ctx := contextOf(env)
+	if ctx.coroutine == nil {
+		return ctx.Loop.runUntil(promise)
+	}
+
+	co := ctx.coroutine
+	promise.OnSettled(func(object.Object) {
+		ctx.Loop.post(func() {
+			co.resume <- struct{}{}
+			<-co.yield
+		})
+	})
+
+	co.yield <- struct{}{}
+	<-co.resume
+
+	result, _ := promise.Result()
+
+	return result
+}
+
+func durationArgument(name string, arg object.Object) (time.Duration, *object.Error) {
+	ms, ok := arg.(*object.Integer)
+	if !ok || ms.Value < 0 {
+		return 0, newArgumentError("delay of `%s` must be a positive `INTEGER` of milliseconds, got=`%s`", name, arg.String())
+	}
+
+	return time.Duration(ms.Value) * time.Millisecond, nil
+}
+
+/* ============================ Timer built ins ============================ */
+// Returns a promise resolved with null after the given number of milliseconds
+func builtIn_sleep(ctx *Context, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	delay, err := durationArgument("sleep", args[0])
+	if err != nil {
+		return err
+	}
+
+	promise, settle := ctx.Loop.NewPromise()
+	time.AfterFunc(delay, func() { settle(NULL) })
+
+	return promise
+}
+
+// Calls the function on the event loop after the given number of milliseconds, the returned promise settles with
+// its result
+func builtIn_setTimeout(ctx *Context, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	delay, err := durationArgument("setTimeout", args[1])
+	if err != nil {
+		return err
+	}
+
+	fn := args[0]
+	promise, settle := ctx.Loop.NewPromise()
+	time.AfterFunc(delay, func() {
+		ctx.Loop.post(func() { settle(applyFunction(fn, []object.Object{})) })
+	})
+
+	return promise
+}
+
+/* ================================ Promise ================================= */
+// Returns a promise of the callback's result once the receiver resolves, a rejection is passed on as it is
+func method_promiseThen(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("then", args, 1); err != nil {
+		return err
+	}
+
+	then := &object.Promise{}
+	receiver.(*object.Promise).OnSettled(func(value object.Object) {
+		if isError(value) {
+			then.Settle(value)
+			return
+		}
+
+		then.Settle(applyFunction(args[0], []object.Object{value}))
+	})
+
+	return then
+}
@@ -1,13 +1,14 @@
 package evaluator
 
 import (
-	"fmt"
 	"fungo/object"
+	"sort"
+	"sync"
 )
 
 func builtIn_len(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
 	}
 
 	switch arg := args[0].(type) {
@@ -16,17 +17,17 @@ func builtIn_len(args ...object.Object) object.Object {
 	case *object.Array:
 		return &object.Integer{Value: int64(len(arg.Elements))}
 	default:
-		return newError("argument to `len` not supported. got=`%s`", args[0].Type())
+		return newArgumentError("argument to `len` not supported. got=`%s`", args[0].Type())
 	}
 }
 
 func builtIn_first(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `first` must be `ARRAY`, got=`%s`", args[0].Type())
+		return newArgumentError("argument to `first` must be `ARRAY`, got=`%s`", args[0].Type())
 	}
 
 	array := args[0].(*object.Array)
@@ -39,11 +40,11 @@ func builtIn_first(args ...object.Object) object.Object {
 
 func builtIn_last(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `last` must be `ARRAY`, got=`%s`", args[0].Type())
+		return newArgumentError("argument to `last` must be `ARRAY`, got=`%s`", args[0].Type())
 	}
 
 	array := args[0].(*object.Array)
@@ -58,11 +59,11 @@ func builtIn_last(args ...object.Object) object.Object {
 
 func builtIn_rest(args ...object.Object) object.Object {
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got=%d, want=1", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `rest` must be `ARRAY`, got=%s", args[0].Type())
+		return newArgumentError("argument to `rest` must be `ARRAY`, got=%s", args[0].Type())
 	}
 
 	array := args[0].(*object.Array)
@@ -79,11 +80,11 @@ func builtIn_rest(args ...object.Object) object.Object {
 
 func builtIn_push(args ...object.Object) object.Object {
 	if len(args) != 2 {
-		return newError("wrong number of 1rguments. got=%d, want=2", len(args))
+		return newArgumentError("wrong number of arguments. got=%d, want=2", len(args))
 	}
 
 	if args[0].Type() != object.ARRAY_OBJ {
-		return newError("argument to `push` must be `ARRAY`, got=`%s`", args[0].Type())
+		return newArgumentError("argument to `push` must be `ARRAY`, got=`%s`", args[0].Type())
 	}
 
 	array := args[0].(*object.Array)
@@ -96,38 +97,167 @@ func builtIn_push(args ...object.Object) object.Object {
 	return &object.Array{Elements: elements}
 }
 
-func builtIn_print(args ...object.Object) object.Object {
-	for _, arg := range args {
-		fmt.Println(arg.String())
+/* ====================== Map of all built in functions ===================== */
+// ContextBuiltIn is a built in function, called with the context of the program calling it for those that need its
+// state, like its event loop or its streams
+type ContextBuiltIn func(ctx *Context, args ...object.Object) object.Object
+
+// Every built in function by name, those registered by the host included
+var builtInsMap map[string]ContextBuiltIn
+
+// Guards builtInsMap, hosts may register built ins while spawned functions look them up
+var builtInsLock sync.RWMutex
+
+// Populated in init, several built ins call back into the evaluator which itself looks built ins up
+func init() {
+	builtInsMap = map[string]ContextBuiltIn{
+		"len":          withoutContext(builtIn_len),
+		"first":        withoutContext(builtIn_first),
+		"last":         withoutContext(builtIn_last),
+		"rest":         withoutContext(builtIn_rest),
+		"push":         withoutContext(builtIn_push),
+		"print":        builtIn_print,
+		"input":        builtIn_input,
+		"readline":     builtIn_readline,
+		"chan":         withoutContext(builtIn_chan),
+		"send":         withoutContext(builtIn_send),
+		"recv":         withoutContext(builtIn_recv),
+		"close":        withoutContext(builtIn_close),
+		"range":        withoutContext(builtIn_range),
+		"take":         withoutContext(builtIn_take),
+		"map":          withoutContext(builtIn_map),
+		"filter":       withoutContext(builtIn_filter),
+		"zip":          withoutContext(builtIn_zip),
+		"iter":         withoutContext(builtIn_iter),
+		"collect":      withoutContext(builtIn_collect),
+		"next":         withoutContext(builtIn_next),
+		"sleep":        builtIn_sleep,
+		"setTimeout":   builtIn_setTimeout,
+		"assert":       withoutContext(builtIn_assert),
+		"assert_eq":    withoutContext(builtIn_assert_eq),
+		"assert_error": withoutContext(builtIn_assert_error),
 	}
+}
 
-	return NULL
+// Most built ins only need their arguments
+func withoutContext(fn func(args ...object.Object) object.Object) ContextBuiltIn {
+	return func(ctx *Context, args ...object.Object) object.Object {
+		return fn(args...)
+	}
 }
 
-/* ====================== Map of all built in functions ===================== */
-var builtInsMap = map[string]*object.BuiltIn{
-	"len": {
-		FnName: "len",
-		Fn:     builtIn_len,
-	},
-	"first": {
-		FnName: "first",
-		Fn:     builtIn_first,
-	},
-	"last": {
-		FnName: "last",
-		Fn:     builtIn_last,
-	},
-	"rest": {
-		FnName: "rest",
-		Fn:     builtIn_rest,
-	},
-	"push": {
-		FnName: "push",
-		Fn:     builtIn_push,
-	},
-	"print": {
-		FnName: "print",
-		Fn:     builtIn_print,
-	},
+// Returns the built in function name, called with the context of the program env belongs to
+func lookupBuiltIn(name string, env *object.Environment) (*object.BuiltIn, bool) {
+	builtInsLock.RLock()
+	builtIn, ok := builtInsMap[name]
+	builtInsLock.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	ctx := contextOf(env)
+
+	return &object.BuiltIn{
+		FnName: name,
+		Fn: func(args ...object.Object) object.Object {
+			return builtIn(ctx, args...)
+		},
+	}, true
+}
+
+// Names of every built in function, including those registered by the host, sorted. `quote` is not one of them, it is
+// special syntax recognised by the evaluator
+func BuiltInNames() []string {
+	builtInsLock.RLock()
+	defer builtInsLock.RUnlock()
+
+	names := []string{}
+	for name := range builtInsMap {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Signatures of the built in functions, written as type annotations. Iterable arguments accept arrays, strings, hashes
+// and iterators, hence any
+var builtInSignatures = map[string]string{
+	"len":          "fn(any): int",
+	"first":        "fn(array): any",
+	"last":         "fn(array): any",
+	"rest":         "fn(array): any",
+	"push":         "fn(array, any): array",
+	"print":        "fn(...any): null",
+	"input":        "fn(...string): string",
+	"readline":     "fn(): any",
+	"chan":         "fn(...int): channel",
+	"send":         "fn(channel, any): null",
+	"recv":         "fn(channel): any",
+	"close":        "fn(channel): null",
+	"range":        "fn(int, ...int): iterator",
+	"take":         "fn(any, int): iterator",
+	"map":          "fn(any, fn): iterator",
+	"filter":       "fn(any, fn): iterator",
+	"zip":          "fn(any, any, ...any): iterator",
+	"iter":         "fn(any): iterator",
+	"collect":      "fn(any): array",
+	"next":         "fn(any): hash[string, any]",
+	"sleep":        "fn(int): promise",
+	"setTimeout":   "fn(fn, int): promise",
+	"assert":       "fn(any, ...string): null",
+	"assert_eq":    "fn(any, any, ...string): null",
+	"assert_error": "fn(fn, ...string): hash[string, any]",
+}
+
+// What each built in function does, as `fungo doc` shows it
+var builtInDocs = map[string]string{
+	"len":          "Returns the number of bytes of a string or the number of elements of an array.",
+	"first":        "Returns the first element of an array, null when it is empty.",
+	"last":         "Returns the last element of an array, null when it is empty.",
+	"rest":         "Returns a new array holding all the elements of an array but the first, null when it is empty.",
+	"push":         "Returns a new array holding the elements of an array followed by the given value.",
+	"print":        "Prints each argument on a line of its own.",
+	"input":        "Prints the prompt, if any, without a newline and returns the next line of input without its line ending. Reaching the end of the input is an error.",
+	"readline":     "Returns the next line of input without its line ending, null at the end of the input.",
+	"chan":         "Creates a channel. chan() is unbuffered, chan(n) buffers up to n values.",
+	"send":         "Sends a value on a channel, blocking until it is received or buffered. Sending on a closed channel is an error.",
+	"recv":         "Receives a value from a channel, blocking until one is available. Returns null once the channel is closed and drained.",
+	"close":        "Closes a channel, values already sent can still be received.",
+	"range":        "Returns an iterator over the integers from start, 0 when omitted, up to end excluded: range(end), range(start, end) or range(start, end, step).",
+	"take":         "Returns an iterator over the first n values of an iterable.",
+	"map":          "Returns an iterator calling the function with each value of an iterable and yielding its results.",
+	"filter":       "Returns an iterator over the values of an iterable the function returns a truthy value for.",
+	"zip":          "Returns an iterator over arrays holding a value of each iterable, until the shortest one is exhausted.",
+	"iter":         "Returns an iterator over an array, the bytes of a string as one byte strings or the values received from a channel.",
+	"collect":      "Runs an iterable to the end and returns its values in an array.",
+	"next":         "Advances an iterator, returning {\"value\": value, \"done\": done}. The value is null once the iterator is done.",
+	"sleep":        "Returns a promise resolved with null after the given number of milliseconds.",
+	"setTimeout":   "Calls the function on the event loop after the given number of milliseconds, the returned promise settles with its result.",
+	"assert":       "Raises an AssertionError with the message, \"assertion failed\" by default, unless the condition is truthy.",
+	"assert_eq":    "Raises an AssertionError listing where the values differ unless the actual and expected values are deeply equal.",
+	"assert_error": "Calls the function and raises an AssertionError unless it raises an error whose message contains the given one. Returns the error as catch would bind it.",
+}
+
+// Returns what a built in function does, host functions have no documentation
+func BuiltInDoc(name string) (string, bool) {
+	doc, ok := builtInDocs[name]
+	return doc, ok
+}
+
+// Returns the signature of a built in function, host functions registered without one have none
+func BuiltInSignature(name string) (string, bool) {
+	signature, ok := builtInSignatures[name]
+	return signature, ok
+}
+
+// RegisterBuiltIn lets Go hosts add built in functions, replacing any existing one with the same name. Programs
+// already running see the functions registered meanwhile
+func RegisterBuiltIn(name string, fn ContextBuiltIn) {
+	builtInsLock.Lock()
+	defer builtInsLock.Unlock()
+
+	builtInsMap[name] = fn
 }
@@ -0,0 +1,237 @@
+package evaluator
+
+import (
+	"fungo/ast"
+	"fungo/object"
+	"reflect"
+)
+
+// The function and its arguments are evaluated right away, only the call runs on a new goroutine. The result, or the
+// error it failed with, is delivered on the returned channel which is closed afterwards
+func evalSpawnExpression(exp *ast.SpawnExpression, env *object.Environment) object.Object {
+	fn := Eval(exp.Call.Function, env)
+	if isError(fn) {
+		return fn
+	}
+
+	args := evalExpressions(exp.Call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	// Created before starting the goroutine, otherwise both sides could race to lazily create it
+	contextOf(env)
+	fn = detachedCall(fn, args, &callChain{})
+
+	result := make(chan object.Object, 1)
+	go func() {
+		result <- applyFunction(fn, args)
+		close(result)
+	}()
+
+	return &object.Channel{Value: result}
+}
+
+func evalSelectExpression(exp *ast.SelectExpression, env *object.Environment) object.Object {
+	cases := make([]reflect.SelectCase, len(exp.Arms))
+
+	for idx, arm := range exp.Arms {
+		if arm.Operation == nil {
+			cases[idx] = reflect.SelectCase{Dir: reflect.SelectDefault}
+			continue
+		}
+
+		args := evalExpressions(arm.Operation.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+
+		channel, ok := args[0].(*object.Channel)
+		if !ok {
+			return newError("cannot select on %s, expected CHANNEL", args[0].Type())
+		}
+
+		if len(args) == 2 {
+			cases[idx] = reflect.SelectCase{
+				Dir:  reflect.SelectSend,
+				Chan: reflect.ValueOf(channel.Value),
+				Send: reflect.ValueOf(&args[1]).Elem(),
+			}
+		} else {
+			cases[idx] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(channel.Value)}
+		}
+	}
+
+	chosen, received, err := selectChannels(cases)
+	if err != nil {
+		return err
+	}
+
+	arm := exp.Arms[chosen]
+	armEnv := object.NewEnclosedEnvironment(env)
+
+	if arm.Binding != nil {
+		armEnv.Set(arm.Binding.Value, received)
+	}
+
+	return Eval(arm.Body, armEnv)
+}
+
+// Receives read as null once the channel is closed, sending on a closed channel is an error rather than a panic
+func selectChannels(cases []reflect.SelectCase) (chosen int, received object.Object, err *object.Error) {
+	defer func() {
+		if recover() != nil {
+			err = newError("send on closed channel")
+		}
+	}()
+
+	chosen, value, ok := reflect.Select(cases)
+
+	received = NULL
+	if ok {
+		received = value.Interface().(object.Object)
+	}
+
+	return chosen, received, nil
+}
+
+func sendChannel(channel *object.Channel, value object.Object) (err *object.Error) {
+	defer func() {
+		if recover() != nil {
+			err = newError("send on closed channel")
+		}
+	}()
+
+	channel.Value <- value
+
+	return nil
+}
+
+func closeChannel(channel *object.Channel) (err *object.Error) {
+	defer func() {
+		if recover() != nil {
+			err = newError("close of closed channel")
+		}
+	}()
+
+	close(channel.Value)
+
+	return nil
+}
+
+// Iterating over a channel receives from it until it is closed
+func channelIterator(channel *object.Channel) *object.Iterator {
+	return &object.Iterator{
+		Next: func() (object.Object, bool) {
+			value, ok := <-channel.Value
+			return value, !ok
+		},
+	}
+}
+
+func channelArgument(name string, arg object.Object) (*object.Channel, *object.Error) {
+	channel, ok := arg.(*object.Channel)
+	if !ok {
+		return nil, newArgumentError("first argument to `%s` must be `CHANNEL`, got=`%s`", name, arg.Type())
+	}
+
+	return channel, nil
+}
+
+/* ========================== Channel built ins ========================== */
+// chan() is unbuffered, chan(n) buffers up to n values
+func builtIn_chan(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=0..1", len(args))
+	}
+
+	size := int64(0)
+	if len(args) == 1 {
+		integer, ok := args[0].(*object.Integer)
+		if !ok || integer.Value < 0 {
+			return newArgumentError("argument to `chan` must be a positive `INTEGER`, got=`%s`", args[0].String())
+		}
+
+		size = integer.Value
+	}
+
+	return &object.Channel{Value: make(chan object.Object, size)}
+}
+
+func builtIn_send(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	channel, err := channelArgument("send", args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := sendChannel(channel, args[1]); err != nil {
+		return err
+	}
+
+	return NULL
+}
+
+// Blocks until a value is available, returns null once the channel is closed and drained
+func builtIn_recv(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	channel, err := channelArgument("recv", args[0])
+	if err != nil {
+		return err
+	}
+
+	value, ok := <-channel.Value
+	if !ok {
+		return NULL
+	}
+
+	return value
+}
+
+func builtIn_close(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	channel, err := channelArgument("close", args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := closeChannel(channel); err != nil {
+		return err
+	}
+
+	return NULL
+}
+
+/* ================================ Channel ================================= */
+func method_channelSend(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("send", args, 1); err != nil {
+		return err
+	}
+
+	return builtIn_send(receiver, args[0])
+}
+
+func method_channelRecv(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("recv", args, 0); err != nil {
+		return err
+	}
+
+	return builtIn_recv(receiver)
+}
+
+func method_channelClose(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("close", args, 0); err != nil {
+		return err
+	}
+
+	return builtIn_close(receiver)
+}
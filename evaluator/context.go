@@ -0,0 +1,92 @@
+package evaluator
+
+import (
+	"fungo/object"
+	"io"
+	"os"
+	"sync"
+)
+
+// Context is the state shared by all environments of a running program. Hosts attach one to the environment they
+// evaluate in with AttachContext, otherwise a default one is created on first use
+type Context struct {
+	Loader *ModuleLoader
+
+	// Runs timers, host callbacks and the async functions waiting on them
+	Loop *EventLoop
+
+	// Directory relative imports are resolved against
+	Dir string
+
+	// The module being evaluated, nil for the main program
+	Module *object.Module
+
+	// Enforces type annotations: arguments and results are checked when functions are called, values when they are
+	// bound by an annotated let
+	CheckTypes bool
+
+	// Stops the program at breakpoints and steps, see Debugger
+	Debugger *Debugger
+
+	// Records the calls of the program's functions, see Profiler
+	Profiler *Profiler
+
+	// Records the statements that ran and the branches taken, see Coverage
+	Coverage *Coverage
+
+	// The streams of the program: print writes to Stdout, input and readline read lines from Stdin. Lines are read a
+	// byte at a time so that nothing past them is consumed, hosts that read Stdin as well should share a buffered reader
+	// with the program. Stderr is for the diagnostics of host built ins
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Spawned functions print concurrently, their lines must not interleave. Shared by the copies of the context made
+	// for modules, generators and async calls, which write to the same streams
+	output *sync.Mutex
+
+	// The generator whose body is being evaluated, nil outside of generators
+	generator *generator
+
+	// The async function whose body is being evaluated, nil outside of async functions
+	coroutine *coroutine
+
+	// Set while a macro body is evaluated, the quotes it makes are hygienic
+	expandingMacro bool
+
+	// The calls of a spawned function and of the functions it calls, nil for those of the program. See detached
+	chain *callChain
+}
+
+func NewContext() *Context {
+	return &Context{
+		Loader: NewModuleLoader("."),
+		Loop:   NewEventLoop(),
+		Dir:    ".",
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		output: &sync.Mutex{},
+	}
+}
+
+func AttachContext(env *object.Environment, ctx *Context) {
+	env.SetContext(ctx)
+}
+
+func contextOf(env *object.Environment) *Context {
+	if ctx, ok := env.Context().(*Context); ok {
+		return ctx
+	}
+
+	ctx := NewContext()
+	env.Root().SetContext(ctx)
+
+	return ctx
+}
+
+// Runs the event loop of the program `env` belongs to until no timer or host promise is left pending. Hosts call it
+// once evaluation is done so callbacks scheduled by the program get to run
+func RunEventLoop(env *object.Environment) {
+	contextOf(env).Loop.Run()
+}
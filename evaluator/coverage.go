@@ -0,0 +1,388 @@
+package evaluator
+
+import (
+	"fmt"
+	"fungo/ast"
+	"fungo/object"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// How many times a statement ran
+type StatementCoverage struct {
+	Line   int
+	Column int
+	Count  int64
+}
+
+// How many times each arm of an if expression was taken, the else arm counts the times the condition was false even
+// when the expression has no else block
+type BranchCoverage struct {
+	Line   int
+	Column int
+	Then   int64
+	Else   int64
+}
+
+// The coverage of a file, statements and branches in source order
+type FileCoverage struct {
+	Path       string
+	Statements []StatementCoverage
+	Branches   []BranchCoverage
+}
+
+// Coverage is attached to a context to record which statements of the program ran and which arms of its if
+// expressions were taken. Only the files added to it are covered, modules imported while it is attached are added
+// when they are loaded. A file added again, parsed anew, shares the counts of its statements with the previous times
+type Coverage struct {
+	mu         sync.Mutex
+	files      map[string]*fileCoverage
+	statements map[ast.Statement]*StatementCoverage
+	branches   map[*ast.IfExpression]*BranchCoverage
+}
+
+type fileCoverage struct {
+	path       string
+	statements map[[2]int]*StatementCoverage
+	branches   map[[2]int]*BranchCoverage
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{
+		files:      make(map[string]*fileCoverage),
+		statements: make(map[ast.Statement]*StatementCoverage),
+		branches:   make(map[*ast.IfExpression]*BranchCoverage),
+	}
+}
+
+// Adds the statements and if expressions of a program, which are reported as not covered until they run
+func (c *Coverage) Add(path string, program ast.Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	file, ok := c.files[path]
+	if !ok {
+		file = &fileCoverage{path: path, statements: make(map[[2]int]*StatementCoverage), branches: make(map[[2]int]*BranchCoverage)}
+		c.files[path] = file
+	}
+
+	addStatements := func(statements []ast.Statement) {
+		for _, statement := range statements {
+			token := statementToken(statement)
+			position := [2]int{token.Line, token.Column}
+
+			if _, ok := file.statements[position]; !ok {
+				file.statements[position] = &StatementCoverage{Line: token.Line, Column: token.Column}
+			}
+
+			c.statements[statement] = file.statements[position]
+		}
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Program:
+			addStatements(node.Statements)
+		case *ast.BlockStatement:
+			addStatements(node.Statements)
+		case *ast.IfExpression:
+			position := [2]int{node.Token.Line, node.Token.Column}
+
+			if _, ok := file.branches[position]; !ok {
+				file.branches[position] = &BranchCoverage{Line: node.Token.Line, Column: node.Token.Column}
+			}
+
+			c.branches[node] = file.branches[position]
+		}
+
+		return true
+	})
+}
+
+func coverageOf(env *object.Environment) *Coverage {
+	return contextOf(env).Coverage
+}
+
+func (c *Coverage) statement(statement ast.Statement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if counter, ok := c.statements[statement]; ok {
+		counter.Count += 1
+	}
+}
+
+func (c *Coverage) branch(expression *ast.IfExpression, taken bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counter, ok := c.branches[expression]
+	if !ok {
+		return
+	}
+
+	if taken {
+		counter.Then += 1
+	} else {
+		counter.Else += 1
+	}
+}
+
+// Returns the coverage of every file added, sorted by path
+func (c *Coverage) Files() []FileCoverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files := []FileCoverage{}
+
+	for _, file := range c.files {
+		coverage := FileCoverage{Path: file.path, Statements: []StatementCoverage{}, Branches: []BranchCoverage{}}
+
+		for _, statement := range file.statements {
+			coverage.Statements = append(coverage.Statements, *statement)
+		}
+		for _, branch := range file.branches {
+			coverage.Branches = append(coverage.Branches, *branch)
+		}
+
+		sort.Slice(coverage.Statements, func(i, j int) bool {
+			return before(coverage.Statements[i].Line, coverage.Statements[i].Column, coverage.Statements[j].Line, coverage.Statements[j].Column)
+		})
+		sort.Slice(coverage.Branches, func(i, j int) bool {
+			return before(coverage.Branches[i].Line, coverage.Branches[i].Column, coverage.Branches[j].Line, coverage.Branches[j].Column)
+		})
+
+		files = append(files, coverage)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files
+}
+
+func before(line, column, otherLine, otherColumn int) bool {
+	return line < otherLine || line == otherLine && column < otherColumn
+}
+
+// Returns how many statements ran and how many there are
+func (f FileCoverage) StatementsCovered() (int, int) {
+	covered := 0
+	for _, statement := range f.Statements {
+		if statement.Count > 0 {
+			covered += 1
+		}
+	}
+
+	return covered, len(f.Statements)
+}
+
+// Returns how many arms of the if expressions were taken and how many there are, two per if expression
+func (f FileCoverage) BranchesCovered() (int, int) {
+	covered := 0
+	for _, branch := range f.Branches {
+		if branch.Then > 0 {
+			covered += 1
+		}
+		if branch.Else > 0 {
+			covered += 1
+		}
+	}
+
+	return covered, 2 * len(f.Branches)
+}
+
+// The number of times the statements starting on each line ran, the most any of them did
+func (f FileCoverage) lines() map[int]int64 {
+	lines := map[int]int64{}
+	for _, statement := range f.Statements {
+		if count, ok := lines[statement.Line]; !ok || statement.Count > count {
+			lines[statement.Line] = statement.Count
+		}
+	}
+
+	return lines
+}
+
+/* ================================ Reports ================================ */
+
+// Writes the percentage of statements and branches covered in each file and in total
+func (c *Coverage) WriteSummary(w io.Writer) error {
+	var statements, totalStatements, branches, totalBranches int
+
+	for _, file := range c.Files() {
+		fileStatements, fileTotalStatements := file.StatementsCovered()
+		statements, totalStatements = statements+fileStatements, totalStatements+fileTotalStatements
+
+		fileBranches, fileTotalBranches := file.BranchesCovered()
+		branches, totalBranches = branches+fileBranches, totalBranches+fileTotalBranches
+
+		if _, err := fmt.Fprintf(w, "%s: %s of statements, %s of branches\n", file.Path, percent(fileStatements, fileTotalStatements), percent(fileBranches, fileTotalBranches)); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "total: %s of statements, %s of branches\n", percent(statements, totalStatements), percent(branches, totalBranches))
+
+	return err
+}
+
+func percent(covered, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+
+	return fmt.Sprintf("%.1f%%", 100*float64(covered)/float64(total))
+}
+
+// Writes the coverage in the LCOV tracefile format, with a record per file. Lines are those statements start on,
+// branches are numbered by if expression then arm, 0 for then and 1 for else
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	out := &strings.Builder{}
+	out.WriteString("TN:\n")
+
+	for _, file := range c.Files() {
+		fmt.Fprintf(out, "SF:%s\n", file.Path)
+
+		coveredBranches, totalBranches := file.BranchesCovered()
+		for idx, branch := range file.Branches {
+			for arm, count := range []int64{branch.Then, branch.Else} {
+				taken := fmt.Sprint(count)
+				if branch.Then+branch.Else == 0 {
+					taken = "-"
+				}
+
+				fmt.Fprintf(out, "BRDA:%d,%d,%d,%s\n", branch.Line, idx, arm, taken)
+			}
+		}
+		fmt.Fprintf(out, "BRF:%d\nBRH:%d\n", totalBranches, coveredBranches)
+
+		lines := file.lines()
+		numbers := []int{}
+		for line := range lines {
+			numbers = append(numbers, line)
+		}
+		sort.Ints(numbers)
+
+		hit := 0
+		for _, line := range numbers {
+			if lines[line] > 0 {
+				hit += 1
+			}
+			fmt.Fprintf(out, "DA:%d,%d\n", line, lines[line])
+		}
+		fmt.Fprintf(out, "LF:%d\nLH:%d\n", len(numbers), hit)
+
+		out.WriteString("end_of_record\n")
+	}
+
+	_, err := io.WriteString(w, out.String())
+
+	return err
+}
+
+var coverageTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { line-height: 1.3; }
+.line { display: block; white-space: pre; }
+.number { color: #888; display: inline-block; width: 4em; text-align: right; margin-right: 1em; }
+.covered { background: #dfd; }
+.uncovered { background: #fdd; }
+.partial { background: #ffd; }
+</style>
+</head>
+<body>
+<h1>Coverage</h1>
+<ul>
+{{- range .}}
+<li><a href="#{{.Path}}">{{.Path}}</a>: {{.Statements}} of statements, {{.Branches}} of branches</li>
+{{- end}}
+</ul>
+{{- range .}}
+<h2 id="{{.Path}}">{{.Path}}</h2>
+<pre>
+{{- range .Lines}}<span class="line{{with .Class}} {{.}}{{end}}"{{with .Title}} title="{{.}}"{{end}}><span class="number">{{.Number}}</span>{{.Source}}</span>{{end -}}
+</pre>
+{{- end}}
+</body>
+</html>
+`))
+
+type htmlFile struct {
+	Path       string
+	Statements string
+	Branches   string
+	Lines      []htmlLine
+}
+
+type htmlLine struct {
+	Number int
+	Source string
+	Class  string
+	Title  string
+}
+
+// Writes a page with the source of each file, lines marked as covered, not covered, or partially covered when one of
+// their statements or branches was not. The sources are read from the paths the files were added with
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	files := []htmlFile{}
+
+	for _, file := range c.Files() {
+		source, err := os.ReadFile(file.Path)
+		if err != nil {
+			return err
+		}
+
+		statements, totalStatements := file.StatementsCovered()
+		branches, totalBranches := file.BranchesCovered()
+		page := htmlFile{Path: file.Path, Statements: percent(statements, totalStatements), Branches: percent(branches, totalBranches)}
+
+		partial := map[int]bool{}
+		for _, statement := range file.Statements {
+			if statement.Count == 0 {
+				partial[statement.Line] = true
+			}
+		}
+
+		titles := map[int][]string{}
+		for _, branch := range file.Branches {
+			if branch.Then+branch.Else > 0 && (branch.Then == 0 || branch.Else == 0) {
+				partial[branch.Line] = true
+			}
+
+			titles[branch.Line] = append(titles[branch.Line], fmt.Sprintf("if at column %d: then taken %d times, else %d times", branch.Column, branch.Then, branch.Else))
+		}
+
+		lines := file.lines()
+		for idx, text := range strings.Split(string(source), "\n") {
+			line := htmlLine{Number: idx + 1, Source: text, Title: strings.Join(titles[idx+1], "\n")}
+
+			if count, ok := lines[idx+1]; ok {
+				switch {
+				case count == 0:
+					line.Class = "uncovered"
+				case partial[idx+1]:
+					line.Class = "partial"
+				default:
+					line.Class = "covered"
+				}
+			}
+
+			page.Lines = append(page.Lines, line)
+		}
+
+		files = append(files, page)
+	}
+
+	return coverageTemplate.Execute(w, files)
+}
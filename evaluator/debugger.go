@@ -0,0 +1,350 @@
+package evaluator
+
+import (
+	"fungo/ast"
+	"fungo/lexer"
+	"fungo/object"
+	"fungo/parser"
+	"fungo/token"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Why the program stopped
+const (
+	STOP_ENTRY      = "entry"
+	STOP_BREAKPOINT = "breakpoint"
+	STOP_STEP       = "step"
+)
+
+// How the program goes on after it stopped
+type DebugCommand int
+
+const (
+	// Runs until the next breakpoint
+	Continue DebugCommand = iota
+	// Stops at the next statement, inside the functions it calls if needs be
+	StepIn
+	// Stops at the next statement of the current function or of the ones it returns to
+	StepOver
+	// Stops at the next statement once the current function returned
+	StepOut
+	// Ends the program, with an error evaluation returns right away
+	Terminate
+)
+
+// A call of a function being evaluated, the outermost frame is the program itself
+type Frame struct {
+	Name     string
+	Function *object.Function // nil for the program
+	// The environment and the position of the statement the frame is at
+	Env    *object.Environment
+	Line   int
+	Column int
+}
+
+// Where and why the program stopped, the frames of the call stack are innermost first
+type Stop struct {
+	Reason    string
+	Statement ast.Statement
+	Line      int
+	Column    int
+	Env       *object.Environment
+	Frames    []Frame
+
+	debugger *Debugger
+}
+
+// Debugger is attached to a context to stop the program at breakpoints and steps. The program is only stopped at the
+// statements of the main program and the functions it defines: code of imported modules and spawned functions runs
+// through, generators and async functions stop in their body but do not get a frame of their own
+type Debugger struct {
+	// Called on the goroutine evaluating the program whenever it stops, which resumes as the returned command says
+	OnStop func(stop *Stop) DebugCommand
+
+	// Stops at the first statement of the program, so that breakpoints can be set before it runs
+	StopOnEntry bool
+
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	terminated  bool
+	evaluating  bool
+
+	frames    []*Frame
+	started   bool
+	command   DebugCommand
+	depth     int // of the call stack when the last command was given
+	previous  ast.Statement
+	prevLine  int
+	prevDepth int
+}
+
+func NewDebugger(onStop func(stop *Stop) DebugCommand) *Debugger {
+	return &Debugger{OnStop: onStop, breakpoints: make(map[int]bool)}
+}
+
+// Replaces the breakpoints, lines are 1-based
+func (d *Debugger) SetBreakpoints(lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakpoints = make(map[int]bool)
+	for _, line := range lines {
+		d.breakpoints[line] = true
+	}
+}
+
+func (d *Debugger) SetBreakpoint(line int) {
+	d.mu.Lock()
+	d.breakpoints[line] = true
+	d.mu.Unlock()
+}
+
+func (d *Debugger) ClearBreakpoint(line int) {
+	d.mu.Lock()
+	delete(d.breakpoints, line)
+	d.mu.Unlock()
+}
+
+// Returns the lines with a breakpoint, sorted
+func (d *Debugger) Breakpoints() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	lines := []int{}
+	for line := range d.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+
+	return lines
+}
+
+// Ends the program at its next statement, it can be called from any goroutine
+func (d *Debugger) Terminate() {
+	d.mu.Lock()
+	d.terminated = true
+	d.mu.Unlock()
+}
+
+// Whether the program was ended by a Terminate command or call
+func (d *Debugger) Terminated() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.terminated
+}
+
+// The debugger of the main program env belongs to, if any
+func debuggerOf(env *object.Environment) *Debugger {
+	ctx := contextOf(env)
+	if ctx.Debugger == nil || ctx.Module != nil {
+		return nil
+	}
+
+	return ctx.Debugger
+}
+
+// Called before each statement is evaluated, returns the error to end the program with once it is terminated. Only
+// the program's own goroutine gets here, spawned functions are detached from the debugger, but the state is still
+// guarded as front-ends set breakpoints and terminate from theirs
+func (d *Debugger) statement(statement ast.Statement, env *object.Environment) *object.Error {
+	d.mu.Lock()
+
+	if d.evaluating {
+		d.mu.Unlock()
+		return nil
+	}
+
+	if d.terminated {
+		d.mu.Unlock()
+		return newError("terminated by the debugger")
+	}
+
+	if len(d.frames) == 0 {
+		d.frames = append(d.frames, &Frame{Name: "<main>"})
+	}
+
+	at := statementToken(statement)
+	depth := len(d.frames)
+	frame := d.frames[depth-1]
+	frame.Env, frame.Line, frame.Column = env, at.Line, at.Column
+
+	// Several statements on the line of a breakpoint only stop at the first of them, a loop over one statement stops
+	// every time around
+	sameLine := d.previous != nil && d.previous != statement && d.prevLine == at.Line && d.prevDepth == depth
+	d.previous, d.prevLine, d.prevDepth = statement, at.Line, depth
+
+	reason := ""
+	switch {
+	case !d.started && d.StopOnEntry:
+		reason = STOP_ENTRY
+	case d.breakpoints[at.Line] && !sameLine:
+		reason = STOP_BREAKPOINT
+	case !d.started:
+	case d.command == StepIn:
+		reason = STOP_STEP
+	case d.command == StepOver && depth <= d.depth:
+		reason = STOP_STEP
+	case d.command == StepOut && depth < d.depth:
+		reason = STOP_STEP
+	}
+
+	d.started = true
+
+	if reason == "" {
+		d.mu.Unlock()
+		return nil
+	}
+
+	stop := &Stop{Reason: reason, Statement: statement, Line: at.Line, Column: at.Column, Env: env, debugger: d}
+	for idx := len(d.frames) - 1; idx >= 0; idx-- {
+		stop.Frames = append(stop.Frames, *d.frames[idx])
+	}
+
+	// Unlocked while stopped, the front-end evaluates and sets breakpoints meanwhile
+	d.mu.Unlock()
+	command := d.OnStop(stop)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.command, d.depth = command, depth
+
+	if command == Terminate {
+		d.terminated = true
+		return newError("terminated by the debugger")
+	}
+
+	return nil
+}
+
+func (d *Debugger) enter(fn *object.Function, env *object.Environment) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.evaluating {
+		d.frames = append(d.frames, &Frame{Name: functionName(fn), Function: fn, Env: env})
+	}
+}
+
+func (d *Debugger) leave() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.evaluating && len(d.frames) > 1 {
+		d.frames = d.frames[:len(d.frames)-1]
+	}
+}
+
+// Evaluates source in the environment the program stopped in, without stopping at breakpoints. It should only be
+// called while the program is stopped
+func (s *Stop) Evaluate(source string) object.Object {
+	p := parser.NewParser(lexer.NewLexer(source))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return newError("%s", strings.Join(p.Errors(), "\n"))
+	}
+
+	s.debugger.mu.Lock()
+	s.debugger.evaluating = true
+	s.debugger.mu.Unlock()
+
+	defer func() {
+		s.debugger.mu.Lock()
+		s.debugger.evaluating = false
+		s.debugger.mu.Unlock()
+	}()
+
+	return Eval(program, s.Env)
+}
+
+// A one line rendering of a value for debugger front-ends, functions only show their parameters
+func DebugString(value object.Object) string {
+	fn, ok := value.(*object.Function)
+	if !ok {
+		return value.String()
+	}
+
+	params := []string{}
+	for _, param := range fn.Parameters {
+		params = append(params, param.String())
+	}
+
+	return "fn(" + strings.Join(params, ", ") + ")"
+}
+
+// Spawned functions run on their own goroutine, concurrently with the program being debugged or profiled. They get a
+// copy of the context without the debugger and with a call chain of their own, which the profiler keeps a separate
+// stack for. The functions they call are detached into the same chain, see applyFunctionIn
+func detached(fn object.Object, chain *callChain) object.Object {
+	function, ok := fn.(*object.Function)
+	if !ok {
+		return fn
+	}
+
+	ctx := *contextOf(function.Env)
+	if ctx.chain == chain || ctx.Debugger == nil && ctx.Profiler == nil {
+		return fn
+	}
+
+	ctx.Debugger = nil
+	ctx.chain = chain
+
+	env := object.NewEnclosedEnvironment(function.Env)
+	AttachContext(env, &ctx)
+
+	detached := *function
+	detached.Env = env
+
+	return &detached
+}
+
+// Detaches a call into chain: the function called, or the functions passed to a built in or method that may call
+// them back. The arguments are replaced in place
+func detachedCall(fn object.Object, args []object.Object, chain *callChain) object.Object {
+	fn = detached(fn, chain)
+
+	if _, ok := fn.(*object.Function); !ok {
+		for idx, arg := range args {
+			args[idx] = detached(arg, chain)
+		}
+	}
+
+	return fn
+}
+
+// Calls fn from env. Calls made by spawned functions stay in their call chain
+func applyFunctionIn(env *object.Environment, fn object.Object, args []object.Object) object.Object {
+	if chain := contextOf(env).chain; chain != nil {
+		fn = detachedCall(fn, args, chain)
+	}
+
+	return applyFunction(fn, args)
+}
+
+func statementToken(statement ast.Statement) token.Token {
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		return statement.Token
+	case *ast.ReturnStatement:
+		return statement.Token
+	case *ast.ThrowStatement:
+		return statement.Token
+	case *ast.ImportStatement:
+		return statement.Token
+	case *ast.ExportStatement:
+		return statement.Token
+	case *ast.StructStatement:
+		return statement.Token
+	case *ast.EnumStatement:
+		return statement.Token
+	case *ast.ExpressionStatement:
+		return statement.Token
+	case *ast.BlockStatement:
+		return statement.Token
+	}
+
+	return token.Token{}
+}
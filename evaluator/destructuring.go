@@ -0,0 +1,168 @@
+package evaluator
+
+import (
+	"fungo/ast"
+	"fungo/object"
+)
+
+// Binds `value` to every identifier in `target`, returning an error when the value does not have the shape the
+// pattern asks for
+func bindPattern(target ast.Expression, value object.Object, env *object.Environment) *object.Error {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		env.Set(target.Value, value)
+		return nil
+
+	case *ast.ArrayPattern:
+		return bindArrayPattern(target, value, env)
+
+	case *ast.HashPattern:
+		return bindHashPattern(target, value, env)
+
+	default:
+		return newError("invalid binding target: %s", target.String())
+	}
+}
+
+func bindArrayPattern(pattern *ast.ArrayPattern, value object.Object, env *object.Environment) *object.Error {
+	array, ok := value.(*object.Array)
+	if !ok {
+		return newError("cannot destructure %s as ARRAY", value.Type())
+	}
+
+	want, got := len(pattern.Elements), len(array.Elements)
+
+	switch {
+	case pattern.Rest == nil && got != want:
+		return newError("destructuring mismatch: expected %d elements, got %d", want, got)
+	case pattern.Rest != nil && got < want:
+		return newError("destructuring mismatch: expected at least %d elements, got %d", want, got)
+	}
+
+	for idx, element := range pattern.Elements {
+		if err := bindPattern(element, array.Elements[idx], env); err != nil {
+			return err
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := make([]object.Object, got-want)
+		copy(rest, array.Elements[want:])
+
+		env.Set(pattern.Rest.Target.Value, &object.Array{Elements: rest})
+	}
+
+	return nil
+}
+
+func bindHashPattern(pattern *ast.HashPattern, value object.Object, env *object.Environment) *object.Error {
+	if instance, ok := value.(*object.Struct); ok {
+		return bindStructPattern(pattern, instance, env)
+	}
+
+	hash, ok := value.(*object.Hash)
+	if !ok {
+		return newError("cannot destructure %s as HASH", value.Type())
+	}
+
+	used := make(map[object.HashKey]bool)
+
+	for _, property := range pattern.Properties {
+		key := (&object.String{Value: property.Key.Value}).HashKey()
+
+		pair, ok := hash.Pairs[key]
+		if !ok {
+			return newError("destructuring mismatch: key %q not found", property.Key.Value)
+		}
+
+		if err := bindPattern(property.Value, pair.Value, env); err != nil {
+			return err
+		}
+
+		used[key] = true
+	}
+
+	if pattern.Rest != nil {
+		rest := make(map[object.HashKey]object.HashPair)
+
+		for key, pair := range hash.Pairs {
+			if !used[key] {
+				rest[key] = pair
+			}
+		}
+
+		env.Set(pattern.Rest.Target.Value, &object.Hash{Pairs: rest})
+	}
+
+	return nil
+}
+
+// Hash patterns also pick fields out of structs, a rest element collects the remaining fields into a hash
+func bindStructPattern(pattern *ast.HashPattern, instance *object.Struct, env *object.Environment) *object.Error {
+	used := make(map[string]bool)
+
+	for _, property := range pattern.Properties {
+		value, ok := instance.Field(property.Key.Value)
+		if !ok {
+			return newError("destructuring mismatch: %s has no field %s", instance.Definition.Name, property.Key.Value)
+		}
+
+		if err := bindPattern(property.Value, value, env); err != nil {
+			return err
+		}
+
+		used[property.Key.Value] = true
+	}
+
+	if pattern.Rest != nil {
+		rest := make(map[object.HashKey]object.HashPair)
+
+		for idx, field := range instance.Definition.Fields {
+			if !used[field] {
+				setHashPair(rest, field, instance.Values[idx])
+			}
+		}
+
+		env.Set(pattern.Rest.Target.Value, &object.Hash{Pairs: rest})
+	}
+
+	return nil
+}
+
+// Returns every name bound by a let statement, in source order
+func letStatementNames(statement *ast.LetStatement) []string {
+	if statement.Pattern == nil {
+		return []string{statement.Name.Value}
+	}
+
+	return patternNames(statement.Pattern)
+}
+
+func patternNames(target ast.Expression) []string {
+	names := []string{}
+
+	switch target := target.(type) {
+	case *ast.Identifier:
+		names = append(names, target.Value)
+
+	case *ast.ArrayPattern:
+		for _, element := range target.Elements {
+			names = append(names, patternNames(element)...)
+		}
+
+		if target.Rest != nil {
+			names = append(names, target.Rest.Target.Value)
+		}
+
+	case *ast.HashPattern:
+		for _, property := range target.Properties {
+			names = append(names, patternNames(property.Value)...)
+		}
+
+		if target.Rest != nil {
+			names = append(names, target.Rest.Target.Value)
+		}
+	}
+
+	return names
+}
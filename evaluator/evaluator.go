@@ -15,8 +15,20 @@ var (
 
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
+	debugger := debuggerOf(env)
+	coverage := coverageOf(env)
 
 	for _, statement := range program.Statements {
+		if debugger != nil {
+			if err := debugger.statement(statement, env); err != nil {
+				return err
+			}
+		}
+
+		if coverage != nil {
+			coverage.statement(statement)
+		}
+
 		result = Eval(statement, env)
 
 		switch result := result.(type) {
@@ -32,8 +44,20 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
+	debugger := debuggerOf(env)
+	coverage := coverageOf(env)
 
 	for _, statement := range block.Statements {
+		if debugger != nil {
+			if err := debugger.statement(statement, env); err != nil {
+				return err
+			}
+		}
+
+		if coverage != nil {
+			coverage.statement(statement)
+		}
+
 		result = Eval(statement, env)
 
 		if result != nil {
@@ -60,7 +84,7 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 
 func evalMinusOperatorExpression(right object.Object) object.Object {
 	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+		return newError("unknown operator: -%s", typeOf(right))
 	}
 
 	value, ok := right.(*object.Integer)
@@ -147,7 +171,7 @@ func evalPrefixExpression(prefixExpression *ast.PrefixExpression, env *object.En
 		return evalMinusOperatorExpression(right)
 
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError("unknown operator: %s%s", operator, typeOf(right))
 	}
 }
 
@@ -167,14 +191,18 @@ func evalInfixExpression(infixExpression *ast.InfixExpression, env *object.Envir
 		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case isStructural(left) && operator == token.EQ:
+		return nativeBoolToBooleanObject(objectsEqual(left, right))
+	case isStructural(left) && operator == token.NOT_EQ:
+		return nativeBoolToBooleanObject(!objectsEqual(left, right))
 	case operator == token.EQ:
 		return nativeBoolToBooleanObject(left == right)
 	case operator == token.NOT_EQ:
 		return nativeBoolToBooleanObject(left != right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newError("type mismatch: %s %s %s", typeOf(left), operator, typeOf(right))
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError("unknown operator: %s %s %s", typeOf(left), operator, typeOf(right))
 	}
 }
 
@@ -185,6 +213,10 @@ func evalIfExpression(expression *ast.IfExpression, env *object.Environment) obj
 		return condition
 	}
 
+	if coverage := coverageOf(env); coverage != nil {
+		coverage.branch(expression, isTruthy(condition))
+	}
+
 	if isTruthy(condition) {
 		return Eval(expression.IfCondition, env)
 	} else if expression.ElseCondition != nil {
@@ -213,7 +245,7 @@ func evalIdentifier(identifier *ast.Identifier, env *object.Environment) object.
 		return value
 	}
 
-	if builtIn, ok := builtInsMap[identifier.Value]; ok {
+	if builtIn, ok := lookupBuiltIn(identifier.Value, env); ok {
 		return builtIn
 	}
 
@@ -226,6 +258,31 @@ func evalLetStatement(statement *ast.LetStatement, env *object.Environment) obje
 		return value
 	}
 
+	if statement.Type != nil && contextOf(env).CheckTypes {
+		target := statement.Pattern
+		if target == nil {
+			target = statement.Name
+		}
+
+		if err := checkType(statement.Type, value, env, "%s", target.String()); err != nil {
+			return err
+		}
+	}
+
+	if statement.Pattern != nil {
+		if err := bindPattern(statement.Pattern, value, env); err != nil {
+			return err
+		}
+
+		return NOOP
+	}
+
+	// Function literals are named after the binding they are assigned to, which is what error traces report. Only
+	// literals are, they evaluate to a new function no other code holds yet, so naming it changes no shared value
+	if _, ok := statement.Value.(*ast.FunctionLiteral); ok {
+		value.(*object.Function).Name = statement.Name.Value
+	}
+
 	env.Set(statement.Name.Value, value)
 
 	return NOOP
@@ -236,6 +293,11 @@ func evalFunctionLiteral(fn *ast.FunctionLiteral, env *object.Environment) objec
 		Parameters: fn.Parameters,
 		Body:       fn.Body,
 		Env:        env,
+		Generator:  fn.IsGenerator,
+		Async:      fn.IsAsync,
+
+		ParameterTypes: fn.ParameterTypes,
+		ReturnType:     fn.ReturnType,
 	}
 }
 
@@ -260,7 +322,26 @@ func evalArrayLiteral(array *ast.ArrayLiteral, env *object.Environment) object.O
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
-	for keyNode, valueNode := range node.Pairs {
+	// Pairs are evaluated in source order so later keys and spreads override earlier ones
+	for _, keyNode := range node.Keys {
+		if spread, ok := keyNode.(*ast.SpreadElement); ok {
+			value := Eval(spread.Value, env)
+			if isError(value) {
+				return value
+			}
+
+			hash, ok := value.(*object.Hash)
+			if !ok {
+				return newError("cannot spread %s into HASH", value.Type())
+			}
+
+			for hashed, pair := range hash.Pairs {
+				pairs[hashed] = pair
+			}
+
+			continue
+		}
+
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
@@ -271,7 +352,7 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(node.Pairs[keyNode], env)
 		if isError(value) {
 			return value
 		}
@@ -287,6 +368,28 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	var result []object.Object
 
 	for _, exp := range exps {
+		if spread, ok := exp.(*ast.SpreadElement); ok {
+			evaluated := Eval(spread.Value, env)
+			if isError(evaluated) {
+				return []object.Object{evaluated}
+			}
+
+			// Whatever for-in iterates over can be spread
+			iterator, err := toIterator(evaluated)
+			if err != nil {
+				return []object.Object{newError("cannot spread %s, it is not iterable", evaluated.Type())}
+			}
+
+			elements, err := collectIterator(iterator)
+			if err != nil {
+				return []object.Object{err}
+			}
+
+			result = append(result, elements...)
+
+			continue
+		}
+
 		evaluated := Eval(exp, env)
 
 		if isError(evaluated) {
@@ -300,6 +403,10 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 }
 
 func evalCallExpression(exp *ast.CallExpression, env *object.Environment) object.Object {
+	if exp.Function.String() == "quote" {
+		return evalQuote(exp, env)
+	}
+
 	fn := Eval(exp.Function, env)
 	if isError(fn) {
 		return fn
@@ -309,7 +416,38 @@ func evalCallExpression(exp *ast.CallExpression, env *object.Environment) object
 		return args[0]
 	}
 
-	return applyFunction(fn, args)
+	return applyFunctionIn(env, fn, args)
+}
+
+// The left value is passed as the first argument of the right hand call, or as the only argument when the right
+// hand side is not a call
+func evalPipeExpression(exp *ast.PipeExpression, env *object.Environment) object.Object {
+	left := Eval(exp.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	call, ok := exp.Right.(*ast.CallExpression)
+	if !ok {
+		fn := Eval(exp.Right, env)
+		if isError(fn) {
+			return fn
+		}
+
+		return applyFunctionIn(env, fn, []object.Object{left})
+	}
+
+	fn := Eval(call.Function, env)
+	if isError(fn) {
+		return fn
+	}
+
+	args := evalExpressions(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	return applyFunctionIn(env, fn, append([]object.Object{left}, args...))
 }
 
 func evalArrayIndexExpression(ref *object.Array, index *object.Integer) object.Object {
@@ -360,6 +498,24 @@ func evalIndexExpression(exp *ast.IndexExpression, env *object.Environment) obje
 	}
 }
 
+func evalMemberExpression(exp *ast.MemberExpression, env *object.Environment) object.Object {
+	ref := Eval(exp.Ref, env)
+	if isError(ref) {
+		return ref
+	}
+
+	switch ref := ref.(type) {
+	case *object.Module:
+		return evalModuleMember(ref, exp.Property.Value)
+	case *object.Struct:
+		return evalStructMember(ref, exp.Property.Value)
+	case *object.EnumDefinition:
+		return evalEnumMember(ref, exp.Property.Value)
+	default:
+		return evalObjectMember(ref, exp.Property.Value)
+	}
+}
+
 func Eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
@@ -378,8 +534,47 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.ReturnStatement:
 		return evalReturnExpression(node, env)
 
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+
+	case *ast.ExportStatement:
+		return evalExportStatement(node, env)
+
+	case *ast.StructStatement:
+		return evalStructStatement(node, env)
+
+	case *ast.EnumStatement:
+		return evalEnumStatement(node, env)
+
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+
+	case *ast.ForExpression:
+		return evalForExpression(node, env)
+
+	case *ast.YieldExpression:
+		return evalYieldExpression(node, env)
+
+	case *ast.MacroLiteral:
+		return &object.Macro{Parameters: node.Parameters, Body: node.Body, Env: env}
+
+	case *ast.AwaitExpression:
+		return evalAwaitExpression(node, env)
+
+	case *ast.SpawnExpression:
+		return evalSpawnExpression(node, env)
+
+	case *ast.SelectExpression:
+		return evalSelectExpression(node, env)
+
 	case *ast.LetStatement:
-		evalLetStatement(node, env)
+		return evalLetStatement(node, env)
 
 	// Expressions
 	case *ast.PrefixExpression:
@@ -394,6 +589,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IndexExpression:
 		return evalIndexExpression(node, env)
 
+	case *ast.PipeExpression:
+		return evalPipeExpression(node, env)
+
+	case *ast.MemberExpression:
+		return evalMemberExpression(node, env)
+
 	// Values
 	case *ast.IntegerLiteral:
 		return evalIntegerLiteral(node)
@@ -1,11 +1,20 @@
 package evaluator
 
 import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"fungo/ast"
 	"fungo/lexer"
 	"fungo/object"
 	"fungo/parser"
 	"fungo/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -521,3 +530,1934 @@ func (t *EvaluatorTestSuite) TestHashIndexExperssions() {
 		t.testHashIndexExpression(test.expected, result)
 	}
 }
+
+func (t *EvaluatorTestSuite) TestDestructuring() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a + b;", 3},
+		{"let [a, [b, c]] = [1, [2, 3]]; a + b + c;", 6},
+		{"let [a, ..rest] = [1, 2, 3]; rest;", []int{2, 3}},
+		{"let [..rest] = []; len(rest);", 0},
+		{`let {name, age} = {"name": "Law", "age": 30}; age;`, 30},
+		{`let {name: n} = {"name": "Law"}; n;`, "Law"},
+		{`let {a, ...others} = {"a": 1, "b": 2, "c": 3}; others["b"] + others["c"];`, 5},
+		{`let {a, ...others} = {"a": 1, "b": 2}; others["a"];`, nil},
+		{`let {tags: [first, ...more]} = {"tags": [1, 2, 3]}; first;`, 1},
+		{"let sum = fn([a, b]) { a + b }; sum([4, 5]);", 9},
+		{`let greet = fn({name}) { "Hi " + name }; greet({"name": "Law"});`, "Hi Law"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case string:
+			t.testStringObject(expected, result)
+		case []int:
+			t.testArrayObject(expected, result)
+		case nil:
+			t.testNullObject(result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestDestructuringErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = [1];", "destructuring mismatch: expected 2 elements, got 1"},
+		{"let [a] = [1, 2];", "destructuring mismatch: expected 1 elements, got 2"},
+		{"let [a, b, ...rest] = [1];", "destructuring mismatch: expected at least 2 elements, got 1"},
+		{"let [a] = 1;", "cannot destructure INTEGER as ARRAY"},
+		{"let {a} = [1];", "cannot destructure ARRAY as HASH"},
+		{`let {a} = {"b": 1};`, `destructuring mismatch: key "a" not found`},
+		{"let f = fn([a, b]) { a }; f([1]);", "destructuring mismatch: expected 2 elements, got 1"},
+		{"let a = missing; 5;", "identifier not found: missing"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestDefaultAndRestParameters() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = fn(x, y = 10) { x + y }; add(1);", 11},
+		{"let add = fn(x, y = 10) { x + y }; add(1, 2);", 3},
+		{"let add = fn(x, y = x * 2) { x + y }; add(3);", 9},
+		{"let f = fn(first, ...others) { others }; f(1, 2, 3);", []int{2, 3}},
+		{"let f = fn(first, ...others) { len(others) }; f(1);", 0},
+		{"let f = fn(...all) { len(all) }; f();", 0},
+		{"let f = fn(x = 1, ...rest) { x + len(rest) }; f();", 1},
+		{"let f = fn(x = 1, ...rest) { x + len(rest) }; f(5, 6, 7);", 7},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case []int:
+			t.testArrayObject(expected, result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestFunctionArity() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let add = fn(x, y) { x + y }; add(1);", "wrong number of arguments. got=1, want=2"},
+		{"let add = fn(x, y) { x + y }; add(1, 2, 3);", "wrong number of arguments. got=3, want=2"},
+		{"let add = fn(x, y = 1) { x + y }; add();", "wrong number of arguments. got=0, want=1..2"},
+		{"let add = fn(x, y = 1) { x + y }; add(1, 2, 3);", "wrong number of arguments. got=3, want=1..2"},
+		{"let f = fn(x, ...rest) { x }; f();", "wrong number of arguments. got=0, want>=1"},
+		{"let f = fn(x = missing) { x }; f();", "identifier not found: missing"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestSpread() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = [1, 2]; let b = [3, 4]; [...a, ...b];", []int{1, 2, 3, 4}},
+		{"let a = [2, 3]; [1, ...a, 4];", []int{1, 2, 3, 4}},
+		{"[...[]]", []string{}},
+		{`[..."abc"]`, []string{"a", "b", "c"}},
+		{`[..."", ...""]`, []string{}},
+		{"let c = chan(2); send(c, 1); send(c, 2); close(c); [0, ...c];", []int{0, 1, 2}},
+		{"let f = fn(...xs) { len(xs) }; f(...\"ab\", ...range(3));", 5},
+		{"let add = fn(x, y, z) { x + y + z }; add(...[1, 2, 3]);", 6},
+		{"let add = fn(x, y, z) { x + y + z }; add(1, ...[2, 3]);", 6},
+		{"let f = fn(...xs) { len(xs) }; f(...[1, 2], ...[3]);", 3},
+		{`let d = {"a": 1, "b": 2}; let o = {"b": 3}; {...d, ...o}["b"];`, 3},
+		{`let d = {"a": 1, "b": 2}; let o = {"b": 3}; {...d, ...o}["a"];`, 1},
+		{`let d = {"a": 1}; {...d, "a": 5}["a"];`, 5},
+		{`let d = {"a": 1}; {"a": 5, ...d}["a"];`, 1},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case []int:
+			t.testArrayObject(expected, result)
+		case []string:
+			result, ok := result.(*object.Array)
+			t.Require().True(ok, "*object.Array")
+			t.Require().Len(result.Elements, len(expected))
+			for idx, element := range result.Elements {
+				t.Equal(expected[idx], element.(*object.String).Value)
+			}
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestSpreadErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[...1]", "cannot spread INTEGER, it is not iterable"},
+		{"len(...\"abc\")", "wrong number of arguments. got=3, want=1"},
+		{`{...[1, 2]}`, "cannot spread ARRAY into HASH"},
+		{"[...missing]", "identifier not found: missing"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestPipeExpressions() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let double = fn(x) { x * 2 }; 5 |> double;", 10},
+		{"let add = fn(x, y) { x + y }; 5 |> add(3);", 8},
+		{"[1, 2, 3] |> len", 3},
+		{"[1, 2, 3] |> rest |> first", 2},
+		{"[1] |> push(2) |> push(3)", []int{1, 2, 3}},
+		{"4 |> (x => x * x)", 16},
+		{"1 + 2 |> (x => x * 10)", 30},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case []int:
+			t.testArrayObject(expected, result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestArrowFunctions() {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let double = x => x * 2; double(4);", 8},
+		{"let add = (a, b) => a + b; add(1, 2);", 3},
+		{"let answer = () => 42; answer();", 42},
+		{"let adder = x => y => x + y; adder(2)(3);", 5},
+		{"let f = (x, y = 10) => { let z = x + y; z * 2 }; f(1);", 22},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testIntegerObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestPipeErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 |> 5", "not a function: INTEGER"},
+		{"5 |> missing(1)", "identifier not found: missing"},
+		{"missing |> len", "identifier not found: missing"},
+		{"[1] |> len(2)", "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestThrow() {
+	tests := []struct {
+		input        string
+		expected     string
+		expectedKind string
+	}{
+		{`throw "boom"; 5;`, "boom", object.THROWN_ERROR},
+		{`throw 42;`, "42", object.THROWN_ERROR},
+		{`throw {"message": "bad input", "kind": "ValueError"};`, "bad input", "ValueError"},
+		{`let f = fn() { throw "inner"; 1 }; f() + 1;`, "inner", object.THROWN_ERROR},
+		{`1 + true`, "type mismatch: INTEGER + BOOLEAN", object.RUNTIME_ERROR},
+		{`len(1)`, "argument to `len` not supported. got=`INTEGER`", object.ARGUMENT_ERROR},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+		t.Equal(test.expectedKind, result.(*object.Error).Kind)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestTryCatchFinally() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { throw "boom"; 1 } catch (e) { 2 }`, 2},
+		{`try { throw "boom" } catch (e) { e["message"] }`, "boom"},
+		{`try { throw "boom" } catch (e) { e["kind"] }`, "Error"},
+		{`try { 1 + true } catch (e) { e["kind"] }`, "RuntimeError"},
+		{`try { len(1) } catch (e) { e["kind"] }`, "ArgumentError"},
+		{`try { throw 42 } catch (e) { e["value"] }`, 42},
+		{`try { throw {"message": "m", "code": 7} } catch (e) { e["code"] }`, 7},
+		{`try { throw {"message": "m", "kind": "IOError"} } catch ({kind}) { kind }`, "IOError"},
+		{`try { throw "boom" } catch { 3 }`, 3},
+		{`let x = 0; try { x } finally { 5 }`, 0},
+		{`let f = fn() { try { return 1 } finally { 2 } }; f();`, 1},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f();`, 2},
+		{`try { try { throw "a" } finally { 1 } } catch (e) { e["message"] }`, "a"},
+		{`try { try { throw "a" } catch (e) { throw e["message"] + "b" } } catch (e) { e["message"] }`, "ab"},
+		{`
+      let parse = fn(x) { if (len(x) == 0) { throw "empty" } x };
+      let safe = fn(x) { try { parse(x) } catch (e) { "default" } };
+      safe("") + safe("ok");
+    `, "defaultok"},
+		{`try { } catch (e) { 1 }`, nil},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case string:
+			t.testStringObject(expected, result)
+		case nil:
+			t.testNullObject(result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestErrorTrace() {
+	input := `
+let inner = fn(x) { throw "boom" };
+let outer = fn(x) { inner(x) };
+try { outer(1) } catch (e) { e["trace"] }
+`
+
+	result := t.testEval(input)
+	t.testArrayObject([]string{"at inner (2:19)", "at outer (3:19)"}, result)
+
+	result = t.testEval(`let f = fn() { len(1) }; f();`)
+	t.Equal([]string{"at len (builtin)", "at f (1:14)"}, result.(*object.Error).Trace)
+
+	// Binding a function that already exists does not rename it
+	result = t.testEval(`let fs = [fn() { len(1) }]; let g = fs[0]; g();`)
+	t.Equal([]string{"at len (builtin)", "at <anonymous> (1:16)"}, result.(*object.Error).Trace)
+}
+
+func (t *EvaluatorTestSuite) TestFinallyErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { 1 } finally { throw "from finally" }`, "from finally"},
+		{`try { throw "a" } catch (e) { throw "b" }`, "b"},
+		{`try { throw "a" } finally { 1 }`, "a"},
+		{`try { throw "a" } catch ([x]) { x }`, "cannot destructure HASH as ARRAY"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) writeModules(files map[string]string) string {
+	dir := t.T().TempDir()
+
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		t.Require().NoError(os.MkdirAll(filepath.Dir(path), 0o755))
+		t.Require().NoError(os.WriteFile(path, []byte(source), 0o644))
+	}
+
+	return dir
+}
+
+func (t *EvaluatorTestSuite) testEvalInDir(input string, dir string, searchPath ...string) object.Object {
+	parser := parser.NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+	t.Empty(parser.Errors())
+
+	env := object.NewEnvironment()
+	ctx := NewContext()
+	ctx.Dir = dir
+	ctx.Loader = NewModuleLoader(searchPath...)
+	AttachContext(env, ctx)
+
+	return Eval(program, env)
+}
+
+func (t *EvaluatorTestSuite) TestModules() {
+	dir := t.writeModules(map[string]string{
+		"lib/list.fg": `
+      let iter = fn(arr, accumulated, f) {
+        if (len(arr) == 0) { accumulated } else { iter(rest(arr), push(accumulated, f(first(arr))), f) }
+      };
+      export let map = fn(arr, f) { iter(arr, [], f) };
+      export let [one, two] = [1, 2];
+    `,
+		"lib/math.fg": `
+      import "./list.fg" as list;
+      export let doubleAll = fn(arr) { list.map(arr, fn(x) { x * 2 }) };
+    `,
+	})
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "./lib/list.fg" as list; list.map([1, 2], fn(x) { x + 1 });`, []int{2, 3}},
+		{`import "./lib/list" as list; list.one + list.two;`, 3},
+		{`import "./lib/list.fg"; list.two;`, 2},
+		{`import "./lib/math.fg" as math; math.doubleAll([1, 2, 3]);`, []int{2, 4, 6}},
+		{`import "lib/math" as math; math.doubleAll([4]);`, []int{8}},
+	}
+
+	for _, test := range tests {
+		result := t.testEvalInDir(test.input, dir, dir)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case []int:
+			t.testArrayObject(expected, result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestModuleCaching() {
+	dir := t.writeModules(map[string]string{
+		"a.fg": `import "./c.fg" as c; export let value = c.value;`,
+		"b.fg": `import "./c.fg" as c; export let value = c.value;`,
+		"c.fg": `export let value = [1];`,
+	})
+
+	input := `
+    import "./a.fg" as a;
+    import "./b.fg" as b;
+    import "./c.fg" as c;
+    [a.value, b.value, c.value];
+  `
+
+	result, ok := t.testEvalInDir(input, dir).(*object.Array)
+	t.True(ok, "*object.Array")
+
+	t.Same(result.Elements[0], result.Elements[1])
+	t.Same(result.Elements[1], result.Elements[2])
+}
+
+func (t *EvaluatorTestSuite) TestModuleErrors() {
+	dir := t.writeModules(map[string]string{
+		"a.fg":      `import "./b.fg" as b; export let x = 1;`,
+		"b.fg":      `import "./a.fg" as a; export let y = 2;`,
+		"lib.fg":    `let hidden = 1; export let shown = 2;`,
+		"broken.fg": `let = 5;`,
+		"throws.fg": `throw "module failed";`,
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "./lib.fg" as lib; lib.hidden;`, "module lib has no export named hidden"},
+		{`import "./lib.fg" as lib; lib.missing;`, "module lib has no export named missing"},
+		{`import "./missing.fg" as m;`, `cannot import "./missing.fg": module not found`},
+		{`import "./broken.fg" as m;`, `cannot import "./broken.fg": expected next token to be "IDENT", got "=" instead`},
+		{`import "./throws.fg" as m;`, "module failed"},
+		{`import "./a.fg" as a;`, "import cycle: " + strings.Join([]string{
+			filepath.Join(dir, "a.fg"), filepath.Join(dir, "b.fg"), filepath.Join(dir, "a.fg"),
+		}, " -> ")},
+	}
+
+	for _, test := range tests {
+		result := t.testEvalInDir(test.input, dir)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestMemberAccess() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let h = {"name": "Law", "age": 30}; h.age;`, 30},
+		{`let h = {"name": "Law"}; h.name;`, "Law"},
+		{`let h = {"inner": {"value": 5}}; h.inner.value;`, 5},
+		{`let h = {"name": "Law"}; h.missing;`, nil},
+		{`let h = {"add": fn(x, y) { x + y }}; h.add(1, 2);`, 3},
+		{`let h = {"len": 99}; h.len;`, 99},
+		{`let h = {"a": 1, "b": 2}; h.len();`, 2},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case string:
+			t.testStringObject(expected, result)
+		case nil:
+			t.testNullObject(result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestMethodCalls() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"abc".upper()`, "ABC"},
+		{`"ABC".lower()`, "abc"},
+		{`"  abc ".trim()`, "abc"},
+		{`"abc".len()`, 3},
+		{`"a,b,c".split(",").len()`, 3},
+		{`"abc".contains("b")`, true},
+		{`[1, 2, 3].map(x => x * 2)`, []int{2, 4, 6}},
+		{`[1, 2, 3, 4].filter(x => x > 2)`, []int{3, 4}},
+		{`[1, 2, 3].reduce(0, (acc, x) => acc + x)`, 6},
+		{`[1, 2, 3].map(x => x + 1).filter(x => x > 2).len()`, 2},
+		{`[1, 2, 3].join("-")`, "1-2-3"},
+		{`[1, 2, 3].first() + [1, 2, 3].last()`, 4},
+		{`[1].push(2)`, []int{1, 2}},
+		{`[1, 2].rest()`, []int{2}},
+		{`{"a": 1}.has("a")`, true},
+		{`{"a": 1}.has("b")`, false},
+		{`{"a": 1}.get("b", 7)`, 7},
+		{`{"a": 1}.keys()`, []string{"a"}},
+		{`{"a": 1}.values()`, []int{1}},
+		{`{"c": 3, "a": 1, "b": 2, "e": 5, "d": 4}.keys()`, []string{"a", "b", "c", "d", "e"}},
+		{`{"c": 3, "a": 1, "b": 2, "e": 5, "d": 4}.values()`, []int{1, 2, 3, 4, 5}},
+		{`{10: 3, 2: 2, 1: 1}.values()`, []int{1, 2, 3}},
+		{`let up = "abc".upper; up();`, "ABC"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case string:
+			t.testStringObject(expected, result)
+		case bool:
+			t.testBooleanObject(expected, result)
+		case []int, []string:
+			t.testArrayObject(expected, result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestMethodErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`5.x`, "unknown method x for INTEGER"},
+		{`"abc".reverse()`, "unknown method reverse for STRING"},
+		{`"abc".upper(1)`, "wrong number of arguments to `upper`. got=1, want=0"},
+		{`[1, 2].len(1)`, "wrong number of arguments to `len`. got=1, want=0"},
+		{`"abc".len(1)`, "wrong number of arguments to `len`. got=1, want=0"},
+		{`[1, 2].first(1)`, "wrong number of arguments to `first`. got=1, want=0"},
+		{`[1, 2].push()`, "wrong number of arguments to `push`. got=0, want=1"},
+		{`"a,b".split(1)`, "argument to `split` must be `STRING`, got=`INTEGER`"},
+		{`[1, 2].map(x => x + true)`, "type mismatch: INTEGER + BOOLEAN"},
+		{`[1].map(5)`, "not a function: INTEGER"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestRegisterMethod() {
+	RegisterMethod(object.INTEGER_OBJ, "double", func(receiver object.Object, args ...object.Object) object.Object {
+		return &object.Integer{Value: receiver.(*object.Integer).Value * 2}
+	})
+	defer delete(methodsMap, object.INTEGER_OBJ)
+
+	t.testIntegerObject(42, t.testEval(`let x = 21; x.double()`))
+
+	// Hosts may register methods while spawned functions look them up
+	registered := make(chan struct{})
+	go func() {
+		defer close(registered)
+
+		for idx := 0; idx < 100; idx++ {
+			RegisterMethod(object.BOOLEAN_OBJ, fmt.Sprintf("m%d", idx), func(receiver object.Object, args ...object.Object) object.Object {
+				return receiver
+			})
+		}
+	}()
+
+	t.testIntegerObject(50, t.testEval(`
+    let count = fn(out) { out.send("a".len()) };
+    let out = chan(50);
+    for (x in range(50)) { spawn count(out) };
+    collect(take(out, 50)).reduce(0, fn(acc, x) { acc + x })
+  `))
+
+	<-registered
+	delete(methodsMap, object.BOOLEAN_OBJ)
+}
+
+func (t *EvaluatorTestSuite) TestStructs() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"struct Point { x, y }; let p = Point(1, 2); p.x + p.y;", 3},
+		{"struct Point { x, y }; Point(1, 2) == Point(1, 2);", true},
+		{"struct Point { x, y }; Point(1, 2) == Point(2, 1);", false},
+		{"struct Point { x, y }; Point(1, 2) != Point(2, 1);", true},
+		{"struct Line { from, to }; struct Point { x, y }; Line(Point(0, 0), [1]) == Line(Point(0, 0), [1]);", true},
+		{"struct A { x }; struct B { x }; A(1) == B(1);", false},
+		{"struct Point { x, y }; Point(1, 2);", "Point{x: 1, y: 2}"},
+		{`struct User { name, tags }; User("Law", ["a", "b"]);`, `User{name: Law, tags: [a, b]}`},
+		{"struct Point { x, y }; Point;", "struct Point { x, y }"},
+		{"struct Point { x, y }; let {x, y} = Point(3, 4); x * y;", 12},
+		{"struct Point { x, y }; let {x, ...others} = Point(3, 4); others.y;", 4},
+		{"struct Point { x, y }; let norm = fn({x, y}) { x * x + y * y }; norm(Point(1, 2));", 5},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case bool:
+			t.testBooleanObject(expected, result)
+		case string:
+			t.Equal(expected, result.String())
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestStructType() {
+	result := t.testEval("struct Point { x, y }; Point(1, 2);")
+
+	t.Equal(object.ObjectType(object.STRUCT_OBJ), result.Type())
+
+	RegisterDeclaredMethod("Point", "sum", func(receiver object.Object, args ...object.Object) object.Object {
+		point := receiver.(*object.Struct)
+		return &object.Integer{Value: point.Values[0].(*object.Integer).Value + point.Values[1].(*object.Integer).Value}
+	})
+	defer delete(declaredMethodsMap, "Point")
+
+	t.testIntegerObject(3, t.testEval("struct Point { x, y }; Point(1, 2).sum();"))
+	t.testErrorObject("Line has no field sum", t.testEval("struct Line { a, b }; Line(1, 2).sum();"))
+
+	// Structs named like built in types are not taken for them
+	t.testErrorObject("STRING has no field upper", t.testEval(`struct STRING { a }; let s = STRING(1); s.upper();`))
+
+	result = t.testEval(`struct ERROR { a }; let e = ERROR(1); [e.a, 2]`)
+	t.Equal("[1, 2]", result.String())
+
+	t.testIntegerObject(1, t.testEval(`let f = fn(e: ERROR): int { e.a }; struct ERROR { a }; f(ERROR(1))`))
+}
+
+func (t *EvaluatorTestSuite) TestStructErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y }; Point(1);", "wrong number of arguments to `Point`. got=1, want=2"},
+		{"struct Point { x, y }; Point(1, 2).z;", "Point has no field z"},
+		{"struct Point { x, y }; Point(1, 2) + 1;", "type mismatch: Point + INTEGER"},
+		{"struct Point { x, y }; let {z} = Point(1, 2);", "destructuring mismatch: Point has no field z"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestEnums() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"enum Result { Ok(value), Err(message) }; Ok(5);", "Ok(5)"},
+		{"enum Result { Ok(value), Err(message) }; Result.Err(\"bad\");", "Err(bad)"},
+		{"enum Option { Some(value), None }; None;", "None"},
+		{"enum Option { Some(value), None }; Option.None == None;", true},
+		{"enum Option { Some(value), None }; Some(1) == Some(1);", true},
+		{"enum Option { Some(value), None }; Some(1) == Some(2);", false},
+		{"enum Option { Some(value), None }; Some(1) != None;", true},
+		{"enum Option { Some(value), None }; Option;", "enum Option { Some(value), None }"},
+		{"enum Option { Some(value), None }; Some;", "Option.Some(value)"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case bool:
+			t.testBooleanObject(expected, result)
+		case string:
+			t.Equal(expected, result.String())
+		}
+	}
+
+	result := t.testEval("enum Option { Some(value), None }; Some(1);")
+	t.Equal(object.ObjectType(object.ENUM_OBJ), result.Type())
+	t.Equal("Option", typeOf(result))
+
+	// Enums named like built in types are not taken for them
+	t.testErrorObject("unknown method upper for STRING", t.testEval(`enum STRING { A }; A.upper()`))
+	t.Equal("[A, 1]", t.testEval(`enum ERROR { A }; [A, 1]`).String())
+
+	// Variants of two enums cannot share a name, one would replace the other
+	t.testErrorObject(
+		"variant Ok of Outcome is already declared by Result",
+		t.testEval("enum Result { Ok(v), Err(m) }; enum Outcome { Ok, Failed };"),
+	)
+	t.testIntegerObject(1, t.testEval("enum Result { Ok(v) }; enum Result { Ok(v) }; match (Ok(1)) { Ok(v) => v }"))
+	t.testIntegerObject(1, t.testEval("enum Result { Ok(v) }; let f = fn() { enum Outcome { Ok }; 1 }; f()"))
+}
+
+func (t *EvaluatorTestSuite) TestMatchExpressions() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`
+      enum Result { Ok(value), Err(message) };
+      let unwrap = fn(r) { match (r) { Ok(v) => v, Err(m) => 0 } };
+      unwrap(Ok(5)) + unwrap(Err("bad"));
+    `, 5},
+		{`
+      enum Option { Some(value), None };
+      let orElse = fn(o, d) { match (o) { Some(v) => v, None => d } };
+      orElse(None, 7);
+    `, 7},
+		{`
+      enum Shape { Circle(r), Rect(w, h) };
+      let area = fn(s) {
+        match (s) {
+          Shape.Circle(r) => 3 * r * r,
+          Rect(w, h) => { let a = w * h; a }
+        }
+      };
+      area(Circle(2)) + area(Rect(2, 3));
+    `, 18},
+		{`
+      enum Result { Ok(value), Err(message) };
+      match (Err(404)) { Err(500) => 1, Err(404) => 2, _ => 3 };
+    `, 2},
+		{`
+      enum Tree { Leaf(v), Node(l, r) };
+      let sum = fn(t) { match (t) { Leaf(v) => v, Node(l, r) => sum(l) + sum(r) } };
+      sum(Node(Leaf(1), Node(Leaf(2), Leaf(3))));
+    `, 6},
+		{`match (3) { 1 => "one", 3 => "three", _ => "many" }`, "three"},
+		{`match ("b") { "a" => 1, other => other }`, "b"},
+		{`match (true) { false => 0, true => 1 }`, 1},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case string:
+			t.testStringObject(expected, result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestMatchErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (5) { 1 => 1 }`, "no match arm for 5"},
+		{`enum R { Ok(v) }; Ok(1, 2);`, "wrong number of arguments to `Ok`. got=2, want=1"},
+		{`enum R { Ok(v) }; match (Ok(1)) { Ok(a, b) => a };`, "wrong number of fields in pattern for Ok. got=2, want=1"},
+		{`enum O { None }; None();`, "not a function: O"},
+		{`enum O { None }; O.Some;`, "enum O has no variant Some"},
+		{`match (1) { Missing(x) => x }`, "identifier not found: Missing"},
+		{`let f = fn(x) { x }; match (1) { f(x) => x }`, "f is not an enum variant with fields"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestGenerators() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let g = fn() { yield 1; yield 2; yield 3 }; [...g()]`, []int{1, 2, 3}},
+		{`let g = fn(n) { for (x in range(n)) { yield x * x } }; collect(g(4))`, []int{0, 1, 4, 9}},
+		{`let g = fn() { yield 1; return 5; yield 2 }; collect(g())`, []int{1}},
+		{`let nat = fn() { let loop = fn(n) { yield n; loop(n + 1) }; loop(0) }; collect(take(nat(), 3))`, []int{0}},
+		{`
+      let naturals = fn(n) { yield n; for (x in naturals(n + 1)) { yield x } };
+      collect(take(naturals(1), 5))
+    `, []int{1, 2, 3, 4, 5}},
+		{`let g = fn() { yield 1; yield 2 }; let it = g(); it.next(); it.next()["value"]`, 2},
+		{`let g = fn() { yield 1 }; let it = g(); next(it); next(it)["done"]`, true},
+		{`let g = fn() { yield 1; yield 2; yield 3 }; let it = g(); collect(take(it, 1)); collect(it)`, []int{2, 3}},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case []int:
+			t.testArrayObject(expected, result)
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case bool:
+			t.testBooleanObject(expected, result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestLazyBuiltIns() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`collect(range(4))`, []int{0, 1, 2, 3}},
+		{`collect(range(2, 5))`, []int{2, 3, 4}},
+		{`collect(range(10, 0, -3))`, []int{10, 7, 4, 1}},
+		{`collect(take(map(range(1000000000), x => x * 2), 3))`, []int{0, 2, 4}},
+		{`collect(take(filter(range(1000000000), x => x / 2 * 2 == x), 3))`, []int{0, 2, 4}},
+		{`collect(map([1, 2, 3], fn(x) { x + 1 }))`, []int{2, 3, 4}},
+		{`collect(map(zip(range(3), [10, 20, 30, 40]), fn([a, b]) { a + b }))`, []int{10, 21, 32}},
+		{`collect(iter("abc"))`, []string{"a", "b", "c"}},
+		{`range(3).map(x => x + 1).filter(x => x > 1).collect()`, []int{2, 3}},
+		{`range(100).take(2).collect()`, []int{0, 1}},
+		{`[0, ...range(1, 3), 3]`, []int{0, 1, 2, 3}},
+		{`let xs = range(3); [...xs, ...xs]`, []int{0, 1, 2}},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testArrayObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestForExpressions() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let count = fn(xs) { let n = 0; for (x in xs) { let n = n + 1; }; n }; count([1, 2])`, 0},
+		{`let find = fn(xs, y) { for (x in xs) { if (x == y) { return x * 10 } }; -1 }; find(range(100), 7)`, 70},
+		{`let find = fn(xs, y) { for (x in xs) { if (x == y) { return x } }; -1 }; find([1, 2], 7)`, -1},
+		{`let firsts = fn(xs) { for ({a} in xs) { return a } }; firsts([{"a": 4}])`, 4},
+		{`let fns = collect(map(range(3), x => fn() { x })); fns[2]()`, 2},
+		{`for (x in []) { x }`, nil},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case nil:
+			t.testNullObject(result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestIteratorErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`for (x in 5) { x }`, "cannot iterate over INTEGER"},
+		{`range(1, 2, 0)`, "`range` step must not be 0"},
+		{`range("a")`, "arguments to `range` must be `INTEGER`, got=`STRING`"},
+		{`take(5, 1)`, "argument to `take` must be iterable, got=`INTEGER`"},
+		{`collect(map(range(3), fn(x) { x + "a" }))`, "type mismatch: INTEGER + STRING"},
+		{`let g = fn() { yield 1; throw "boom" }; [...g()]`, "boom"},
+		{`let g = fn() { yield 1; missing }; for (x in g()) { x }`, "identifier not found: missing"},
+		{`next([1])`, "argument to `next` must be `ITERATOR`, got=`ARRAY`"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestSpawnAndChannels() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let add = fn(a, b) { a + b }; recv(spawn add(1, 2))`, 3},
+		{`let c = chan(); spawn send(c, 5); recv(c)`, 5},
+		{`let c = chan(2); c.send(1); c.send(2); c.recv() + c.recv()`, 3},
+		{`let c = chan(1); c.close(); c.recv()`, nil},
+		{`
+      let producer = fn(c, n) { for (x in range(n)) { c.send(x) }; c.close() };
+      let c = chan();
+      spawn producer(c, 5);
+      collect(c)
+    `, []int{0, 1, 2, 3, 4}},
+		{`
+      let square = fn(x, out) { out.send(x * x) };
+      let out = chan(10);
+      let tasks = collect(map(range(10), fn(x) { spawn square(x, out) }));
+      for (task in tasks) { recv(task) };
+      out.close();
+      collect(out).reduce(0, fn(acc, x) { acc + x })
+    `, 285},
+		{`let limit = 3; let read = fn() { limit * 2 }; let limit = 4; recv(spawn read())`, 8},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case []int:
+			t.testArrayObject(expected, result)
+		case nil:
+			t.testNullObject(result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestSelectExpressions() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let a = chan(1); let b = chan(1); b.send(7); select { recv(a) as v => v, recv(b) as v => v * 2 }`, 14},
+		{`let a = chan(); select { recv(a) as v => v, _ => "empty" }`, "empty"},
+		{`let a = chan(1); select { send(a, 3) => a.recv(), _ => 0 }`, 3},
+		{`let a = chan(); a.close(); select { recv(a) as v => v }`, nil},
+		{`let a = chan(); spawn fn() { a.send("late") }(); select { recv(a) as v => v }`, "late"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case string:
+			t.testStringObject(expected, result)
+		case nil:
+			t.testNullObject(result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestConcurrencyErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let fail = fn() { throw "boom" }; recv(spawn fail())`, "boom"},
+		{`spawn missing()`, "identifier not found: missing"},
+		{`let c = chan(); c.close(); c.send(1)`, "send on closed channel"},
+		{`let c = chan(); c.close(); close(c)`, "close of closed channel"},
+		{`let c = chan(); c.close(); select { send(c, 1) => 1 }`, "send on closed channel"},
+		{`select { recv(5) => 1 }`, "cannot select on INTEGER, expected CHANNEL"},
+		{`send(1, 2)`, "first argument to `send` must be `CHANNEL`, got=`INTEGER`"},
+		{`chan(-1)`, "argument to `chan` must be a positive `INTEGER`, got=`-1`"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestConcurrentImports() {
+	dir := t.writeModules(map[string]string{
+		"counter.fg": `export let value = 42;`,
+	})
+
+	result := t.testEvalInDir(`
+    let load = fn(out) { import "./counter"; out.send(counter.value) };
+    let out = chan(8);
+    for (x in range(8)) { spawn load(out) };
+    collect(take(out, 8)).reduce(0, fn(acc, x) { acc + x })
+  `, dir)
+
+	t.testIntegerObject(336, result)
+}
+
+func (t *EvaluatorTestSuite) TestConcurrentImportCycle() {
+	dir := t.writeModules(map[string]string{
+		"a.fg": `await sleep(20); import "./b"; export let a = 1;`,
+		"b.fg": `await sleep(20); import "./a"; export let b = 2;`,
+	})
+
+	// Each import starts on its own, then waits on the module the other one is evaluating
+	loader := NewModuleLoader()
+	errs := make(chan *object.Error, 2)
+	for _, path := range []string{"./a", "./b"} {
+		go func(path string) {
+			ctx := NewContext()
+			ctx.Dir = dir
+			ctx.Loader = loader
+
+			_, err := loader.Load(ctx, path, dir)
+			errs <- err
+		}(path)
+	}
+
+	for idx := 0; idx < 2; idx++ {
+		select {
+		case err := <-errs:
+			t.Require().NotNil(err)
+			t.Contains(err.Message, "import cycle: ")
+		case <-time.After(5 * time.Second):
+			t.FailNow("concurrent imports deadlocked")
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestAsyncAwait() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let double = async fn(x) { x * 2 }; await double(21)`, 42},
+		{`let f = async fn() { await sleep(1); 7 }; await f()`, 7},
+		{`let f = async fn(x) { return x; 1 }; await f(3)`, 3},
+		{`await 5`, 5},
+		{`await setTimeout(fn() { "done" }, 1)`, "done"},
+		{`let f = async fn(x) { x + 1 }; await f(1).then(fn(x) { x * 10 })`, 20},
+		{`
+      let inner = async fn(x) { await sleep(1); x * 2 };
+      let outer = async fn(x) { let y = await inner(x); y + 1 };
+      await outer(5)
+    `, 11},
+		{`
+      let log = chan(10);
+      let task = async fn(name, ms) { await sleep(ms); log.send(name) };
+      let slow = task("slow", 60);
+      let fast = task("fast", 1);
+      log.send("sync");
+      await slow;
+      await fast;
+      log.close();
+      collect(log)
+    `, []string{"sync", "fast", "slow"}},
+		{`
+      let fail = async fn() { await sleep(1); throw "boom" };
+      try { await fail() } catch (e) { e["message"] }
+    `, "boom"},
+		// Closures created in an async body await on their own once it finished
+		{`
+      let mk = async fn() { fn(p) { await p; 3 } };
+      let f = await mk();
+      f(sleep(10))
+    `, 3},
+		{`
+      let mk = async fn() { fn(p) { await p; 4 } };
+      let use = async fn(f) { await sleep(1); f(sleep(1)) };
+      await use(await mk())
+    `, 4},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case string:
+			t.testStringObject(expected, result)
+		case []string:
+			t.testArrayObject(expected, result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestAsyncErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = async fn() { missing }; await f()`, "identifier not found: missing"},
+		{`sleep(-1)`, "delay of `sleep` must be a positive `INTEGER` of milliseconds, got=`-1`"},
+		{`setTimeout(fn() { 1 })`, "wrong number of arguments. got=1, want=2"},
+		{`await setTimeout(fn() { 1 + true }, 1)`, "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+		t.testErrorObject(test.expected, result)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestRegisterBuiltIn() {
+	RegisterBuiltIn("lookup", func(ctx *Context, args ...object.Object) object.Object {
+		promise, settle := ctx.Loop.NewPromise()
+
+		// Resolved from another goroutine, as a host waiting on a database would
+		go func() {
+			time.Sleep(time.Millisecond)
+			settle(&object.Integer{Value: args[0].(*object.Integer).Value + 100})
+		}()
+
+		return promise
+	})
+	defer delete(builtInsMap, "lookup")
+
+	t.testIntegerObject(101, t.testEval(`let get = async fn(id) { await lookup(id) }; await get(1)`))
+
+	// Hosts may register built ins while spawned functions look them up
+	registered := make(chan struct{})
+	go func() {
+		defer close(registered)
+
+		for idx := 0; idx < 100; idx++ {
+			RegisterBuiltIn(fmt.Sprintf("host%d", idx), func(ctx *Context, args ...object.Object) object.Object { return NULL })
+		}
+	}()
+
+	t.testIntegerObject(50, t.testEval(`
+    let count = fn(out) { send(out, len("a")) };
+    let out = chan(50);
+    for (x in range(50)) { spawn count(out) };
+    collect(take(out, 50)).reduce(0, fn(acc, x) { acc + x })
+  `))
+
+	<-registered
+	for idx := 0; idx < 100; idx++ {
+		delete(builtInsMap, fmt.Sprintf("host%d", idx))
+	}
+}
+
+func (t *EvaluatorTestSuite) TestBuiltInNames() {
+	RegisterBuiltIn("lookup", func(ctx *Context, args ...object.Object) object.Object { return NULL })
+	defer delete(builtInsMap, "lookup")
+
+	names := BuiltInNames()
+
+	t.IsIncreasing(names)
+	t.Subset(names, []string{"len", "print", "range", "sleep", "lookup"})
+	t.NotContains(names, "quote")
+}
+
+func (t *EvaluatorTestSuite) TestBuiltInSignatures() {
+	for _, name := range BuiltInNames() {
+		signature, ok := BuiltInSignature(name)
+		t.True(ok, name)
+
+		annotation, err := parser.ParseType(signature)
+		t.NoError(err, name)
+		t.Equal("fn", annotation.Name, name)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestInputOutput() {
+	tests := []struct {
+		input    string
+		stdin    string
+		expected interface{}
+		stdout   string
+	}{
+		{`print(1, "two")`, "", nil, "1\ntwo\n"},
+		{`let name = input("name? "); print("hi " + name); name`, "Ada\nBob\n", "Ada", "name? hi Ada\n"},
+		{`input() + readline()`, "a\r\nb", "ab", ""},
+		{`readline()`, "\n", "", ""},
+		{`readline()`, "", nil, ""},
+		{`[readline(), readline(), readline()]`, "a\nb\n", `["a", "b", null]`, ""},
+		{`input("> ")`, "", "input: end of input", "> "},
+		{`input(1)`, "", "argument to `input` must be `STRING`, got=`INTEGER`", ""},
+		{`readline(1)`, "", "wrong number of arguments. got=1, want=0", ""},
+	}
+
+	for _, tt := range tests {
+		program := parser.NewParser(lexer.NewLexer(tt.input)).ParseProgram()
+		stdout := &strings.Builder{}
+
+		env := object.NewEnvironment()
+		ctx := NewContext()
+		ctx.Stdin = strings.NewReader(tt.stdin)
+		ctx.Stdout = stdout
+		AttachContext(env, ctx)
+
+		evaluated := Eval(program, env)
+
+		switch expected := tt.expected.(type) {
+		case nil:
+			t.Equal(NULL, evaluated, tt.input)
+		case string:
+			if err, ok := evaluated.(*object.Error); ok {
+				t.Equal(expected, err.Message, tt.input)
+			} else if _, ok := evaluated.(*object.Array); ok {
+				t.Equal(expected, formatValue(evaluated), tt.input)
+			} else {
+				t.testStringObject(expected, evaluated)
+			}
+		}
+
+		t.Equal(tt.stdout, stdout.String(), tt.input)
+	}
+}
+
+// Blocks writes until released, signalling the first one
+type blockingWriter struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.entered:
+	default:
+		close(w.entered)
+	}
+
+	<-w.release
+
+	return len(p), nil
+}
+
+func (t *EvaluatorTestSuite) TestOutputPerContext() {
+	program := parser.NewParser(lexer.NewLexer(`print("line")`)).ParseProgram()
+
+	// The first program blocks in print until its output is released
+	writer := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+	defer close(writer.release)
+
+	blocked := object.NewEnvironment()
+	ctx := NewContext()
+	ctx.Stdout = writer
+	AttachContext(blocked, ctx)
+
+	go Eval(program, blocked)
+	<-writer.entered
+
+	stdout := &strings.Builder{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		env := object.NewEnvironment()
+		ctx := NewContext()
+		ctx.Stdout = stdout
+		AttachContext(env, ctx)
+
+		Eval(program, env)
+	}()
+
+	select {
+	case <-done:
+		t.Equal("line\n", stdout.String())
+	case <-time.After(5 * time.Second):
+		t.Fail("print waited on another program's output")
+	}
+}
+
+func (t *EvaluatorTestSuite) testEvalTyped(input string, checkTypes bool) object.Object {
+	parser := parser.NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+	t.Empty(parser.Errors())
+
+	env := object.NewEnvironment()
+	ctx := NewContext()
+	ctx.CheckTypes = checkTypes
+	AttachContext(env, ctx)
+
+	return Eval(program, env)
+}
+
+func (t *EvaluatorTestSuite) TestTypeAnnotations() {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let add = fn(a: int, b: int): int { a + b }; add(1, 2)`, 3},
+		{`let x: string = "a"; x`, "a"},
+		{`let f = fn(xs: array[int]) { len(xs) }; f([1, 2, 3])`, 3},
+		{`let f = fn(h: hash[string, int]) { h["a"] }; f({"a": 1})`, 1},
+		{`let f = fn(x: any, ...rest: array[int]) { len(rest) }; f("a", 1, 2)`, 2},
+		{`let f = fn(x: int = 5) { x }; f()`, 5},
+		{`let f = fn(x: string) { x }; f("a")`, "a"},
+		{`let f = fn(g: fn(int): int) { g(2) }; f(fn(x) { x * 2 })`, 4},
+		{`let f = fn(g: fn) { g([1]) }; f(len)`, 1},
+		{`struct Point { x, y }; let f = fn(p: Point): int { p.x }; f(Point(1, 2))`, 1},
+		{`enum Shape { Dot, Circle(r) }; let f = fn(s: Shape) { 1 }; f(Shape.Dot)`, 1},
+		{`let f = fn(x: int): int { if (x > 1) { return x } 0 }; f(5)`, 5},
+		{`let f = async fn(): int { 1 }; f().then(fn(x) { x }); 2`, 2},
+	}
+
+	for _, test := range tests {
+		result := t.testEvalTyped(test.input, true)
+
+		switch expected := test.expected.(type) {
+		case int:
+			t.testIntegerObject(int64(expected), result)
+		case string:
+			t.testStringObject(expected, result)
+		}
+	}
+}
+
+func (t *EvaluatorTestSuite) TestTypeAnnotationErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let add = fn(a: int, b: int) { a + b }; add(1, "2")`, "argument b of add must be int, got string"},
+		{`let f = fn(): int { "a" }; f()`, "result of f must be int, got string"},
+		{`let f = fn(x): string { return x }; f(1)`, "result of f must be string, got int"},
+		{`let x: bool = 1`, "x must be bool, got int"},
+		{`let [a, b]: array[string] = ["a", 1]`, "[a, b] must be array[string], got array"},
+		{`let f = fn(x: int = "a") { x }; f()`, "argument x of f must be int, got string"},
+		{`let f = fn(...xs: array[int]) { xs }; f(1, true)`, "argument ...xs of f must be array[int], got array"},
+		{`let f = fn(h: hash[string, int]) { h }; f({"a": "b"})`, "argument h of f must be hash[string, int], got hash"},
+		{`let f = fn(g: fn) { g }; f(1)`, "argument g of f must be fn, got int"},
+		{`struct A { x }; struct B { x }; let f = fn(a: A) { a }; f(B(1))`, "argument a of f must be A, got B"},
+		{`let f = fn(x: Nope) { x }; f(1)`, "unknown type Nope"},
+		{`fn(x: int) { x }("a")`, "argument x of <anonymous> must be int, got string"},
+	}
+
+	for _, test := range tests {
+		result := t.testEvalTyped(test.input, true)
+		t.testErrorObject(test.expected, result)
+		t.Equal(object.TYPE_ERROR, result.(*object.Error).Kind, test.input)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestTypeAnnotationsNotEnforcedByDefault() {
+	result := t.testEvalTyped(`let f = fn(x: int): int { x }; let y: bool = f("a"); y`, false)
+
+	t.testStringObject("a", result)
+}
+
+func (t *EvaluatorTestSuite) TestRunEventLoop() {
+	parser := parser.NewParser(lexer.NewLexer(`
+    let out = chan(2);
+    setTimeout(fn() { out.send(1) }, 5);
+    let later = async fn() { await sleep(1); out.send(2) };
+    later();
+    out
+  `))
+	program := parser.ParseProgram()
+	env := object.NewEnvironment()
+
+	out := Eval(program, env).(*object.Channel)
+	t.Len(out.Value, 0)
+
+	RunEventLoop(env)
+	t.Len(out.Value, 2)
+}
+
+func (t *EvaluatorTestSuite) TestQuoteUnquote() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(5 + 8)`, `(5 + 8)`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+		{`quote(unquote(4))`, `4`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`quote(unquote(4 + 4) + 8)`, `(8 + 8)`},
+		{`let foobar = 8; quote(foobar)`, `foobar`},
+		{`let foobar = 8; quote(unquote(foobar))`, `8`},
+		{`quote(unquote(true == false))`, `false`},
+		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+		{`let q = quote(4 + 4); quote(unquote(4 + 4) + unquote(q))`, `(8 + (4 + 4))`},
+		{`let f = fn(x) { quote(unquote(x) + 1) }; f(1); f(2)`, `(2 + 1)`},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		quote, ok := result.(*object.Quote)
+		t.True(ok, "*object.Quote")
+		t.Equal(test.expected, quote.Node.String())
+	}
+
+	t.testErrorObject("wrong number of arguments to `quote`. got=2, want=1", t.testEval(`quote(1, 2)`))
+}
+
+func (t *EvaluatorTestSuite) testExpandMacros(input string) (ast.Node, *object.Error) {
+	parser := parser.NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+	t.Empty(parser.Errors())
+
+	env := object.NewEnvironment()
+	DefineMacros(program, env)
+
+	return ExpandMacros(program, env)
+}
+
+func (t *EvaluatorTestSuite) TestDefineMacros() {
+	input := `
+    let number = 1;
+    let function = fn(x, y) { x + y };
+    let mymacro = macro(x, y) { x + y; };
+  `
+
+	parser := parser.NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+	env := object.NewEnvironment()
+
+	DefineMacros(program, env)
+
+	t.Len(program.Statements, 2)
+
+	_, ok := env.Get("number")
+	t.False(ok)
+
+	value, ok := env.Get("mymacro")
+	t.True(ok)
+
+	macro, ok := value.(*object.Macro)
+	t.True(ok, "*object.Macro")
+	t.Len(macro.Parameters, 2)
+	t.Equal("(x + y)", macro.Body.String())
+}
+
+func (t *EvaluatorTestSuite) TestExpandMacros() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`
+      let infixExpression = macro() { quote(1 + 2); };
+      infixExpression();
+    `, `(1 + 2)`},
+		{`
+      let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+      reverse(2 + 2, 10 - 5);
+    `, `(10 - 5) - (2 + 2)`},
+		{`
+      let unless = macro(condition, consequence, alternative) {
+        quote(if (!(unquote(condition))) { unquote(consequence); } else { unquote(alternative); });
+      };
+      unless(10 > 5, print("not greater"), print("greater"));
+    `, `if (!(10 > 5)) { print("not greater") } else { print("greater") }`},
+	}
+
+	for _, test := range tests {
+		expected := parser.NewParser(lexer.NewLexer(test.expected)).ParseProgram()
+
+		expanded, err := t.testExpandMacros(test.input)
+		t.Nil(err)
+		t.Equal(expected.String(), expanded.String())
+	}
+}
+
+func (t *EvaluatorTestSuite) TestMacroHygiene() {
+	input := `
+    let swap = macro(a, b) {
+      quote(fn() { let tmp = unquote(a); [unquote(b), tmp] }());
+    };
+    let tmp = 1;
+    let other = 2;
+    swap(tmp, other);
+  `
+
+	expanded, err := t.testExpandMacros(input)
+	t.Nil(err)
+
+	// The macro's own tmp is renamed, so the tmp passed to it still refers to the program's binding
+	t.testArrayObject([]int{2, 1}, Eval(expanded, object.NewEnvironment()))
+	t.NotContains(expanded.String(), "let tmp = tmp")
+
+	input = `
+    let withKey = macro(value) {
+      quote(fn() { let key = unquote(value); let h = {"key": key}; h.key }());
+    };
+    let key = 7;
+    withKey(key);
+  `
+
+	expanded, err = t.testExpandMacros(input)
+	t.Nil(err)
+	t.testIntegerObject(7, Eval(expanded, object.NewEnvironment()))
+
+	// Names are renamed per scope: the x bound by the inner function is renamed, the x used outside of it is the
+	// program's, and the inner y shadows the renamed one only within its function
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+      let twice = macro(v) { quote(fn(x) { x * 2 }(unquote(v)) + x) };
+      let x = 10;
+      twice(1);
+    `, 12},
+		{`
+      let m = macro(v) { quote(fn() { let y = unquote(v); let f = fn(y) { y * 10 }; f(1) + y }()) };
+      let y = 5;
+      m(y);
+    `, 15},
+		{`
+      let m = macro(v) { quote(fn() { let total = 0; for (i in [1, 2]) { let total = i }; total + unquote(v) }()) };
+      let i = 100;
+      m(i);
+    `, 100},
+	}
+
+	for _, test := range tests {
+		expanded, err := t.testExpandMacros(test.input)
+		t.Nil(err)
+		t.testIntegerObject(test.expected, Eval(expanded, object.NewEnvironment()))
+	}
+
+	// Names the quote uses without binding them are not renamed: they resolve at the call site, where a parameter
+	// shadows the len built in
+	input = `
+    let count = macro(v) { quote(len(unquote(v))) };
+    let f = fn(len) { count([1, 2]) };
+    [count([1, 2]), f(fn(xs) { 42 })];
+  `
+
+	expanded, err = t.testExpandMacros(input)
+	t.Nil(err)
+	t.testArrayObject([]int{2, 42}, Eval(expanded, object.NewEnvironment()))
+}
+
+func (t *EvaluatorTestSuite) TestMacroErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = macro(x) { quote(x) }; m(1, 2)`, "wrong number of arguments to macro `m`. got=2, want=1"},
+		{`let m = macro(x) { 5 }; m(1)`, "macro `m` must return a QUOTE, got INTEGER"},
+		{`let m = macro(x) { quote(unquote(missing)) }; m(1)`, "identifier not found: missing"},
+		{`let m = macro(x) { quote(unquote([1])) }; m(1)`, "cannot unquote ARRAY"},
+	}
+
+	for _, test := range tests {
+		_, err := t.testExpandMacros(test.input)
+		t.testErrorObject(test.expected, err)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestModuleMacros() {
+	dir := t.writeModules(map[string]string{
+		"twice.fg": `
+      let twice = macro(x) { quote(unquote(x) * 2) };
+      export let value = twice(21);
+    `,
+	})
+
+	t.testIntegerObject(42, t.testEvalInDir(`import "./twice"; twice.value`, dir))
+}
+
+// Runs input under a debugger answering each stop with the next command, and returns where it stopped
+func (t *EvaluatorTestSuite) testDebug(input string, breakpoints []int, commands ...DebugCommand) ([]string, object.Object) {
+	parser := parser.NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+	t.Empty(parser.Errors())
+
+	stops := []string{}
+	debugger := NewDebugger(func(stop *Stop) DebugCommand {
+		frames := []string{}
+		for _, frame := range stop.Frames {
+			frames = append(frames, fmt.Sprintf("%s:%d", frame.Name, frame.Line))
+		}
+		stops = append(stops, fmt.Sprintf("%s %s", stop.Reason, strings.Join(frames, " < ")))
+
+		if len(commands) == 0 {
+			return Continue
+		}
+
+		command := commands[0]
+		commands = commands[1:]
+
+		return command
+	})
+	debugger.StopOnEntry = true
+	debugger.SetBreakpoints(breakpoints)
+
+	env := object.NewEnvironment()
+	ctx := NewContext()
+	ctx.Debugger = debugger
+	AttachContext(env, ctx)
+
+	return stops, Eval(program, env)
+}
+
+func (t *EvaluatorTestSuite) TestDebugger() {
+	input := `let double = fn(x) {
+  let y = x * 2;
+  y
+};
+let a = double(1);
+let b = double(a);
+b`
+
+	tests := []struct {
+		breakpoints []int
+		commands    []DebugCommand
+		expected    []string
+	}{
+		{nil, nil, []string{"entry <main>:1"}},
+		{[]int{2}, nil, []string{"entry <main>:1", "breakpoint double:2 < <main>:5", "breakpoint double:2 < <main>:6"}},
+		{nil, []DebugCommand{StepOver, StepOver, StepOver}, []string{
+			"entry <main>:1",
+			"step <main>:5",
+			"step <main>:6",
+			"step <main>:7",
+		}},
+		{nil, []DebugCommand{StepOver, StepIn, StepIn, StepIn, StepIn}, []string{
+			"entry <main>:1",
+			"step <main>:5",
+			"step double:2 < <main>:5",
+			"step double:3 < <main>:5",
+			"step <main>:6",
+			"step double:2 < <main>:6",
+		}},
+		{[]int{2}, []DebugCommand{Continue, StepOut, Continue}, []string{
+			"entry <main>:1",
+			"breakpoint double:2 < <main>:5",
+			"step <main>:6",
+			"breakpoint double:2 < <main>:6",
+		}},
+	}
+
+	for _, test := range tests {
+		stops, result := t.testDebug(input, test.breakpoints, test.commands...)
+		t.Equal(test.expected, stops, test.commands)
+		t.Equal(int64(4), result.(*object.Integer).Value)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestDebuggerInspection() {
+	input := "let n = 10;\nlet f = fn(x) {\n  let y = x + n;\n  y\n};\nf(1)"
+
+	var env *object.Environment
+	var evaluated object.Object
+
+	parser := parser.NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+
+	debugger := NewDebugger(func(stop *Stop) DebugCommand {
+		env = stop.Env
+		evaluated = stop.Evaluate("x * n + f(0)")
+		return Continue
+	})
+	debugger.SetBreakpoint(4)
+
+	ctx := NewContext()
+	ctx.Debugger = debugger
+	root := object.NewEnvironment()
+	AttachContext(root, ctx)
+
+	result := Eval(program, root)
+
+	t.Equal(int64(11), result.(*object.Integer).Value)
+	t.Equal([]string{"x", "y"}, env.Names())
+	t.Equal([]string{"f", "n"}, env.Outer().Names())
+	// Evaluating calls f again, without stopping at the breakpoint inside it
+	t.Equal(int64(20), evaluated.(*object.Integer).Value)
+	t.Equal([]int{4}, debugger.Breakpoints())
+}
+
+func (t *EvaluatorTestSuite) TestDebuggerTerminate() {
+	stops, result := t.testDebug("let x = 1;\nx + 1", nil, Terminate)
+
+	t.Equal([]string{"entry <main>:1"}, stops)
+	t.Equal("terminated by the debugger", result.(*object.Error).Message)
+}
+
+// Spawned calls run through the breakpoints, run with -race to check they leave the debugger alone
+func (t *EvaluatorTestSuite) TestDebuggerSpawn() {
+	input := `let g = fn(x) {
+  let y = x * 2;
+  y
+};
+let worker = fn(x) { g(x) };
+let spawned = collect(map(range(20), fn(x) { spawn worker(x) }));
+let a = g(3);
+a + len(collect(map(spawned, fn(c) { recv(c) })))`
+
+	stops, result := t.testDebug(input, []int{2})
+
+	t.Equal([]string{"entry <main>:1", "breakpoint g:2 < <main>:7"}, stops)
+	t.Equal(int64(26), result.(*object.Integer).Value)
+}
+
+func (t *EvaluatorTestSuite) TestProfiler() {
+	input := `let fib = fn(n) {
+  if (n < 2) { n } else { fib(n - 1) + fib(n - 2) }
+};
+let twice = fn(f, x) { f(f(x)) };
+fib(10) + twice(fn(x) { x + 1 }, 0)`
+
+	parser := parser.NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+
+	env := object.NewEnvironment()
+	ctx := NewContext()
+	ctx.Profiler = NewProfiler()
+	AttachContext(env, ctx)
+
+	result := Eval(program, env)
+	ctx.Profiler.Stop()
+	t.Equal(int64(57), result.(*object.Integer).Value)
+
+	profiles := map[string]FunctionProfile{}
+	var exclusive time.Duration
+
+	for _, profile := range ctx.Profiler.Functions() {
+		profiles[fmt.Sprintf("%s %d:%d", profile.Name, profile.Line, profile.Column)] = profile
+		exclusive += profile.Exclusive
+		t.GreaterOrEqual(profile.Inclusive, profile.Exclusive, profile.Name)
+	}
+
+	t.Len(profiles, 4)
+	t.Equal(int64(1), profiles["<main> 0:0"].Calls)
+	t.Equal(int64(177), profiles["fib 1:17"].Calls)
+	t.Equal(int64(1), profiles["twice 4:22"].Calls)
+	t.Equal(int64(2), profiles["<anonymous> 5:23"].Calls)
+
+	// Every call's time is its own or that of the calls it made
+	t.Equal(profiles["<main> 0:0"].Inclusive, exclusive)
+	t.Less(profiles["fib 1:17"].Inclusive, profiles["<main> 0:0"].Inclusive)
+	t.Greater(profiles["fib 1:17"].AllocObjects, int64(0))
+
+	report := &strings.Builder{}
+	t.NoError(ctx.Profiler.WriteReport(report))
+	lines := strings.Split(report.String(), "\n")
+	t.Regexp(`^total \S+, 4 functions$`, lines[0])
+	t.Regexp(`^\s+calls\s+inclusive\s+exclusive\s+self alloc\s+self objects\s+function$`, lines[1])
+	t.Contains(report.String(), "fib (1:17)")
+	t.Regexp(`\s+2\s+.*<anonymous> \(5:23\)`, report.String())
+
+	profile := &bytes.Buffer{}
+	t.NoError(ctx.Profiler.WritePprof(profile, "main.fg"))
+
+	reader, err := gzip.NewReader(profile)
+	t.Require().NoError(err)
+	decoded, err := io.ReadAll(reader)
+	t.Require().NoError(err)
+
+	for _, name := range []string{"calls", "time", "nanoseconds", "main", "fib", "twice", "anonymous@5:23", "main.fg"} {
+		t.True(bytes.Contains(decoded, []byte(name)), name)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestProfilerAfterError() {
+	parser := parser.NewParser(lexer.NewLexer("let f = fn() { throw \"boom\" }; f()"))
+	program := parser.ParseProgram()
+
+	env := object.NewEnvironment()
+	ctx := NewContext()
+	ctx.Profiler = NewProfiler()
+	AttachContext(env, ctx)
+
+	t.True(isError(Eval(program, env)))
+	ctx.Profiler.Stop()
+
+	functions := ctx.Profiler.Functions()
+	t.Len(functions, 2)
+	for _, profile := range functions {
+		t.Equal(int64(1), profile.Calls, profile.Name)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestProfilerSpawn() {
+	input := `let h = fn(x) { x + 1 };
+let g = fn(x) { h(x) * 2 };
+let spawned = collect(map(range(20), fn(x) { spawn g(x) }));
+let direct = collect(map(range(20), fn(x) { h(x) }));
+len(collect(map(spawned, fn(c) { recv(c) }))) + len(direct)`
+
+	parser := parser.NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+
+	env := object.NewEnvironment()
+	ctx := NewContext()
+	ctx.Profiler = NewProfiler()
+	AttachContext(env, ctx)
+
+	result := Eval(program, env)
+	ctx.Profiler.Stop()
+	t.Equal(int64(40), result.(*object.Integer).Value)
+
+	calls := map[string]int64{}
+	for _, profile := range ctx.Profiler.Functions() {
+		calls[profile.Name] += profile.Calls
+	}
+	t.Equal(int64(40), calls["h"])
+	t.Equal(int64(20), calls["g"])
+
+	// Spawned calls are rooted at the spawned function, never under whatever the program was calling meanwhile
+	stacks := map[string]int64{}
+	for _, sample := range ctx.Profiler.samples {
+		names := []string{}
+		for _, profile := range sample.stack {
+			names = append(names, profile.Name)
+		}
+		stacks[strings.Join(names, " < ")] += sample.calls
+	}
+
+	t.Equal(int64(20), stacks["g"])
+	t.Equal(int64(20), stacks["h < g"])
+	t.Equal(int64(20), stacks["h < <anonymous> < <main>"])
+	for stack := range stacks {
+		t.False(strings.Contains(stack, "g <"), stack)
+	}
+}
+
+func (t *EvaluatorTestSuite) TestCoverage() {
+	input := `let sign = fn(n) {
+  if (n < 0) {
+    return -1;
+  }
+  if (n == 0) { 0 } else { 1 }
+};
+let unused = fn() { 1 };
+sign(5) + sign(0)`
+
+	program := parser.NewParser(lexer.NewLexer(input)).ParseProgram()
+
+	env := object.NewEnvironment()
+	ctx := NewContext()
+	ctx.Coverage = NewCoverage()
+	ctx.Coverage.Add("main.fg", program)
+	AttachContext(env, ctx)
+
+	t.Equal(int64(1), Eval(program, env).(*object.Integer).Value)
+
+	files := ctx.Coverage.Files()
+	t.Require().Len(files, 1)
+	t.Equal("main.fg", files[0].Path)
+	t.Equal([]StatementCoverage{
+		{Line: 1, Column: 1, Count: 1},
+		{Line: 2, Column: 3, Count: 2},
+		{Line: 3, Column: 5, Count: 0},
+		{Line: 5, Column: 3, Count: 2},
+		{Line: 5, Column: 17, Count: 1},
+		{Line: 5, Column: 28, Count: 1},
+		{Line: 7, Column: 1, Count: 1},
+		{Line: 7, Column: 21, Count: 0},
+		{Line: 8, Column: 1, Count: 1},
+	}, files[0].Statements)
+	t.Equal([]BranchCoverage{
+		{Line: 2, Column: 3, Then: 0, Else: 2},
+		{Line: 5, Column: 3, Then: 1, Else: 1},
+	}, files[0].Branches)
+
+	summary := &strings.Builder{}
+	t.NoError(ctx.Coverage.WriteSummary(summary))
+	t.Equal("main.fg: 77.8% of statements, 75.0% of branches\ntotal: 77.8% of statements, 75.0% of branches\n", summary.String())
+
+	lcov := &strings.Builder{}
+	t.NoError(ctx.Coverage.WriteLCOV(lcov))
+	t.Equal(strings.Join([]string{
+		"TN:",
+		"SF:main.fg",
+		"BRDA:2,0,0,0",
+		"BRDA:2,0,1,2",
+		"BRDA:5,1,0,1",
+		"BRDA:5,1,1,1",
+		"BRF:4",
+		"BRH:3",
+		"DA:1,1",
+		"DA:2,2",
+		"DA:3,0",
+		"DA:5,2",
+		"DA:7,1",
+		"DA:8,1",
+		"LF:6",
+		"LH:5",
+		"end_of_record",
+		"",
+	}, "\n"), lcov.String())
+
+	// Evaluating the program parsed again adds to the same counts
+	program = parser.NewParser(lexer.NewLexer(input)).ParseProgram()
+	ctx.Coverage.Add("main.fg", program)
+	Eval(program, env)
+
+	files = ctx.Coverage.Files()
+	t.Equal(int64(2), files[0].Statements[0].Count)
+	t.Equal(BranchCoverage{Line: 2, Column: 3, Then: 0, Else: 4}, files[0].Branches[0])
+}
+
+func (t *EvaluatorTestSuite) TestCoverageOfModules() {
+	dir := t.T().TempDir()
+	t.Require().NoError(os.WriteFile(filepath.Join(dir, "lib.fg"), []byte("export let half = fn(n) {\n  if (n > 100) { 0 } else { n / 2 }\n};"), 0644))
+
+	program := parser.NewParser(lexer.NewLexer(`import "./lib" as lib; lib.half(4)`)).ParseProgram()
+
+	env := object.NewEnvironment()
+	ctx := NewContext()
+	ctx.Dir = dir
+	ctx.Coverage = NewCoverage()
+	AttachContext(env, ctx)
+
+	t.Equal(int64(2), Eval(program, env).(*object.Integer).Value)
+
+	files := ctx.Coverage.Files()
+	t.Require().Len(files, 1)
+	t.Equal(filepath.Join(dir, "lib.fg"), files[0].Path)
+
+	covered, total := files[0].StatementsCovered()
+	t.Equal([2]int{3, 4}, [2]int{covered, total})
+
+	covered, total = files[0].BranchesCovered()
+	t.Equal([2]int{1, 2}, [2]int{covered, total})
+}
+
+func (t *EvaluatorTestSuite) TestAssertions() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`assert(1 < 2)`, ""},
+		{`assert(false)`, "assertion failed"},
+		{`assert(false, "must be set")`, "must be set"},
+		{`assert_eq([1, {"a": "b"}], [1, {"a": "b"}])`, ""},
+		{`assert_eq(1, 2, "sums differ")`, "sums differ\n  actual:   1\n  expected: 2"},
+		{`assert_eq("1", 1)`, "values are not equal\n  actual:   \"1\"\n  expected: 1"},
+		{`assert_eq([1, [2, 3], 4], [1, [2, 4]])`, strings.Join([]string{
+			"values are not equal",
+			"  actual:   [1, [2, 3], 4]",
+			"  expected: [1, [2, 4]]",
+			"  differences:",
+			"    [1][1]: 3 != 4",
+			"    [2]: unexpected 4",
+		}, "\n")},
+		{`assert_eq({"a": 1, "b": {"c": true}}, {"b": {"c": false}, "d": 0})`, strings.Join([]string{
+			"values are not equal",
+			`  actual:   {"a": 1, "b": {"c": true}}`,
+			`  expected: {"b": {"c": false}, "d": 0}`,
+			"  differences:",
+			`    ["a"]: unexpected 1`,
+			`    ["b"]["c"]: true != false`,
+			`    ["d"]: missing 0`,
+		}, "\n")},
+		{`struct P { x, y }; assert_eq(P(1, 2), P(1, 3))`, strings.Join([]string{
+			"values are not equal",
+			"  actual:   P { x: 1, y: 2 }",
+			"  expected: P { x: 1, y: 3 }",
+			"  differences:",
+			"    .y: 2 != 3",
+		}, "\n")},
+		{`assert_error(fn() { throw "boom" }, "oo")["message"]`, ""},
+		{`assert_error(fn() { 1 })`, "expected an error, got 1"},
+		{`assert_error(fn() { 1 + true }, "boom")`, `expected an error containing "boom", got RuntimeError: type mismatch: INTEGER + BOOLEAN`},
+		{`assert_error(fn() { assert(false) })`, "assertion failed"},
+	}
+
+	for _, test := range tests {
+		result := t.testEval(test.input)
+
+		if test.expected == "" {
+			t.False(isError(result), test.input)
+			continue
+		}
+
+		t.testErrorObject(test.expected, result)
+		t.Equal(object.ASSERTION_ERROR, result.(*object.Error).Kind)
+	}
+
+	result := t.testEval(`assert_eq(1)`)
+	t.Equal(object.ARGUMENT_ERROR, result.(*object.Error).Kind)
+
+	result = t.testEval(`assert_eq(range(20) |> collect, range(1, 21) |> collect)`)
+	t.True(strings.HasSuffix(result.(*object.Error).Message, "    [9]: 9 != 10\n    ... and 10 more"), result.String())
+}
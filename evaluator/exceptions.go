@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"fmt"
+	"fungo/ast"
+	"fungo/object"
+)
+
+// `throw <value>` raises an error. Strings become the message, hashes may provide `message` and `kind` keys, and the
+// thrown value is kept so `catch` can hand it back
+func evalThrowStatement(statement *ast.ThrowStatement, env *object.Environment) object.Object {
+	value := Eval(statement.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	err := &object.Error{Message: value.String(), Kind: object.THROWN_ERROR, Value: value}
+
+	if hash, ok := value.(*object.Hash); ok {
+		if message, ok := hashStringValue(hash, "message"); ok {
+			err.Message = message
+		}
+
+		if kind, ok := hashStringValue(hash, "kind"); ok {
+			err.Kind = kind
+		}
+	}
+
+	return err
+}
+
+func evalTryExpression(expression *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(expression.Block, env)
+
+	if err, ok := result.(*object.Error); ok && expression.CatchBlock != nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+
+		if expression.CatchParameter != nil {
+			if bindErr := bindPattern(expression.CatchParameter, errorToHash(err), catchEnv); bindErr != nil {
+				return bindErr
+			}
+		}
+
+		result = Eval(expression.CatchBlock, catchEnv)
+	}
+
+	// An error or return raised by the finally block replaces the outcome of the try and catch blocks
+	if expression.FinallyBlock != nil {
+		finally := Eval(expression.FinallyBlock, env)
+
+		if finally != nil && (finally.Type() == object.ERROR_OBJ || finally.Type() == object.RETURN_VAL_OBJ) {
+			return finally
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+
+	return result
+}
+
+// The value bound by `catch`: a hash with `message`, `kind` and `trace` keys. Thrown hashes keep their other keys,
+// any other thrown value is available under `value`
+func errorToHash(err *object.Error) *object.Hash {
+	pairs := make(map[object.HashKey]object.HashPair)
+
+	switch value := err.Value.(type) {
+	case *object.Hash:
+		for key, pair := range value.Pairs {
+			pairs[key] = pair
+		}
+	case nil:
+	default:
+		setHashPair(pairs, "value", value)
+	}
+
+	kind := err.Kind
+	if kind == "" {
+		kind = object.RUNTIME_ERROR
+	}
+
+	trace := []object.Object{}
+	for _, frame := range err.Trace {
+		trace = append(trace, &object.String{Value: frame})
+	}
+
+	setHashPair(pairs, "message", &object.String{Value: err.Message})
+	setHashPair(pairs, "kind", &object.String{Value: kind})
+	setHashPair(pairs, "trace", &object.Array{Elements: trace})
+
+	return &object.Hash{Pairs: pairs}
+}
+
+func setHashPair(pairs map[object.HashKey]object.HashPair, key string, value object.Object) {
+	hashKey := &object.String{Value: key}
+	pairs[hashKey.HashKey()] = object.HashPair{Key: hashKey, Value: value}
+}
+
+func hashStringValue(hash *object.Hash, key string) (string, bool) {
+	pair, ok := hash.Pairs[(&object.String{Value: key}).HashKey()]
+	if !ok {
+		return "", false
+	}
+
+	str, ok := pair.Value.(*object.String)
+	if !ok {
+		return "", false
+	}
+
+	return str.Value, true
+}
+
+func addTraceFrame(err *object.Error, frame string) {
+	err.Trace = append(err.Trace, frame)
+}
+
+func functionFrame(fn *object.Function) string {
+	return fmt.Sprintf("at %s (%d:%d)", functionName(fn), fn.Body.Token.Line, fn.Body.Token.Column)
+}
+
+func functionName(fn *object.Function) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+
+	return fn.Name
+}
@@ -0,0 +1,113 @@
+package evaluator
+
+import (
+	"fmt"
+	"fungo/object"
+	"io"
+	"strings"
+)
+
+// Prints each argument on a line of its own to the program's standard output
+func builtIn_print(ctx *Context, args ...object.Object) object.Object {
+	ctx.output.Lock()
+	defer ctx.output.Unlock()
+
+	for _, arg := range args {
+		fmt.Fprintln(ctx.Stdout, arg.String())
+	}
+
+	return NULL
+}
+
+// input(prompt?) prints the prompt without a newline and reads a line, the end of the input is an error
+func builtIn_input(ctx *Context, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+
+	if len(args) == 1 {
+		prompt, ok := args[0].(*object.String)
+		if !ok {
+			return newArgumentError("argument to `input` must be `STRING`, got=`%s`", args[0].Type())
+		}
+
+		ctx.output.Lock()
+		io.WriteString(ctx.Stdout, prompt.Value)
+		ctx.output.Unlock()
+	}
+
+	line, err := readLine(ctx.Stdin)
+	if err == io.EOF {
+		return newError("input: end of input")
+	}
+	if err != nil {
+		return newError("input: %s", err)
+	}
+
+	return &object.String{Value: line}
+}
+
+// readline() reads a line, null at the end of the input
+func builtIn_readline(ctx *Context, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newArgumentError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+
+	line, err := readLine(ctx.Stdin)
+	if err == io.EOF {
+		return NULL
+	}
+	if err != nil {
+		return newError("readline: %s", err)
+	}
+
+	return &object.String{Value: line}
+}
+
+// Reads the next line without its line ending. The last line may lack one, io.EOF is only returned once there is
+// nothing left to read
+func readLine(r io.Reader) (string, error) {
+	reader, ok := r.(io.ByteReader)
+	if !ok {
+		reader = byteReader{r}
+	}
+
+	line := strings.Builder{}
+
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF && line.Len() > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if b == '\n' {
+			break
+		}
+
+		line.WriteByte(b)
+	}
+
+	return strings.TrimSuffix(line.String(), "\r"), nil
+}
+
+// Reads an unbuffered reader a byte at a time, so that nothing past the line read is consumed
+type byteReader struct {
+	io.Reader
+}
+
+func (r byteReader) ReadByte() (byte, error) {
+	buf := []byte{0}
+
+	for {
+		n, err := r.Read(buf)
+		if n == 1 {
+			return buf[0], nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
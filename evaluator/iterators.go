@@ -0,0 +1,471 @@
+package evaluator
+
+import (
+	"fungo/ast"
+	"fungo/object"
+	"runtime"
+	"sync"
+)
+
+// generator runs the body of a generator function on its own goroutine, handing control back and forth with the
+// consumer so only one side evaluates at a time
+type generator struct {
+	mu      sync.Mutex
+	yields  chan object.Object
+	resume  chan struct{}
+	cancel  chan struct{}
+	started bool
+	done    bool
+}
+
+func newGenerator(fn *object.Function, env *object.Environment) *object.Iterator {
+	gen := &generator{
+		yields: make(chan object.Object),
+		resume: make(chan struct{}),
+		cancel: make(chan struct{}),
+	}
+
+	ctx := *contextOf(env)
+	ctx.generator = gen
+	AttachContext(env, &ctx)
+
+	iterator := &object.Iterator{
+		Next: func() (object.Object, bool) {
+			gen.mu.Lock()
+			defer gen.mu.Unlock()
+
+			if gen.done {
+				return nil, true
+			}
+
+			// The body only starts running on the first call, like any other lazy iterator
+			if gen.started {
+				gen.resume <- struct{}{}
+			} else {
+				gen.started = true
+				go gen.run(fn, env)
+			}
+
+			value, ok := <-gen.yields
+			if !ok || isError(value) {
+				gen.done = true
+			}
+
+			if !ok {
+				return nil, true
+			}
+
+			return value, false
+		},
+	}
+
+	// An abandoned generator would otherwise block forever waiting to be resumed
+	runtime.SetFinalizer(iterator, func(*object.Iterator) {
+		close(gen.cancel)
+	})
+
+	return iterator
+}
+
+func (g *generator) run(fn *object.Function, env *object.Environment) {
+	defer close(g.yields)
+
+	evaluated := unwrapReturnValue(Eval(fn.Body, env))
+	if err, ok := evaluated.(*object.Error); ok {
+		addTraceFrame(err, functionFrame(fn))
+		g.yields <- err
+	}
+}
+
+func evalYieldExpression(exp *ast.YieldExpression, env *object.Environment) object.Object {
+	gen := contextOf(env).generator
+	if gen == nil {
+		return newError("yield outside of a generator")
+	}
+
+	value := Eval(exp.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	gen.yields <- value
+
+	select {
+	case <-gen.resume:
+	case <-gen.cancel:
+		runtime.Goexit()
+	}
+
+	return NULL
+}
+
+func evalForExpression(exp *ast.ForExpression, env *object.Environment) object.Object {
+	iterable := Eval(exp.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	iterator, err := toIterator(iterable)
+	if err != nil {
+		return err
+	}
+
+	for {
+		value, done := iterator.Next()
+		if done {
+			break
+		}
+
+		if isError(value) {
+			return value
+		}
+
+		// Every iteration gets its own scope so closures capture the value of that iteration
+		loopEnv := object.NewEnclosedEnvironment(env)
+		if err := bindPattern(exp.Target, value, loopEnv); err != nil {
+			return err
+		}
+
+		result := Eval(exp.Body, loopEnv)
+		if result != nil && (result.Type() == object.RETURN_VAL_OBJ || result.Type() == object.ERROR_OBJ) {
+			return result
+		}
+	}
+
+	return NULL
+}
+
+// Arrays, strings and channels are iterated over lazily from the start, iterators are returned as they are
+func toIterator(obj object.Object) (*object.Iterator, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Iterator:
+		return obj, nil
+
+	case *object.Array:
+		idx := 0
+		return &object.Iterator{
+			Next: func() (object.Object, bool) {
+				if idx >= len(obj.Elements) {
+					return nil, true
+				}
+
+				idx += 1
+				return obj.Elements[idx-1], false
+			},
+		}, nil
+
+	case *object.Channel:
+		return channelIterator(obj), nil
+
+	case *object.String:
+		idx := 0
+		return &object.Iterator{
+			Next: func() (object.Object, bool) {
+				if idx >= len(obj.Value) {
+					return nil, true
+				}
+
+				idx += 1
+				return &object.String{Value: obj.Value[idx-1 : idx]}, false
+			},
+		}, nil
+
+	default:
+		return nil, newError("cannot iterate over %s", obj.Type())
+	}
+}
+
+// Drains an iterator into a slice, returning the first error it produces
+func collectIterator(iterator *object.Iterator) ([]object.Object, *object.Error) {
+	elements := []object.Object{}
+
+	for {
+		value, done := iterator.Next()
+		if done {
+			return elements, nil
+		}
+
+		if err, ok := value.(*object.Error); ok {
+			return nil, err
+		}
+
+		elements = append(elements, value)
+	}
+}
+
+func iteratorArgument(name string, arg object.Object) (*object.Iterator, *object.Error) {
+	iterator, err := toIterator(arg)
+	if err != nil {
+		return nil, newArgumentError("argument to `%s` must be iterable, got=`%s`", name, arg.Type())
+	}
+
+	return iterator, nil
+}
+
+func mapIterator(iterator *object.Iterator, fn object.Object) *object.Iterator {
+	return &object.Iterator{
+		Next: func() (object.Object, bool) {
+			value, done := iterator.Next()
+			if done || isError(value) {
+				return value, done
+			}
+
+			return applyFunction(fn, []object.Object{value}), false
+		},
+	}
+}
+
+func filterIterator(iterator *object.Iterator, fn object.Object) *object.Iterator {
+	return &object.Iterator{
+		Next: func() (object.Object, bool) {
+			for {
+				value, done := iterator.Next()
+				if done || isError(value) {
+					return value, done
+				}
+
+				keep := applyFunction(fn, []object.Object{value})
+				if isError(keep) {
+					return keep, false
+				}
+
+				if isTruthy(keep) {
+					return value, false
+				}
+			}
+		},
+	}
+}
+
+func takeIterator(iterator *object.Iterator, count int64) *object.Iterator {
+	taken := int64(0)
+
+	return &object.Iterator{
+		Next: func() (object.Object, bool) {
+			if taken >= count {
+				return nil, true
+			}
+
+			taken += 1
+			return iterator.Next()
+		},
+	}
+}
+
+/* ============================ Lazy built ins ============================ */
+func builtIn_range(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1..3", len(args))
+	}
+
+	bounds := make([]int64, len(args))
+	for idx, arg := range args {
+		integer, ok := arg.(*object.Integer)
+		if !ok {
+			return newArgumentError("arguments to `range` must be `INTEGER`, got=`%s`", arg.Type())
+		}
+
+		bounds[idx] = integer.Value
+	}
+
+	start, end, step := int64(0), bounds[0], int64(1)
+	if len(bounds) > 1 {
+		start, end = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+
+	if step == 0 {
+		return newArgumentError("`range` step must not be 0")
+	}
+
+	current := start
+	return &object.Iterator{
+		Next: func() (object.Object, bool) {
+			if (step > 0 && current >= end) || (step < 0 && current <= end) {
+				return nil, true
+			}
+
+			current += step
+			return &object.Integer{Value: current - step}, false
+		},
+	}
+}
+
+func builtIn_take(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	iterator, err := iteratorArgument("take", args[0])
+	if err != nil {
+		return err
+	}
+
+	count, ok := args[1].(*object.Integer)
+	if !ok {
+		return newArgumentError("second argument to `take` must be `INTEGER`, got=`%s`", args[1].Type())
+	}
+
+	return takeIterator(iterator, count.Value)
+}
+
+func builtIn_map(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	iterator, err := iteratorArgument("map", args[0])
+	if err != nil {
+		return err
+	}
+
+	return mapIterator(iterator, args[1])
+}
+
+func builtIn_filter(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	iterator, err := iteratorArgument("filter", args[0])
+	if err != nil {
+		return err
+	}
+
+	return filterIterator(iterator, args[1])
+}
+
+// Yields arrays holding one element of each argument, stopping as soon as the shortest one is exhausted
+func builtIn_zip(args ...object.Object) object.Object {
+	if len(args) < 2 {
+		return newArgumentError("wrong number of arguments. got=%d, want>=2", len(args))
+	}
+
+	iterators := make([]*object.Iterator, len(args))
+	for idx, arg := range args {
+		iterator, err := iteratorArgument("zip", arg)
+		if err != nil {
+			return err
+		}
+
+		iterators[idx] = iterator
+	}
+
+	return &object.Iterator{
+		Next: func() (object.Object, bool) {
+			elements := make([]object.Object, len(iterators))
+
+			for idx, iterator := range iterators {
+				value, done := iterator.Next()
+				if done || isError(value) {
+					return value, done
+				}
+
+				elements[idx] = value
+			}
+
+			return &object.Array{Elements: elements}, false
+		},
+	}
+}
+
+func builtIn_iter(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	iterator, err := iteratorArgument("iter", args[0])
+	if err != nil {
+		return err
+	}
+
+	return iterator
+}
+
+func builtIn_collect(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	iterator, err := iteratorArgument("collect", args[0])
+	if err != nil {
+		return err
+	}
+
+	elements, err := collectIterator(iterator)
+	if err != nil {
+		return err
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// Returns `{"value": <value>, "done": <bool>}`, value is null once the iterator is done
+func builtIn_next(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newArgumentError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	iterator, ok := args[0].(*object.Iterator)
+	if !ok {
+		return newArgumentError("argument to `next` must be `ITERATOR`, got=`%s`", args[0].Type())
+	}
+
+	value, done := iterator.Next()
+	if isError(value) {
+		return value
+	}
+
+	if done {
+		value = NULL
+	}
+
+	pairs := make(map[object.HashKey]object.HashPair)
+	setHashPair(pairs, "value", value)
+	setHashPair(pairs, "done", nativeBoolToBooleanObject(done))
+
+	return &object.Hash{Pairs: pairs}
+}
+
+/* ================================ Iterator ================================ */
+func method_iteratorNext(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("next", args, 0); err != nil {
+		return err
+	}
+
+	return builtIn_next(receiver)
+}
+
+func method_iteratorMap(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("map", args, 1); err != nil {
+		return err
+	}
+
+	return mapIterator(receiver.(*object.Iterator), args[0])
+}
+
+func method_iteratorFilter(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("filter", args, 1); err != nil {
+		return err
+	}
+
+	return filterIterator(receiver.(*object.Iterator), args[0])
+}
+
+func method_iteratorTake(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("take", args, 1); err != nil {
+		return err
+	}
+
+	return builtIn_take(receiver, args[0])
+}
+
+func method_iteratorCollect(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("collect", args, 0); err != nil {
+		return err
+	}
+
+	return builtIn_collect(receiver)
+}
@@ -0,0 +1,481 @@
+package evaluator
+
+import (
+	"fmt"
+	"fungo/ast"
+	"fungo/object"
+	"fungo/token"
+	"strconv"
+	"sync/atomic"
+)
+
+// Counter making the names macros bind unique across all expansions
+var gensymCounter atomic.Int64
+
+// DefineMacros moves the top level `let <name> = macro(...) { ... }` statements out of the program and binds the
+// macros they define in env, ready for ExpandMacros
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	statements := []ast.Statement{}
+
+	for _, statement := range program.Statements {
+		let, ok := statement.(*ast.LetStatement)
+		if !ok || let.Name == nil {
+			statements = append(statements, statement)
+			continue
+		}
+
+		literal, ok := let.Value.(*ast.MacroLiteral)
+		if !ok {
+			statements = append(statements, statement)
+			continue
+		}
+
+		env.Set(let.Name.Value, &object.Macro{Parameters: literal.Parameters, Body: literal.Body, Env: env})
+	}
+
+	program.Statements = statements
+}
+
+// ExpandMacros replaces every call of a macro defined in env by the syntax tree its body returns. The macro receives
+// its arguments unevaluated, as quotes. Names bound inside the quotes a macro returns are renamed so they can neither
+// capture nor shadow the names used by the code passed to it. Hygiene stops there: the names the quotes use without
+// binding them, built ins included, resolve where the macro is called, where a binding of the same name shadows them
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var expansionErr *object.Error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		if expansionErr != nil {
+			return node
+		}
+
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+
+		identifier, ok := call.Function.(*ast.Identifier)
+		if !ok {
+			return node
+		}
+
+		value, ok := env.Get(identifier.Value)
+		if !ok {
+			return node
+		}
+
+		macro, ok := value.(*object.Macro)
+		if !ok {
+			return node
+		}
+
+		expansion, err := expandMacro(identifier.Value, macro, call.Arguments)
+		if err != nil {
+			expansionErr = err
+			return node
+		}
+
+		return expansion
+	})
+
+	if expansionErr != nil {
+		return nil, expansionErr
+	}
+
+	return expanded, nil
+}
+
+func expandMacro(name string, macro *object.Macro, args []ast.Expression) (ast.Node, *object.Error) {
+	if len(args) != len(macro.Parameters) {
+		return nil, newError("wrong number of arguments to macro `%s`. got=%d, want=%d", name, len(args), len(macro.Parameters))
+	}
+
+	env := object.NewEnclosedEnvironment(macro.Env)
+	for idx, param := range macro.Parameters {
+		env.Set(param.Value, &object.Quote{Node: args[idx]})
+	}
+
+	ctx := *contextOf(env)
+	ctx.expandingMacro = true
+	AttachContext(env, &ctx)
+
+	evaluated := unwrapReturnValue(Eval(macro.Body, env))
+	if err, ok := evaluated.(*object.Error); ok {
+		addTraceFrame(err, "at macro "+name)
+		return nil, err
+	}
+
+	quote, ok := evaluated.(*object.Quote)
+	if !ok {
+		return nil, newError("macro `%s` must return a QUOTE, got %s", name, evaluated.Type())
+	}
+
+	return quote.Node, nil
+}
+
+func isUnquoteCall(node ast.Node) (*ast.CallExpression, bool) {
+	call, ok := node.(*ast.CallExpression)
+	if !ok || len(call.Arguments) != 1 {
+		return nil, false
+	}
+
+	return call, call.Function.String() == "unquote"
+}
+
+// quote(<expression>) evaluates to the syntax tree of its argument, where every unquote(<expression>) call is replaced
+// by the tree of the value its argument evaluates to
+func evalQuote(call *ast.CallExpression, env *object.Environment) object.Object {
+	if len(call.Arguments) != 1 {
+		return newArgumentError("wrong number of arguments to `quote`. got=%d, want=1", len(call.Arguments))
+	}
+
+	var node ast.Node = call.Arguments[0]
+
+	// Renamed before unquoting, so the code spliced in is left alone
+	if contextOf(env).expandingMacro {
+		node = renameBindings(node)
+	}
+
+	var unquoteErr object.Object
+	node = ast.Modify(node, func(node ast.Node) ast.Node {
+		unquote, ok := isUnquoteCall(node)
+		if !ok || unquoteErr != nil {
+			return node
+		}
+
+		value := Eval(unquote.Arguments[0], env)
+		if isError(value) {
+			unquoteErr = value
+			return node
+		}
+
+		replacement, err := objectToNode(value, unquote.Token)
+		if err != nil {
+			unquoteErr = err
+			return node
+		}
+
+		return replacement
+	})
+
+	if unquoteErr != nil {
+		return unquoteErr
+	}
+
+	return &object.Quote{Node: node}
+}
+
+func objectToNode(obj object.Object, tok token.Token) (ast.Node, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		literal := strconv.FormatInt(obj.Value, 10)
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal, Line: tok.Line, Column: tok.Column}, Value: obj.Value}, nil
+
+	case *object.Boolean:
+		tokenType := token.TokenType(token.FALSE)
+		if obj.Value {
+			tokenType = token.TRUE
+		}
+
+		return &ast.Boolean{Token: token.Token{Type: tokenType, Literal: obj.String(), Line: tok.Line, Column: tok.Column}, Value: obj.Value}, nil
+
+	case *object.String:
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: obj.Value, Line: tok.Line, Column: tok.Column}, Value: obj.Value}, nil
+
+	case *object.Quote:
+		return obj.Node, nil
+
+	default:
+		return nil, newError("cannot unquote %s", obj.Type())
+	}
+}
+
+// Gives every name bound inside the tree (lets, parameters, loop, catch and select bindings) a fresh name, along
+// with the identifiers referring to it. Scopes follow the evaluator: functions, loop bodies, catch blocks and match or
+// select arms have their own, and a let binds for its whole scope. Names the tree uses without binding them, match
+// pattern bindings, unquote calls, property names and struct fields are left as they are, see ExpandMacros
+func renameBindings(node ast.Node) ast.Node {
+	r := &renamer{}
+
+	r.push(nil, hoistedNames(node))
+	defer r.pop()
+
+	return r.rewrite(node)
+}
+
+// renamer maps the names bound in each scope of the tree being renamed to their fresh names, innermost scope last
+type renamer struct {
+	scopes []map[string]string
+}
+
+// Opens a scope binding names with fresh names and kept without renaming them, shadowing the outer scopes either way
+func (r *renamer) push(kept []string, renamed []string) {
+	scope := make(map[string]string)
+
+	for _, name := range kept {
+		scope[name] = name
+	}
+
+	for _, name := range renamed {
+		if _, ok := scope[name]; !ok {
+			scope[name] = fmt.Sprintf("%s__%d", name, gensymCounter.Add(1))
+		}
+	}
+
+	r.scopes = append(r.scopes, scope)
+}
+
+func (r *renamer) pop() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *renamer) lookup(name string) (string, bool) {
+	for idx := len(r.scopes) - 1; idx >= 0; idx-- {
+		if renamed, ok := r.scopes[idx][name]; ok {
+			return renamed, true
+		}
+	}
+
+	return "", false
+}
+
+func (r *renamer) rewrite(node ast.Node) ast.Node {
+	return ast.Rewrite(node, r.visit)
+}
+
+// Rewrites a block in a scope of its own, which also binds names
+func (r *renamer) scoped(block *ast.BlockStatement, kept []string, renamed []string) *ast.BlockStatement {
+	if block == nil {
+		return nil
+	}
+
+	r.push(kept, append(renamed, hoistedNames(block)...))
+	defer r.pop()
+
+	return r.rewrite(block).(*ast.BlockStatement)
+}
+
+func (r *renamer) visit(node ast.Node) (ast.Node, bool) {
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		if _, ok := isUnquoteCall(node); ok {
+			return node, false
+		}
+
+	case *ast.Identifier:
+		if renamed, ok := r.lookup(node.Value); ok && renamed != node.Value {
+			return &ast.Identifier{Token: node.Token, Value: renamed}, false
+		}
+
+		return node, false
+
+	case *ast.FunctionLiteral:
+		literal := *node
+
+		r.push(nil, append(bindingNames(node), hoistedNames(node.Body)...))
+		defer r.pop()
+
+		literal.Parameters = make([]ast.Expression, len(node.Parameters))
+		for idx, param := range node.Parameters {
+			literal.Parameters[idx] = r.rewrite(param).(ast.Expression)
+		}
+		literal.Body = r.rewrite(node.Body).(*ast.BlockStatement)
+
+		return &literal, false
+
+	case *ast.ForExpression:
+		loop := *node
+		loop.Iterable = r.rewrite(node.Iterable).(ast.Expression)
+
+		r.push(nil, append(bindingNames(node), hoistedNames(node.Body)...))
+		defer r.pop()
+
+		loop.Target = r.rewrite(node.Target).(ast.Expression)
+		loop.Body = r.rewrite(node.Body).(*ast.BlockStatement)
+
+		return &loop, false
+
+	case *ast.TryExpression:
+		try := *node
+		try.Block = r.rewrite(node.Block).(*ast.BlockStatement)
+
+		if node.CatchBlock != nil {
+			r.push(nil, append(bindingNames(node), hoistedNames(node.CatchBlock)...))
+			if node.CatchParameter != nil {
+				try.CatchParameter = r.rewrite(node.CatchParameter).(ast.Expression)
+			}
+			try.CatchBlock = r.rewrite(node.CatchBlock).(*ast.BlockStatement)
+			r.pop()
+		}
+
+		if node.FinallyBlock != nil {
+			try.FinallyBlock = r.rewrite(node.FinallyBlock).(*ast.BlockStatement)
+		}
+
+		return &try, false
+
+	case *ast.MatchExpression:
+		match := *node
+		match.Subject = r.rewrite(node.Subject).(ast.Expression)
+
+		match.Arms = make([]*ast.MatchArm, len(node.Arms))
+		for idx, arm := range node.Arms {
+			match.Arms[idx] = &ast.MatchArm{Pattern: arm.Pattern, Body: r.scoped(arm.Body, matchPatternNames(arm.Pattern), nil)}
+		}
+
+		return &match, false
+
+	case *ast.SelectExpression:
+		selection := *node
+
+		selection.Arms = make([]*ast.SelectArm, len(node.Arms))
+		for idx, arm := range node.Arms {
+			renamed := *arm
+			if arm.Operation != nil {
+				renamed.Operation = r.rewrite(arm.Operation).(*ast.CallExpression)
+			}
+
+			binding := []string{}
+			if arm.Binding != nil {
+				binding = append(binding, arm.Binding.Value)
+			}
+
+			r.push(nil, append(binding, hoistedNames(arm.Body)...))
+			if arm.Binding != nil {
+				renamed.Binding = r.rewrite(arm.Binding).(*ast.Identifier)
+			}
+			renamed.Body = r.rewrite(arm.Body).(*ast.BlockStatement)
+			r.pop()
+
+			selection.Arms[idx] = &renamed
+		}
+
+		return &selection, false
+
+	case *ast.MemberExpression:
+		member := *node
+		member.Ref = r.rewrite(node.Ref).(ast.Expression)
+		return &member, false
+
+	case *ast.HashPattern:
+		pattern := *node
+		pattern.Properties = make([]*ast.HashPatternProperty, len(node.Properties))
+		for idx, property := range node.Properties {
+			pattern.Properties[idx] = &ast.HashPatternProperty{
+				Key:   property.Key,
+				Value: r.rewrite(property.Value).(ast.Expression),
+			}
+		}
+		if node.Rest != nil {
+			pattern.Rest = r.rewrite(node.Rest).(*ast.RestElement)
+		}
+		return &pattern, false
+
+	case *ast.StructStatement:
+		return node, false
+	}
+
+	return node, true
+}
+
+// The names bound by the lets of a scope, leaving out those of the scopes nested in it
+func hoistedNames(node ast.Node) []string {
+	names := []string{}
+
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.CallExpression:
+			_, unquote := isUnquoteCall(node)
+			return !unquote
+
+		case *ast.LetStatement:
+			names = append(names, letStatementNames(node)...)
+
+		case *ast.FunctionLiteral, *ast.MacroLiteral:
+			return false
+
+		case *ast.ForExpression:
+			names = append(names, hoistedNames(node.Iterable)...)
+			return false
+
+		case *ast.TryExpression:
+			names = append(names, hoistedNames(node.Block)...)
+			if node.FinallyBlock != nil {
+				names = append(names, hoistedNames(node.FinallyBlock)...)
+			}
+			return false
+
+		case *ast.MatchExpression:
+			names = append(names, hoistedNames(node.Subject)...)
+			return false
+
+		case *ast.SelectExpression:
+			for _, arm := range node.Arms {
+				if arm.Operation != nil {
+					names = append(names, hoistedNames(arm.Operation)...)
+				}
+			}
+			return false
+		}
+
+		return true
+	})
+
+	return names
+}
+
+// The names a match pattern binds, variants without fields included: they are kept, so they only shadow
+func matchPatternNames(pattern ast.Expression) []string {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		return []string{pattern.Value}
+
+	case *ast.VariantPattern:
+		names := []string{}
+		for _, field := range pattern.Fields {
+			names = append(names, matchPatternNames(field)...)
+		}
+		return names
+	}
+
+	return nil
+}
+
+func bindingNames(node ast.Node) []string {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		return letStatementNames(node)
+
+	case *ast.FunctionLiteral:
+		names := []string{}
+		for _, param := range node.Parameters {
+			switch param := param.(type) {
+			case *ast.RestElement:
+				names = append(names, param.Target.Value)
+			case *ast.DefaultParameter:
+				names = append(names, patternNames(param.Target)...)
+			default:
+				names = append(names, patternNames(param)...)
+			}
+		}
+		return names
+
+	case *ast.ForExpression:
+		return patternNames(node.Target)
+
+	case *ast.TryExpression:
+		if node.CatchParameter != nil {
+			return patternNames(node.CatchParameter)
+		}
+
+	case *ast.SelectExpression:
+		names := []string{}
+		for _, arm := range node.Arms {
+			if arm.Binding != nil {
+				names = append(names, arm.Binding.Value)
+			}
+		}
+		return names
+	}
+
+	return nil
+}
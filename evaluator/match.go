@@ -0,0 +1,178 @@
+package evaluator
+
+import (
+	"fungo/ast"
+	"fungo/object"
+)
+
+// Binds the enum to its name and every variant to its own name, so both Result.Ok(1) and Ok(1) work. A variant name
+// already bound by another enum of the same scope is an error, as one would silently replace the other
+func evalEnumStatement(statement *ast.EnumStatement, env *object.Environment) object.Object {
+	definition := &object.EnumDefinition{Name: statement.Name.Value}
+
+	for _, node := range statement.Variants {
+		variant := &object.EnumVariant{Enum: definition, Name: node.Name.Value, Fields: []string{}}
+
+		for _, field := range node.Fields {
+			variant.Fields = append(variant.Fields, field.Value)
+		}
+
+		if len(variant.Fields) == 0 {
+			variant.Unit = &object.EnumValue{Variant: variant}
+		}
+
+		definition.Variants = append(definition.Variants, variant)
+
+		if other, ok := declaringEnum(env, variant.Name); ok && other.Name != definition.Name {
+			return newError("variant %s of %s is already declared by %s", variant.Name, definition.Name, other.Name)
+		}
+	}
+
+	env.Set(definition.Name, definition)
+
+	for _, variant := range definition.Variants {
+		env.Set(variant.Name, variantValue(variant))
+	}
+
+	return NOOP
+}
+
+// The enum whose variant is bound to name in the scope of env itself
+func declaringEnum(env *object.Environment, name string) (*object.EnumDefinition, bool) {
+	switch value, _ := env.GetLocal(name); value := value.(type) {
+	case *object.EnumVariant:
+		return value.Enum, true
+	case *object.EnumValue:
+		if value.Variant.Unit == value {
+			return value.Variant.Enum, true
+		}
+	}
+
+	return nil, false
+}
+
+// What a variant name evaluates to: the constructor, or the value itself for variants without fields
+func variantValue(variant *object.EnumVariant) object.Object {
+	if variant.Unit != nil {
+		return variant.Unit
+	}
+
+	return variant
+}
+
+func constructEnumValue(variant *object.EnumVariant, args []object.Object) object.Object {
+	if len(args) != len(variant.Fields) {
+		return newArgumentError(
+			"wrong number of arguments to `%s`. got=%d, want=%d", variant.Name, len(args), len(variant.Fields),
+		)
+	}
+
+	values := make([]object.Object, len(args))
+	copy(values, args)
+
+	return &object.EnumValue{Variant: variant, Values: values}
+}
+
+func evalEnumMember(definition *object.EnumDefinition, name string) object.Object {
+	if variant, ok := definition.Variant(name); ok {
+		return variantValue(variant)
+	}
+
+	return newError("enum %s has no variant %s", definition.Name, name)
+}
+
+func evalMatchExpression(expression *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(expression.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range expression.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+
+		matched, err := matchPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return err
+		}
+
+		if matched {
+			result := Eval(arm.Body, armEnv)
+			if result == nil {
+				return NULL
+			}
+
+			return result
+		}
+	}
+
+	return newError("no match arm for %s", subject.String())
+}
+
+// Reports whether `value` matches `pattern`, binding the pattern's identifiers in `env` as it goes
+func matchPattern(pattern ast.Expression, value object.Object, env *object.Environment) (bool, *object.Error) {
+	switch pattern := pattern.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return objectsEqual(Eval(pattern, env), value), nil
+
+	case *ast.Identifier:
+		if pattern.Value == "_" {
+			return true, nil
+		}
+
+		// Names of variants without fields match that variant, any other name binds the value
+		if current, ok := env.Get(pattern.Value); ok {
+			if unit, ok := current.(*object.EnumValue); ok && unit.Variant.Unit == unit {
+				return value == unit, nil
+			}
+		}
+
+		env.Set(pattern.Value, value)
+		return true, nil
+
+	case *ast.MemberExpression:
+		unit := Eval(pattern, env)
+		if err, ok := unit.(*object.Error); ok {
+			return false, err
+		}
+
+		return value == unit, nil
+
+	case *ast.VariantPattern:
+		return matchVariantPattern(pattern, value, env)
+
+	default:
+		return false, newError("invalid match pattern: %s", pattern.String())
+	}
+}
+
+func matchVariantPattern(pattern *ast.VariantPattern, value object.Object, env *object.Environment) (bool, *object.Error) {
+	resolved := Eval(pattern.Variant, env)
+	if err, ok := resolved.(*object.Error); ok {
+		return false, err
+	}
+
+	variant, ok := resolved.(*object.EnumVariant)
+	if !ok {
+		return false, newError("%s is not an enum variant with fields", pattern.Variant.String())
+	}
+
+	if len(pattern.Fields) != len(variant.Fields) {
+		return false, newError(
+			"wrong number of fields in pattern for %s. got=%d, want=%d", variant.Name, len(pattern.Fields), len(variant.Fields),
+		)
+	}
+
+	enumValue, ok := value.(*object.EnumValue)
+	if !ok || enumValue.Variant != variant {
+		return false, nil
+	}
+
+	for idx, field := range pattern.Fields {
+		matched, err := matchPattern(field, enumValue.Values[idx], env)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
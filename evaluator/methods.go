@@ -0,0 +1,389 @@
+package evaluator
+
+import (
+	"fungo/object"
+	"strings"
+	"sync"
+)
+
+// Method is a function bound to a value of a given type, called as `<receiver>.<name>(<args>)`
+type Method func(receiver object.Object, args ...object.Object) object.Object
+
+var methodsMap map[object.ObjectType]map[string]Method
+
+// Methods of the values of the structs and enums programs declare, by the name of their declaration
+var declaredMethodsMap = map[string]map[string]Method{}
+
+// Guards both method maps, hosts may register methods while spawned functions look them up
+var methodsLock sync.RWMutex
+
+// Populated in init, the array methods call back into the evaluator which itself looks methods up
+func init() {
+	methodsMap = map[object.ObjectType]map[string]Method{
+		object.STRING_OBJ: {
+			"len":      method_stringLen,
+			"upper":    method_stringUpper,
+			"lower":    method_stringLower,
+			"trim":     method_stringTrim,
+			"split":    method_stringSplit,
+			"contains": method_stringContains,
+		},
+		object.ARRAY_OBJ: {
+			"len":    method_arrayLen,
+			"first":  method_arrayFirst,
+			"last":   method_arrayLast,
+			"rest":   method_arrayRest,
+			"push":   method_arrayPush,
+			"map":    method_arrayMap,
+			"filter": method_arrayFilter,
+			"reduce": method_arrayReduce,
+			"join":   method_arrayJoin,
+		},
+		object.HASH_OBJ: {
+			"len":    method_hashLen,
+			"keys":   method_hashKeys,
+			"values": method_hashValues,
+			"has":    method_hashHas,
+			"get":    method_hashGet,
+		},
+		object.ITERATOR_OBJ: {
+			"next":    method_iteratorNext,
+			"map":     method_iteratorMap,
+			"filter":  method_iteratorFilter,
+			"take":    method_iteratorTake,
+			"collect": method_iteratorCollect,
+		},
+		object.CHANNEL_OBJ: {
+			"send":  method_channelSend,
+			"recv":  method_channelRecv,
+			"close": method_channelClose,
+		},
+		object.PROMISE_OBJ: {
+			"then": method_promiseThen,
+		},
+	}
+}
+
+// RegisterMethod lets Go hosts attach methods to an object type, replacing any existing method with the same name
+func RegisterMethod(objectType object.ObjectType, name string, method Method) {
+	methodsLock.Lock()
+	defer methodsLock.Unlock()
+
+	methods, ok := methodsMap[objectType]
+	if !ok {
+		methods = make(map[string]Method)
+		methodsMap[objectType] = methods
+	}
+
+	methods[name] = method
+}
+
+// RegisterDeclaredMethod lets Go hosts attach methods to the values of the struct or enum named declaration, replacing
+// any existing method with the same name. Methods registered for object.STRUCT_OBJ or object.ENUM_OBJ apply to every
+// struct or enum value
+func RegisterDeclaredMethod(declaration string, name string, method Method) {
+	methodsLock.Lock()
+	defer methodsLock.Unlock()
+
+	methods, ok := declaredMethodsMap[declaration]
+	if !ok {
+		methods = make(map[string]Method)
+		declaredMethodsMap[declaration] = methods
+	}
+
+	methods[name] = method
+}
+
+func lookupMethod(receiver object.Object, name string) (*object.BuiltIn, bool) {
+	methodsLock.RLock()
+	method, ok := methodsMap[receiver.Type()][name]
+
+	if declaration, declared := declaredName(receiver); declared {
+		if declaredMethod, found := declaredMethodsMap[declaration][name]; found {
+			method, ok = declaredMethod, true
+		}
+	}
+	methodsLock.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	return &object.BuiltIn{
+		FnName: name,
+		Fn: func(args ...object.Object) object.Object {
+			return method(receiver, args...)
+		},
+	}, true
+}
+
+// Hash fields shadow methods of the same name, missing fields read as null like `hash["key"]` does
+func evalObjectMember(ref object.Object, name string) object.Object {
+	if hash, ok := ref.(*object.Hash); ok {
+		if pair, ok := hash.Pairs[(&object.String{Value: name}).HashKey()]; ok {
+			return pair.Value
+		}
+	}
+
+	if method, ok := lookupMethod(ref, name); ok {
+		return method
+	}
+
+	if ref.Type() == object.HASH_OBJ {
+		return NULL
+	}
+
+	return newError("unknown method %s for %s", name, typeOf(ref))
+}
+
+func checkMethodArgs(name string, args []object.Object, want int) *object.Error {
+	if len(args) != want {
+		return newArgumentError("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), want)
+	}
+
+	return nil
+}
+
+/* ================================= String ================================= */
+func method_stringLen(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("len", args, 0); err != nil {
+		return err
+	}
+
+	return builtIn_len(receiver)
+}
+
+func method_stringUpper(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("upper", args, 0); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.ToUpper(receiver.(*object.String).Value)}
+}
+
+func method_stringLower(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("lower", args, 0); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.ToLower(receiver.(*object.String).Value)}
+}
+
+func method_stringTrim(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("trim", args, 0); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.TrimSpace(receiver.(*object.String).Value)}
+}
+
+func method_stringSplit(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("split", args, 1); err != nil {
+		return err
+	}
+
+	sep, ok := args[0].(*object.String)
+	if !ok {
+		return newArgumentError("argument to `split` must be `STRING`, got=`%s`", args[0].Type())
+	}
+
+	elements := []object.Object{}
+	for _, part := range strings.Split(receiver.(*object.String).Value, sep.Value) {
+		elements = append(elements, &object.String{Value: part})
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func method_stringContains(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("contains", args, 1); err != nil {
+		return err
+	}
+
+	sub, ok := args[0].(*object.String)
+	if !ok {
+		return newArgumentError("argument to `contains` must be `STRING`, got=`%s`", args[0].Type())
+	}
+
+	return nativeBoolToBooleanObject(strings.Contains(receiver.(*object.String).Value, sub.Value))
+}
+
+/* ================================== Array ================================= */
+func method_arrayLen(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("len", args, 0); err != nil {
+		return err
+	}
+
+	return builtIn_len(receiver)
+}
+
+func method_arrayFirst(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("first", args, 0); err != nil {
+		return err
+	}
+
+	return builtIn_first(receiver)
+}
+
+func method_arrayLast(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("last", args, 0); err != nil {
+		return err
+	}
+
+	return builtIn_last(receiver)
+}
+
+func method_arrayRest(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("rest", args, 0); err != nil {
+		return err
+	}
+
+	return builtIn_rest(receiver)
+}
+
+func method_arrayPush(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("push", args, 1); err != nil {
+		return err
+	}
+
+	return builtIn_push(receiver, args[0])
+}
+
+func method_arrayMap(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("map", args, 1); err != nil {
+		return err
+	}
+
+	elements := []object.Object{}
+	for _, element := range receiver.(*object.Array).Elements {
+		mapped := applyFunction(args[0], []object.Object{element})
+		if isError(mapped) {
+			return mapped
+		}
+
+		elements = append(elements, mapped)
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func method_arrayFilter(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("filter", args, 1); err != nil {
+		return err
+	}
+
+	elements := []object.Object{}
+	for _, element := range receiver.(*object.Array).Elements {
+		keep := applyFunction(args[0], []object.Object{element})
+		if isError(keep) {
+			return keep
+		}
+
+		if isTruthy(keep) {
+			elements = append(elements, element)
+		}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func method_arrayReduce(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("reduce", args, 2); err != nil {
+		return err
+	}
+
+	result := args[0]
+	for _, element := range receiver.(*object.Array).Elements {
+		result = applyFunction(args[1], []object.Object{result, element})
+		if isError(result) {
+			return result
+		}
+	}
+
+	return result
+}
+
+func method_arrayJoin(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("join", args, 1); err != nil {
+		return err
+	}
+
+	sep, ok := args[0].(*object.String)
+	if !ok {
+		return newArgumentError("argument to `join` must be `STRING`, got=`%s`", args[0].Type())
+	}
+
+	parts := []string{}
+	for _, element := range receiver.(*object.Array).Elements {
+		parts = append(parts, element.String())
+	}
+
+	return &object.String{Value: strings.Join(parts, sep.Value)}
+}
+
+/* ================================== Hash ================================== */
+func method_hashLen(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("len", args, 0); err != nil {
+		return err
+	}
+
+	return &object.Integer{Value: int64(len(receiver.(*object.Hash).Pairs))}
+}
+
+func method_hashKeys(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("keys", args, 0); err != nil {
+		return err
+	}
+
+	keys := []object.Object{}
+	for _, pair := range sortedPairs(receiver.(*object.Hash)) {
+		keys = append(keys, pair.Key)
+	}
+
+	return &object.Array{Elements: keys}
+}
+
+func method_hashValues(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("values", args, 0); err != nil {
+		return err
+	}
+
+	values := []object.Object{}
+	for _, pair := range sortedPairs(receiver.(*object.Hash)) {
+		values = append(values, pair.Value)
+	}
+
+	return &object.Array{Elements: values}
+}
+
+func method_hashHas(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("has", args, 1); err != nil {
+		return err
+	}
+
+	key, ok := args[0].(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: `%s`", args[0].Type())
+	}
+
+	_, ok = receiver.(*object.Hash).Pairs[key.HashKey()]
+
+	return nativeBoolToBooleanObject(ok)
+}
+
+func method_hashGet(receiver object.Object, args ...object.Object) object.Object {
+	if err := checkMethodArgs("get", args, 2); err != nil {
+		return err
+	}
+
+	key, ok := args[0].(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: `%s`", args[0].Type())
+	}
+
+	if pair, ok := receiver.(*object.Hash).Pairs[key.HashKey()]; ok {
+		return pair.Value
+	}
+
+	return args[1]
+}
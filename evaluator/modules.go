@@ -0,0 +1,268 @@
+package evaluator
+
+import (
+	"errors"
+	"fungo/ast"
+	"fungo/lexer"
+	"fungo/object"
+	"fungo/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const MODULE_EXT = ".fg"
+
+// ModuleLoader resolves, evaluates and caches modules. Every module is evaluated once in its own environment, later
+// imports of the same file share the resulting module object. A loader is safe for concurrent use, an import of a
+// module another goroutine is still evaluating waits for it to finish, unless that module is itself waiting on the
+// importing one, which is an import cycle
+type ModuleLoader struct {
+	// Directories searched, in order, for imports that are not relative (`./`, `../`) or absolute
+	SearchPath []string
+
+	mu      sync.Mutex
+	cache   map[string]*object.Module
+	pending map[string]chan struct{}  // modules being evaluated, closed once they are done
+	waits   map[string]map[string]int // modules being evaluated, by the imports they are blocked on
+}
+
+func NewModuleLoader(searchPath ...string) *ModuleLoader {
+	return &ModuleLoader{
+		SearchPath: searchPath,
+		cache:      make(map[string]*object.Module),
+		pending:    make(map[string]chan struct{}),
+		waits:      make(map[string]map[string]int),
+	}
+}
+
+// Loads the module `path` imported from a module living in `dir`
+func (l *ModuleLoader) Load(ctx *Context, path string, dir string) (*object.Module, *object.Error) {
+	resolved, err := l.resolve(path, dir)
+	if err != nil {
+		return nil, newError("cannot import %q: %s", path, err.Error())
+	}
+
+	// The main program cannot be imported, so it is never part of a cycle
+	importer := ""
+	if ctx.Module != nil {
+		importer = ctx.Module.Path
+	}
+
+	l.mu.Lock()
+	for {
+		if module, ok := l.cache[resolved]; ok {
+			l.mu.Unlock()
+			return module, nil
+		}
+
+		done, ok := l.pending[resolved]
+		if !ok {
+			break
+		}
+
+		if cycle := l.cycle(importer, resolved); cycle != nil {
+			l.mu.Unlock()
+			return nil, newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		// Evaluated by another goroutine, if it failed this one tries again and reports its own error
+		l.wait(importer, resolved, 1)
+		l.mu.Unlock()
+		<-done
+		l.mu.Lock()
+		l.wait(importer, resolved, -1)
+	}
+
+	done := make(chan struct{})
+	l.pending[resolved] = done
+	l.wait(importer, resolved, 1)
+	l.mu.Unlock()
+
+	module, evalErr := l.evaluate(ctx, path, resolved)
+
+	l.mu.Lock()
+	if evalErr == nil {
+		l.cache[resolved] = module
+	}
+	l.wait(importer, resolved, -1)
+	delete(l.pending, resolved)
+	close(done)
+	l.mu.Unlock()
+
+	return module, evalErr
+}
+
+func (l *ModuleLoader) evaluate(ctx *Context, path string, resolved string) (*object.Module, *object.Error) {
+	source, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, newError("cannot import %q: %s", path, err.Error())
+	}
+
+	parser := parser.NewParser(lexer.NewLexer(string(source)))
+	program := parser.ParseProgram()
+
+	if len(parser.Errors()) != 0 {
+		return nil, newError("cannot import %q: %s", path, parser.Errors()[0])
+	}
+
+	module := &object.Module{
+		Name:    strings.TrimSuffix(filepath.Base(resolved), filepath.Ext(resolved)),
+		Path:    resolved,
+		Env:     object.NewEnvironment(),
+		Exports: make(map[string]bool),
+	}
+
+	moduleCtx := *ctx
+	moduleCtx.Dir = filepath.Dir(resolved)
+	moduleCtx.Module = module
+	AttachContext(module.Env, &moduleCtx)
+
+	// Macros are local to the module defining them
+	macroEnv := object.NewEnvironment()
+	AttachContext(macroEnv, &moduleCtx)
+	DefineMacros(program, macroEnv)
+
+	expanded, expandErr := ExpandMacros(program, macroEnv)
+	if expandErr != nil {
+		addTraceFrame(expandErr, "at import "+resolved)
+		return nil, expandErr
+	}
+
+	if ctx.Coverage != nil {
+		ctx.Coverage.Add(resolved, expanded)
+	}
+
+	result := Eval(expanded, module.Env)
+
+	if err, ok := result.(*object.Error); ok {
+		addTraceFrame(err, "at import "+resolved)
+		return nil, err
+	}
+
+	return module, nil
+}
+
+// Records that the evaluation of importer is blocked on module, or no longer is when delta is -1. Must be called with
+// the lock held
+func (l *ModuleLoader) wait(importer string, module string, delta int) {
+	if importer == "" {
+		return
+	}
+
+	if l.waits[importer] == nil {
+		l.waits[importer] = make(map[string]int)
+	}
+
+	l.waits[importer][module] += delta
+	if l.waits[importer][module] == 0 {
+		delete(l.waits[importer], module)
+	}
+
+	if len(l.waits[importer]) == 0 {
+		delete(l.waits, importer)
+	}
+}
+
+// Returns the import cycle waiting on module from importer would close, starting and ending with module, or nil if
+// there is none. Must be called with the lock held
+func (l *ModuleLoader) cycle(importer string, module string) []string {
+	if importer == "" {
+		return nil
+	}
+
+	visited := map[string]bool{}
+
+	var path func(from string) []string
+	path = func(from string) []string {
+		if from == importer {
+			return []string{from}
+		}
+
+		if visited[from] {
+			return nil
+		}
+		visited[from] = true
+
+		for next := range l.waits[from] {
+			if rest := path(next); rest != nil {
+				return append([]string{from}, rest...)
+			}
+		}
+
+		return nil
+	}
+
+	if found := path(module); found != nil {
+		return append(found, module)
+	}
+
+	return nil
+}
+
+// Finds the file an import refers to, `.fg` may be omitted from the path
+func (l *ModuleLoader) resolve(path string, dir string) (string, error) {
+	candidates := []string{}
+
+	switch {
+	case filepath.IsAbs(path):
+		candidates = append(candidates, path)
+	case strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../"):
+		candidates = append(candidates, filepath.Join(dir, path))
+	default:
+		for _, searchDir := range l.SearchPath {
+			candidates = append(candidates, filepath.Join(searchDir, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		for _, file := range []string{candidate, candidate + MODULE_EXT} {
+			info, err := os.Stat(file)
+			if err != nil || info.IsDir() {
+				continue
+			}
+
+			return filepath.Abs(file)
+		}
+	}
+
+	return "", errors.New("module not found")
+}
+
+func evalImportStatement(statement *ast.ImportStatement, env *object.Environment) object.Object {
+	ctx := contextOf(env)
+
+	module, err := ctx.Loader.Load(ctx, statement.Path.Value, ctx.Dir)
+	if err != nil {
+		return err
+	}
+
+	env.Set(statement.Alias.Value, module)
+
+	return NOOP
+}
+
+// Exports only mean something inside a module, in the main program they behave like a plain let
+func evalExportStatement(statement *ast.ExportStatement, env *object.Environment) object.Object {
+	result := evalLetStatement(statement.Statement, env)
+	if isError(result) {
+		return result
+	}
+
+	if module := contextOf(env).Module; module != nil {
+		for _, name := range letStatementNames(statement.Statement) {
+			module.Exports[name] = true
+		}
+	}
+
+	return result
+}
+
+func evalModuleMember(module *object.Module, name string) object.Object {
+	if value, ok := module.Get(name); ok {
+		return value
+	}
+
+	return newError("module %s has no export named %s", module.Name, name)
+}
@@ -0,0 +1,446 @@
+package evaluator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"fungo/ast"
+	"fungo/object"
+	"io"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// What the profiler recorded about the calls of a function. Exclusive time and allocations leave out the functions it
+// called, inclusive time counts recursive calls once
+type FunctionProfile struct {
+	Name         string
+	Line         int
+	Column       int
+	Calls        int64
+	Inclusive    time.Duration
+	Exclusive    time.Duration
+	AllocBytes   int64
+	AllocObjects int64
+
+	id int
+}
+
+// Profiler is attached to a context to record the calls of the functions the program defines, those of its modules
+// included. The program itself is profiled as the <main> function, which runs from NewProfiler to Stop. Functions
+// spawned on their own goroutine are profiled on a call stack of their own, rooted at the spawned function, generators
+// and async functions count towards whoever resumes them. Allocations are read from the runtime's cumulative heap
+// counters, which does not stop the world but counts those of every goroutine running meanwhile
+type Profiler struct {
+	mu        sync.Mutex
+	start     time.Time
+	duration  time.Duration
+	main      *FunctionProfile
+	functions map[*ast.BlockStatement]*FunctionProfile
+	ordered   []*FunctionProfile
+	calls     *callChain // those of the program
+	samples   map[string]*sample
+	stopped   bool
+}
+
+// The calls running on one goroutine: the program's, or those of a spawned function and the functions it calls
+type callChain struct {
+	stack  []*call
+	active map[*FunctionProfile]int // calls running, recursion only adds to the inclusive time of the outermost
+}
+
+type call struct {
+	function *FunctionProfile
+	stack    string // ids of the functions on the stack, innermost first
+	start    time.Time
+	bytes    uint64
+	objects  uint64
+
+	childTime    time.Duration
+	childBytes   uint64
+	childObjects uint64
+}
+
+// The exclusive cost of the calls made with the same functions on the stack
+type sample struct {
+	stack   []*FunctionProfile // innermost first
+	calls   int64
+	time    time.Duration
+	bytes   int64
+	objects int64
+}
+
+func NewProfiler() *Profiler {
+	p := &Profiler{functions: make(map[*ast.BlockStatement]*FunctionProfile), samples: make(map[string]*sample), calls: &callChain{}}
+
+	p.main = p.add("<main>", 0, 0)
+	p.start = time.Now()
+	p.push(p.calls, p.main, p.start)
+
+	return p
+}
+
+// Ends the profile, calls made afterwards are not recorded
+func (p *Profiler) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return
+	}
+
+	// Calls interrupted by an error are still on the stack
+	for len(p.calls.stack) > 0 {
+		p.pop(p.calls)
+	}
+
+	p.duration = time.Since(p.start)
+	p.stopped = true
+}
+
+func (p *Profiler) add(name string, line, column int) *FunctionProfile {
+	profile := &FunctionProfile{Name: name, Line: line, Column: column, id: len(p.ordered) + 1}
+	p.ordered = append(p.ordered, profile)
+
+	return profile
+}
+
+// The profiler of the program env belongs to, if any, and the chain of the calls made there
+func profilerOf(env *object.Environment) (*Profiler, *callChain) {
+	ctx := contextOf(env)
+	if ctx.Profiler == nil {
+		return nil, nil
+	}
+
+	if ctx.chain == nil {
+		return ctx.Profiler, ctx.Profiler.calls
+	}
+
+	return ctx.Profiler, ctx.chain
+}
+
+func (p *Profiler) enter(chain *callChain, fn *object.Function) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return
+	}
+
+	profile, ok := p.functions[fn.Body]
+	if !ok {
+		profile = p.add(functionName(fn), fn.Body.Token.Line, fn.Body.Token.Column)
+		p.functions[fn.Body] = profile
+	}
+
+	p.push(chain, profile, time.Now())
+}
+
+func (p *Profiler) leave(chain *callChain) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The main function stays on the stack of the program until Stop
+	if !p.stopped && (len(chain.stack) > 1 || chain != p.calls && len(chain.stack) > 0) {
+		p.pop(chain)
+	}
+}
+
+func (p *Profiler) push(chain *callChain, profile *FunctionProfile, now time.Time) {
+	stack := strconv.Itoa(profile.id)
+	if len(chain.stack) > 0 {
+		stack += "," + chain.stack[len(chain.stack)-1].stack
+	}
+
+	bytes, objects := readAllocs()
+
+	if chain.active == nil {
+		chain.active = make(map[*FunctionProfile]int)
+	}
+
+	chain.active[profile] += 1
+	chain.stack = append(chain.stack, &call{function: profile, stack: stack, start: now, bytes: bytes, objects: objects})
+}
+
+func (p *Profiler) pop(chain *callChain) {
+	elapsed := time.Since(chain.stack[len(chain.stack)-1].start)
+
+	totalBytes, totalObjects := readAllocs()
+
+	c := chain.stack[len(chain.stack)-1]
+	chain.stack = chain.stack[:len(chain.stack)-1]
+
+	bytes, objects := totalBytes-c.bytes, totalObjects-c.objects
+
+	if len(chain.stack) > 0 {
+		parent := chain.stack[len(chain.stack)-1]
+		parent.childTime += elapsed
+		parent.childBytes += bytes
+		parent.childObjects += objects
+	}
+
+	profile := c.function
+	chain.active[profile] -= 1
+	profile.Calls += 1
+	profile.Exclusive += elapsed - c.childTime
+	profile.AllocBytes += int64(bytes - c.childBytes)
+	profile.AllocObjects += int64(objects - c.childObjects)
+
+	if chain.active[profile] == 0 {
+		profile.Inclusive += elapsed
+	}
+
+	s, ok := p.samples[c.stack]
+	if !ok {
+		s = &sample{stack: []*FunctionProfile{profile}}
+		for idx := len(chain.stack) - 1; idx >= 0; idx-- {
+			s.stack = append(s.stack, chain.stack[idx].function)
+		}
+		p.samples[c.stack] = s
+	}
+
+	s.calls += 1
+	s.time += elapsed - c.childTime
+	s.bytes += int64(bytes - c.childBytes)
+	s.objects += int64(objects - c.childObjects)
+}
+
+// Bytes and objects allocated on the heap since the program started
+func readAllocs() (uint64, uint64) {
+	samples := []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}, {Name: "/gc/heap/allocs:objects"}}
+	metrics.Read(samples)
+
+	return samples[0].Value.Uint64(), samples[1].Value.Uint64()
+}
+
+// Returns the profiles of the functions that were called, the most exclusive time first
+func (p *Profiler) Functions() []FunctionProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	profiles := []FunctionProfile{}
+	for _, profile := range p.ordered {
+		if profile.Calls > 0 {
+			profiles = append(profiles, *profile)
+		}
+	}
+
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return profiles[i].Exclusive > profiles[j].Exclusive
+	})
+
+	return profiles
+}
+
+/* ================================ Reports ================================ */
+
+// Writes a table of the functions that were called, the most exclusive time first
+func (p *Profiler) WriteReport(w io.Writer) error {
+	profiles := p.Functions()
+
+	p.mu.Lock()
+	duration := p.duration
+	p.mu.Unlock()
+
+	fmt.Fprintf(w, "total %s, %d functions\n", round(duration), len(profiles))
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "calls\tinclusive\texclusive\tself alloc\tself objects\t\tfunction")
+
+	for _, profile := range profiles {
+		name := profile.Name
+		if profile.Line != 0 {
+			name = fmt.Sprintf("%s (%d:%d)", profile.Name, profile.Line, profile.Column)
+		}
+
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%d\t\t%s\n",
+			profile.Calls, round(profile.Inclusive), round(profile.Exclusive), formatBytes(profile.AllocBytes), profile.AllocObjects, name)
+	}
+
+	return table.Flush()
+}
+
+func round(duration time.Duration) time.Duration {
+	return duration.Round(time.Microsecond)
+}
+
+func formatBytes(bytes int64) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(bytes)/(1<<10))
+	}
+
+	return fmt.Sprintf("%dB", bytes)
+}
+
+// Writes the profile in the gzipped protocol buffer format of pprof. Samples hold the exclusive cost of the calls with
+// a given stack, pprof adds them up into the inclusive cost. filename is the source file the functions are reported in
+func (p *Profiler) WritePprof(w io.Writer, filename string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	table := newStringTable()
+	profile := &protobuf{}
+
+	sampleTypes := [][2]string{{"calls", "count"}, {"time", "nanoseconds"}, {"alloc_space", "bytes"}, {"alloc_objects", "count"}}
+	for _, sampleType := range sampleTypes {
+		valueType := &protobuf{}
+		valueType.int64(1, table.index(sampleType[0]))
+		valueType.int64(2, table.index(sampleType[1]))
+		profile.message(1, valueType)
+	}
+
+	keys := []string{}
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := p.samples[key]
+
+		locations := []uint64{}
+		for _, function := range s.stack {
+			locations = append(locations, uint64(function.id))
+		}
+
+		sample := &protobuf{}
+		sample.packed(1, locations)
+		sample.packed(2, []uint64{uint64(s.calls), uint64(s.time), uint64(s.bytes), uint64(s.objects)})
+		profile.message(2, sample)
+	}
+
+	// A location per function, with the same id
+	for _, function := range p.ordered {
+		line := &protobuf{}
+		line.int64(1, int64(function.id))
+		line.int64(2, int64(function.Line))
+
+		location := &protobuf{}
+		location.int64(1, int64(function.id))
+		location.message(4, line)
+		profile.message(4, location)
+	}
+
+	for _, function := range p.ordered {
+		name := pprofName(function)
+
+		message := &protobuf{}
+		message.int64(1, int64(function.id))
+		message.int64(2, table.index(name))
+		message.int64(3, table.index(name))
+		message.int64(4, table.index(filename))
+		message.int64(5, int64(function.Line))
+		profile.message(5, message)
+	}
+
+	timeType := table.index("time")
+	nanoseconds := table.index("nanoseconds")
+
+	for _, s := range table.table {
+		profile.string(6, s)
+	}
+
+	profile.int64(9, p.start.UnixNano())
+	profile.int64(10, int64(p.duration))
+
+	periodType := &protobuf{}
+	periodType.int64(1, timeType)
+	periodType.int64(2, nanoseconds)
+	profile.message(11, periodType)
+	profile.int64(12, 1)
+	profile.int64(14, timeType)
+
+	compressed := gzip.NewWriter(w)
+	if _, err := compressed.Write(profile.Bytes()); err != nil {
+		return err
+	}
+
+	return compressed.Close()
+}
+
+// pprof strips what is between angle brackets from names, as it would template arguments
+func pprofName(function *FunctionProfile) string {
+	switch function.Name {
+	case "<main>":
+		return "main"
+	case "<anonymous>":
+		return fmt.Sprintf("anonymous@%d:%d", function.Line, function.Column)
+	}
+
+	return function.Name
+}
+
+type stringTable struct {
+	table   []string
+	indices map[string]int64
+}
+
+// pprof string tables start with the empty string
+func newStringTable() *stringTable {
+	return &stringTable{table: []string{""}, indices: map[string]int64{"": 0}}
+}
+
+func (t *stringTable) index(s string) int64 {
+	if idx, ok := t.indices[s]; ok {
+		return idx
+	}
+
+	t.indices[s] = int64(len(t.table))
+	t.table = append(t.table, s)
+
+	return t.indices[s]
+}
+
+// Encodes the few protocol buffer wire types a pprof profile needs
+type protobuf struct {
+	bytes.Buffer
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.WriteByte(byte(x) | 0x80)
+		x >>= 7
+	}
+	b.WriteByte(byte(x))
+}
+
+func (b *protobuf) tag(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protobuf) int64(field int, x int64) {
+	if x == 0 {
+		return
+	}
+
+	b.tag(field, 0)
+	b.varint(uint64(x))
+}
+
+func (b *protobuf) string(field int, s string) {
+	b.tag(field, 2)
+	b.varint(uint64(len(s)))
+	b.WriteString(s)
+}
+
+func (b *protobuf) message(field int, message *protobuf) {
+	b.tag(field, 2)
+	b.varint(uint64(message.Len()))
+	b.Write(message.Bytes())
+}
+
+func (b *protobuf) packed(field int, xs []uint64) {
+	values := &protobuf{}
+	for _, x := range xs {
+		values.varint(x)
+	}
+
+	b.message(field, values)
+}
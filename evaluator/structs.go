@@ -0,0 +1,126 @@
+package evaluator
+
+import (
+	"fungo/ast"
+	"fungo/object"
+)
+
+func evalStructStatement(statement *ast.StructStatement, env *object.Environment) object.Object {
+	fields := []string{}
+	for _, field := range statement.Fields {
+		fields = append(fields, field.Value)
+	}
+
+	env.Set(statement.Name.Value, &object.StructDefinition{Name: statement.Name.Value, Fields: fields})
+
+	return NOOP
+}
+
+// Struct definitions are called like functions, with one argument per field in declaration order
+func constructStruct(definition *object.StructDefinition, args []object.Object) object.Object {
+	if len(args) != len(definition.Fields) {
+		return newArgumentError(
+			"wrong number of arguments to `%s`. got=%d, want=%d", definition.Name, len(args), len(definition.Fields),
+		)
+	}
+
+	values := make([]object.Object, len(args))
+	copy(values, args)
+
+	return &object.Struct{Definition: definition, Values: values}
+}
+
+func evalStructMember(instance *object.Struct, name string) object.Object {
+	if value, ok := instance.Field(name); ok {
+		return value
+	}
+
+	if method, ok := lookupMethod(instance, name); ok {
+		return method
+	}
+
+	return newError("%s has no field %s", instance.Definition.Name, name)
+}
+
+// Structs are equal when they come from the same definition and all their fields are equal
+func structsEqual(left *object.Struct, right *object.Struct) bool {
+	if left.Definition != right.Definition {
+		return false
+	}
+
+	for idx := range left.Values {
+		if !objectsEqual(left.Values[idx], right.Values[idx]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Deep equality of two values: integers, strings and booleans by value, arrays, hashes, structs and enum values by
+// content and everything else by identity
+func objectsEqual(left object.Object, right object.Object) bool {
+	switch left := left.(type) {
+	case *object.Integer:
+		right, ok := right.(*object.Integer)
+		return ok && left.Value == right.Value
+
+	case *object.String:
+		right, ok := right.(*object.String)
+		return ok && left.Value == right.Value
+
+	case *object.Boolean:
+		right, ok := right.(*object.Boolean)
+		return ok && left.Value == right.Value
+
+	case *object.Array:
+		right, ok := right.(*object.Array)
+		if !ok || len(left.Elements) != len(right.Elements) {
+			return false
+		}
+
+		for idx := range left.Elements {
+			if !objectsEqual(left.Elements[idx], right.Elements[idx]) {
+				return false
+			}
+		}
+
+		return true
+
+	case *object.Hash:
+		right, ok := right.(*object.Hash)
+		if !ok || len(left.Pairs) != len(right.Pairs) {
+			return false
+		}
+
+		for key, pair := range left.Pairs {
+			other, ok := right.Pairs[key]
+			if !ok || !objectsEqual(pair.Value, other.Value) {
+				return false
+			}
+		}
+
+		return true
+
+	case *object.Struct:
+		right, ok := right.(*object.Struct)
+		return ok && structsEqual(left, right)
+
+	case *object.EnumValue:
+		right, ok := right.(*object.EnumValue)
+		if !ok || left.Variant != right.Variant {
+			return false
+		}
+
+		for idx := range left.Values {
+			if !objectsEqual(left.Values[idx], right.Values[idx]) {
+				return false
+			}
+		}
+
+		return true
+
+	default:
+		return left == right
+	}
+}
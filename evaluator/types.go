@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"fungo/ast"
+	"fungo/object"
+)
+
+// Object types admitted by the type names annotations can use besides the names of structs and enums. Parameterized
+// array and hash types also check their elements, function types only check that the value can be called
+var annotationTypes = map[string][]object.ObjectType{
+	"int":      {object.INTEGER_OBJ},
+	"string":   {object.STRING_OBJ},
+	"bool":     {object.BOOLEAN_OBJ},
+	"null":     {object.NULL_OBJ},
+	"array":    {object.ARRAY_OBJ},
+	"hash":     {object.HASH_OBJ},
+	"fn":       {object.FUNCTION_OBJ, object.BUILTIN_OBJ, object.STRUCT_DEF_OBJ, object.VARIANT_OBJ},
+	"iterator": {object.ITERATOR_OBJ},
+	"channel":  {object.CHANNEL_OBJ},
+	"promise":  {object.PROMISE_OBJ},
+	"module":   {object.MODULE_OBJ},
+}
+
+func newTypeError(format string, args ...interface{}) *object.Error {
+	err := newError(format, args...)
+	err.Kind = object.TYPE_ERROR
+
+	return err
+}
+
+// Checks value against an annotation, struct and enum names are looked up in env
+func checkType(annotation *ast.TypeAnnotation, value object.Object, env *object.Environment, format string, args ...interface{}) *object.Error {
+	if value == nil {
+		value = NULL
+	}
+
+	ok, err := typeMatches(annotation, value, env)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return newTypeError(format+" must be %s, got %s", append(args, annotation.String(), typeName(value))...)
+	}
+
+	return nil
+}
+
+func typeMatches(annotation *ast.TypeAnnotation, value object.Object, env *object.Environment) (bool, *object.Error) {
+	if annotation.Name == "any" {
+		return true, nil
+	}
+
+	types, ok := annotationTypes[annotation.Name]
+	if !ok {
+		return namedTypeMatches(annotation, value, env)
+	}
+
+	matches := false
+	for _, objectType := range types {
+		matches = matches || value.Type() == objectType
+	}
+
+	if !matches || len(annotation.Parameters) == 0 {
+		return matches, nil
+	}
+
+	switch value := value.(type) {
+	case *object.Array:
+		for _, element := range value.Elements {
+			if ok, err := typeMatches(annotation.Parameters[0], element, env); !ok || err != nil {
+				return ok, err
+			}
+		}
+
+	case *object.Hash:
+		if len(annotation.Parameters) != 2 {
+			return false, newTypeError("hash types take a key and a value type, got %s", annotation.String())
+		}
+
+		for _, pair := range value.Pairs {
+			if ok, err := typeMatches(annotation.Parameters[0], pair.Key, env); !ok || err != nil {
+				return ok, err
+			}
+
+			if ok, err := typeMatches(annotation.Parameters[1], pair.Value, env); !ok || err != nil {
+				return ok, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// Struct values match the name of their definition and enum values the name of their enum
+func namedTypeMatches(annotation *ast.TypeAnnotation, value object.Object, env *object.Environment) (bool, *object.Error) {
+	switch definition, _ := env.Get(annotation.Name); definition.(type) {
+	case *object.StructDefinition, *object.EnumDefinition:
+	default:
+		return false, newTypeError("unknown type %s", annotation.Name)
+	}
+
+	if name, ok := declaredName(value); ok {
+		return name == annotation.Name, nil
+	}
+
+	return false, nil
+}
+
+// The name annotations use for the type of value
+func typeName(value object.Object) string {
+	if name, ok := declaredName(value); ok {
+		return name
+	}
+
+	for name, types := range annotationTypes {
+		if len(types) == 1 && types[0] == value.Type() {
+			return name
+		}
+	}
+
+	return string(value.Type())
+}
+
+// Type checks are enforced when the context the function was defined in asks for them
+func checksTypes(fn *object.Function) bool {
+	return (fn.ParameterTypes != nil || fn.ReturnType != nil) && contextOf(fn.Env).CheckTypes
+}
@@ -2,7 +2,9 @@ package evaluator
 
 import (
 	"fmt"
+	"fungo/ast"
 	"fungo/object"
+	"sort"
 )
 
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
@@ -26,14 +28,115 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+func extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, *object.Error) {
+	if err := checkArity(fn, args); err != nil {
+		return nil, err
+	}
+
 	env := object.NewEnclosedEnvironment(fn.Env)
+	checked := checksTypes(fn)
 
 	for idx, param := range fn.Parameters {
-		env.Set(param.Value, args[idx])
+		switch param := param.(type) {
+		case *ast.RestElement:
+			rest := []object.Object{}
+			if idx < len(args) {
+				rest = make([]object.Object, len(args)-idx)
+				copy(rest, args[idx:])
+			}
+
+			if err := checkParameter(fn, idx, &object.Array{Elements: rest}, checked); err != nil {
+				return nil, err
+			}
+
+			env.Set(param.Target.Value, &object.Array{Elements: rest})
+
+		case *ast.DefaultParameter:
+			var value object.Object
+
+			// Defaults are evaluated on every call, inside the function scope so they can refer to earlier parameters
+			if idx < len(args) {
+				value = args[idx]
+			} else {
+				value = Eval(param.Value, env)
+				if err, ok := value.(*object.Error); ok {
+					return nil, err
+				}
+			}
+
+			if err := checkParameter(fn, idx, value, checked); err != nil {
+				return nil, err
+			}
+
+			if err := bindPattern(param.Target, value, env); err != nil {
+				return nil, err
+			}
+
+		default:
+			if err := checkParameter(fn, idx, args[idx], checked); err != nil {
+				return nil, err
+			}
+
+			if err := bindPattern(param, args[idx], env); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return env, nil
+}
+
+func checkParameter(fn *object.Function, idx int, value object.Object, checked bool) *object.Error {
+	if !checked || idx >= len(fn.ParameterTypes) || fn.ParameterTypes[idx] == nil {
+		return nil
+	}
+
+	name := fn.Parameters[idx].String()
+	if param, ok := fn.Parameters[idx].(*ast.DefaultParameter); ok {
+		name = param.Target.String()
+	}
+
+	err := checkType(fn.ParameterTypes[idx], value, fn.Env, "argument %s of %s", name, functionName(fn))
+	if err != nil {
+		addTraceFrame(err, functionFrame(fn))
 	}
 
-	return env
+	return err
+}
+
+// Returns the minimum and maximum number of arguments a function accepts, max is -1 when it takes rest parameters
+func functionArity(fn *object.Function) (int, int) {
+	min, max := 0, 0
+
+	for _, param := range fn.Parameters {
+		switch param.(type) {
+		case *ast.RestElement:
+			return min, -1
+		case *ast.DefaultParameter:
+			max += 1
+		default:
+			min += 1
+			max += 1
+		}
+	}
+
+	return min, max
+}
+
+func checkArity(fn *object.Function, args []object.Object) *object.Error {
+	min, max := functionArity(fn)
+	got := len(args)
+
+	switch {
+	case max == -1 && got < min:
+		return newError("wrong number of arguments. got=%d, want>=%d", got, min)
+	case max != -1 && min == max && got != min:
+		return newError("wrong number of arguments. got=%d, want=%d", got, min)
+	case max != -1 && (got < min || got > max):
+		return newError("wrong number of arguments. got=%d, want=%d..%d", got, min, max)
+	}
+
+	return nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -47,19 +150,84 @@ func unwrapReturnValue(obj object.Object) object.Object {
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		evaluated := Eval(fn.Body, extendFunctionEnv(fn, args))
-		return unwrapReturnValue(evaluated)
+		env, err := extendFunctionEnv(fn, args)
+		if err != nil {
+			return err
+		}
+
+		if fn.Generator {
+			return newGenerator(fn, env)
+		}
+
+		if fn.Async {
+			return callAsyncFunction(fn, env)
+		}
+
+		// The coroutine belongs to the async call that is running it, functions created in its body may be called
+		// once it finished or from another coroutine, where awaiting runs the event loop like outside async functions
+		if ctx := contextOf(env); ctx.coroutine != nil {
+			plain := *ctx
+			plain.coroutine = nil
+			AttachContext(env, &plain)
+		}
+
+		if debugger := debuggerOf(fn.Env); debugger != nil {
+			debugger.enter(fn, env)
+			defer debugger.leave()
+		}
+
+		if profiler, chain := profilerOf(fn.Env); profiler != nil {
+			profiler.enter(chain, fn)
+			defer profiler.leave(chain)
+		}
+
+		evaluated := unwrapReturnValue(Eval(fn.Body, env))
+		if err, ok := evaluated.(*object.Error); ok {
+			addTraceFrame(err, functionFrame(fn))
+			return err
+		}
+
+		// Generators and async functions return before their body runs, so only plain results are checked
+		if fn.ReturnType != nil && checksTypes(fn) {
+			if err := checkType(fn.ReturnType, evaluated, fn.Env, "result of %s", functionName(fn)); err != nil {
+				addTraceFrame(err, functionFrame(fn))
+				return err
+			}
+		}
+
+		return evaluated
+
+	case *object.StructDefinition:
+		return constructStruct(fn, args)
+
+	case *object.EnumVariant:
+		return constructEnumValue(fn, args)
 
 	case *object.BuiltIn:
-		return fn.Fn(args...)
+		result := fn.Fn(args...)
+		if err, ok := result.(*object.Error); ok {
+			addTraceFrame(err, "at "+fn.FnName+" (builtin)")
+		}
+
+		return result
 
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newError("not a function: %s", typeOf(fn))
 	}
 }
 
+// Calls a function value from Go, hosts use it to run the functions a program defines or hands them
+func CallFunction(fn object.Object, args ...object.Object) object.Object {
+	return applyFunction(fn, args)
+}
+
 func newError(format string, args ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, args...)}
+	return &object.Error{Message: fmt.Sprintf(format, args...), Kind: object.RUNTIME_ERROR}
+}
+
+// Errors raised by builtins that were called with the wrong number or type of arguments
+func newArgumentError(format string, args ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, args...), Kind: object.ARGUMENT_ERROR}
 }
 
 func isError(obj object.Object) bool {
@@ -69,3 +237,64 @@ func isError(obj object.Object) bool {
 
 	return false
 }
+
+// The name of the declaration a struct or enum value comes from, values of other types have none
+func declaredName(obj object.Object) (string, bool) {
+	switch obj := obj.(type) {
+	case *object.Struct:
+		return obj.Definition.Name, true
+	case *object.EnumValue:
+		return obj.Variant.Enum.Name, true
+	}
+
+	return "", false
+}
+
+// The type of a value as errors name it: the declaration of structs and enum values, the object type of other values
+func typeOf(obj object.Object) string {
+	if name, ok := declaredName(obj); ok {
+		return name
+	}
+
+	return string(obj.Type())
+}
+
+// Structs and enum values compare by content with == and !=
+func isStructural(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Struct, *object.EnumValue:
+		return true
+	default:
+		return false
+	}
+}
+
+// The pairs of a hash in a stable order: keys of different types by type name, integers by value, strings in lexical
+// order and false before true
+func sortedPairs(hash *object.Hash) []object.HashPair {
+	pairs := []object.HashPair{}
+	for _, pair := range hash.Pairs {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return keyLess(pairs[i].Key, pairs[j].Key)
+	})
+
+	return pairs
+}
+
+func keyLess(left object.Object, right object.Object) bool {
+	if left.Type() != right.Type() {
+		return left.Type() < right.Type()
+	}
+
+	switch left := left.(type) {
+	case *object.Integer:
+		return left.Value < right.(*object.Integer).Value
+	case *object.Boolean:
+		return !left.Value && right.(*object.Boolean).Value
+	}
+
+	return left.String() < right.String()
+}
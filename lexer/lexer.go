@@ -1,6 +1,9 @@
 package lexer
 
-import "fungo/token"
+import (
+	"fungo/token"
+	"strings"
+)
 
 // Needs to support peeking the next character
 type Lexer struct {
@@ -8,10 +11,14 @@ type Lexer struct {
 	position     int  // current char position in input
 	readPosition int  // current reading position in input (after current char)
 	char         byte // current char
+	line         int  // line of the current char
+	column       int  // column of the current char
+
+	comments []token.Token // `//` comments skipped so far, in source order
 }
 
 func NewLexer(input string) *Lexer {
-	lexer := &Lexer{input: input, position: 0, readPosition: 0, char: 0}
+	lexer := &Lexer{input: input, position: 0, readPosition: 0, char: 0, line: 1, column: 0}
 	lexer.readChar()
 
 	return lexer
@@ -20,6 +27,12 @@ func NewLexer(input string) *Lexer {
 // Read the next character and advance position in the `input` string
 // Only support ASCII characters
 func (l *Lexer) readChar() {
+	if l.char == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
+
 	// Assign character if exists
 	if l.readPosition >= len(l.input) {
 		// In ASCII, the `0th` byte represents null
@@ -53,6 +66,30 @@ func (l *Lexer) skipWhiteSpace() {
 	}
 }
 
+// Skips whitespace and `//` comments, keeping the comments so tools such as the printer can put them back
+func (l *Lexer) skipWhiteSpaceAndComments() {
+	l.skipWhiteSpace()
+
+	for l.char == '/' && l.peekChar() == '/' {
+		comment := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+		position := l.position
+
+		for l.char != '\n' && l.char != 0 {
+			l.readChar()
+		}
+
+		comment.Literal = strings.TrimRight(l.input[position:l.position], " \t\r")
+		l.comments = append(l.comments, comment)
+
+		l.skipWhiteSpace()
+	}
+}
+
+// The comments read so far, the parser never sees them
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
 func (l *Lexer) readNumber() string {
 	position := l.position
 
@@ -93,10 +130,24 @@ func (l *Lexer) readString() string {
 	return l.input[position:l.position]
 }
 
+// Reads either `..` or `...`, both of which are accepted as the rest/spread marker
+func (l *Lexer) readEllipsis() string {
+	position := l.position
+
+	l.readChar()
+	if l.peekChar() == '.' {
+		l.readChar()
+	}
+
+	return l.input[position:l.readPosition]
+}
+
 func (l *Lexer) NextToken() token.Token {
 	var newToken token.Token
 
-	l.skipWhiteSpace()
+	l.skipWhiteSpaceAndComments()
+
+	line, column := l.line, l.column
 
 	switch l.char {
 	case '=':
@@ -107,6 +158,13 @@ func (l *Lexer) NextToken() token.Token {
 				Type:    token.EQ,
 				Literal: string(prevCh) + string(l.char),
 			}
+		} else if l.peekChar() == '>' {
+			prevCh := l.char
+			l.readChar()
+			newToken = token.Token{
+				Type:    token.ARROW,
+				Literal: string(prevCh) + string(l.char),
+			}
 		} else {
 			newToken = createNewToken(token.ASSIGN, l.char)
 		}
@@ -121,6 +179,17 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			newToken = createNewToken(token.BANG, l.char)
 		}
+	case '|':
+		if l.peekChar() == '>' {
+			prevCh := l.char
+			l.readChar()
+			newToken = token.Token{
+				Type:    token.PIPE,
+				Literal: string(prevCh) + string(l.char),
+			}
+		} else {
+			newToken = createNewToken(token.ILLEGAL, l.char)
+		}
 	case '+':
 		newToken = createNewToken(token.PLUS, l.char)
 	case '-':
@@ -141,6 +210,15 @@ func (l *Lexer) NextToken() token.Token {
 		newToken = createNewToken(token.SEMICOLON, l.char)
 	case ':':
 		newToken = createNewToken(token.COLON, l.char)
+	case '.':
+		if l.peekChar() == '.' {
+			newToken = token.Token{
+				Type:    token.ELLIPSIS,
+				Literal: l.readEllipsis(),
+			}
+		} else {
+			newToken = createNewToken(token.DOT, l.char)
+		}
 
 	case '(':
 		newToken = createNewToken(token.LPAREN, l.char)
@@ -170,16 +248,20 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.char) {
 			newToken.Literal = l.readIdentifier()
 			newToken.Type = token.LookupIdent(newToken.Literal)
+			newToken.Line, newToken.Column = line, column
 			return newToken
 		} else if isDigit(l.char) {
 			newToken.Type = token.INT
 			newToken.Literal = l.readNumber()
+			newToken.Line, newToken.Column = line, column
 			return newToken
 		} else {
 			newToken = createNewToken(token.ILLEGAL, l.char)
 		}
 	}
 
+	newToken.Line, newToken.Column = line, column
+
 	// After reading identifier, shift lexer to next place
 	l.readChar()
 
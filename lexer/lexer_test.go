@@ -155,3 +155,232 @@ func (t *LexerTestSuite) TestNextToken() {
 		t.Equal(test.expectedLiteral, token.Literal)
 	}
 }
+
+func (t *LexerTestSuite) TestNextTokenEllipsis() {
+	input := `let [a, ..rest] = [...xs];`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, ".."},
+		{token.IDENT, "rest"},
+		{token.RBRACKET, "]"},
+		{token.ASSIGN, "="},
+		{token.LBRACKET, "["},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "xs"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for _, test := range tests {
+		token := l.NextToken()
+
+		t.Equal(test.expectedType, token.Type)
+		t.Equal(test.expectedLiteral, token.Literal)
+	}
+}
+
+func (t *LexerTestSuite) TestNextTokenPipeAndArrow() {
+	input := `xs |> map(x => x * 2) | 1`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "xs"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "map"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "x"},
+		{token.ASTERISK, "*"},
+		{token.INT, "2"},
+		{token.RPAREN, ")"},
+		{token.ILLEGAL, "|"},
+		{token.INT, "1"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for _, test := range tests {
+		token := l.NextToken()
+
+		t.Equal(test.expectedType, token.Type)
+		t.Equal(test.expectedLiteral, token.Literal)
+	}
+}
+
+func (t *LexerTestSuite) TestNextTokenPosition() {
+	input := "let x = 5;\n  x + \"a b\""
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 2, 3},
+		{token.PLUS, 2, 5},
+		{token.STRING, 2, 7},
+		{token.EOF, 2, 12},
+	}
+
+	l := NewLexer(input)
+
+	for _, test := range tests {
+		token := l.NextToken()
+
+		t.Equal(test.expectedType, token.Type)
+		t.Equal(test.expectedLine, token.Line)
+		t.Equal(test.expectedColumn, token.Column)
+	}
+}
+
+func (t *LexerTestSuite) TestNextTokenModules() {
+	input := `import "lib/list.fg" as list; export let x = list.map;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IMPORT, "import"},
+		{token.STRING, "lib/list.fg"},
+		{token.AS, "as"},
+		{token.IDENT, "list"},
+		{token.SEMICOLON, ";"},
+		{token.EXPORT, "export"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "list"},
+		{token.DOT, "."},
+		{token.IDENT, "map"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for _, test := range tests {
+		token := l.NextToken()
+
+		t.Equal(test.expectedType, token.Type)
+		t.Equal(test.expectedLiteral, token.Literal)
+	}
+}
+
+func (t *LexerTestSuite) TestNextTokenGenerators() {
+	input := `for (x in xs) { yield x }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.IN, "in"},
+		{token.IDENT, "xs"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.YIELD, "yield"},
+		{token.IDENT, "x"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for _, test := range tests {
+		token := l.NextToken()
+
+		t.Equal(test.expectedType, token.Type)
+		t.Equal(test.expectedLiteral, token.Literal)
+	}
+}
+
+func (t *LexerTestSuite) TestNextTokenConcurrency() {
+	input := `spawn f(c); select { recv(c) as v => v }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.SPAWN, "spawn"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "c"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.SELECT, "select"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "recv"},
+		{token.LPAREN, "("},
+		{token.IDENT, "c"},
+		{token.RPAREN, ")"},
+		{token.AS, "as"},
+		{token.IDENT, "v"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "v"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for _, test := range tests {
+		token := l.NextToken()
+
+		t.Equal(test.expectedType, token.Type)
+		t.Equal(test.expectedLiteral, token.Literal)
+	}
+}
+
+func (t *LexerTestSuite) TestNextTokenComments() {
+	input := "// header\nlet x = 10 / 2; // half\n//\nx"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for _, test := range tests {
+		token := l.NextToken()
+
+		t.Equal(test.expectedType, token.Type)
+		t.Equal(test.expectedLiteral, token.Literal)
+	}
+
+	t.Equal([]token.Token{
+		{Type: token.COMMENT, Literal: "// header", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// half", Line: 2, Column: 17},
+		{Type: token.COMMENT, Literal: "//", Line: 3, Column: 1},
+	}, l.Comments())
+}
@@ -0,0 +1,623 @@
+package lint
+
+import (
+	"fmt"
+	"fungo/ast"
+	"fungo/evaluator"
+	"fungo/lexer"
+	"fungo/parser"
+	"fungo/token"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	ERROR   = "error"
+	WARNING = "warning"
+)
+
+// Rules a diagnostic can come from
+const (
+	UNDEFINED          = "undefined"
+	UNUSED             = "unused"
+	UNREACHABLE        = "unreachable"
+	SHADOWED_BUILTIN   = "shadowed-builtin"
+	CONSTANT_CONDITION = "constant-condition"
+	SYNTAX             = "syntax" // parse errors, which carry no position
+	NON_EXHAUSTIVE     = "non-exhaustive"
+)
+
+type Diagnostic struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s (%s)", d.Severity, d.Message, d.Rule)
+	}
+
+	return fmt.Sprintf("%d:%d: %s: %s (%s)", d.Line, d.Column, d.Severity, d.Message, d.Rule)
+}
+
+// Checks a program without running it and returns its diagnostics sorted by position. Scopes follow the evaluator:
+// functions, loop bodies, catch blocks and match or select arms have their own, the blocks of an if or a try share
+// the enclosing one. Names used in a function body may be bound after the function, as long as it is before it runs
+func Lint(program *ast.Program) []Diagnostic {
+	l := run(program)
+	sortDiagnostics(l.diagnostics)
+
+	return l.diagnostics
+}
+
+// The warnings of the parser as diagnostics, they are all about matches missing variants of an enum
+func ParserWarnings(warnings []parser.Warning) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	for _, warning := range warnings {
+		diagnostics = append(diagnostics, Diagnostic{
+			Line:     warning.Token.Line,
+			Column:   warning.Token.Column,
+			Severity: WARNING,
+			Rule:     NON_EXHAUSTIVE,
+			Message:  warning.Message,
+		})
+	}
+
+	return diagnostics
+}
+
+func sortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+}
+
+func run(program *ast.Program) *linter {
+	l := &linter{builtins: make(map[string]bool)}
+
+	for _, name := range evaluator.BuiltInNames() {
+		l.builtins[name] = true
+	}
+
+	l.diagnostics = []Diagnostic{}
+	l.resolution = &Resolution{
+		Declarations: make(map[*ast.Identifier]*ast.Identifier),
+		Kinds:        make(map[*ast.Identifier]string),
+	}
+	l.push(program, false)
+	l.statements(program.Statements)
+	l.finish()
+
+	return l
+}
+
+// Parses and lints source, along with the warnings of the parser. When it does not parse, its parse errors are the
+// only diagnostics
+func Source(source []byte) []Diagnostic {
+	p := parser.NewParser(lexer.NewLexer(string(source)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		diagnostics := []Diagnostic{}
+		for _, msg := range p.Errors() {
+			diagnostics = append(diagnostics, Diagnostic{Severity: ERROR, Rule: SYNTAX, Message: msg})
+		}
+
+		return diagnostics
+	}
+
+	diagnostics := append(Lint(program), ParserWarnings(p.Warnings())...)
+	sortDiagnostics(diagnostics)
+
+	return diagnostics
+}
+
+type binding struct {
+	identifier *ast.Identifier
+	kind       string
+	used       bool
+	unit       bool // a variant without fields, which match patterns compare against instead of binding
+}
+
+type scope struct {
+	parent   *scope
+	names    map[string]*binding
+	function bool
+	resolved *Scope
+}
+
+// An identifier not bound when it was reached, which a binding made later in a scope enclosing one of its functions
+// may still provide
+type reference struct {
+	identifier *ast.Identifier
+	scope      *scope
+}
+
+type linter struct {
+	scope       *scope
+	builtins    map[string]bool
+	bindings    []*binding
+	deferred    []reference
+	diagnostics []Diagnostic
+	resolution  *Resolution
+}
+
+func (l *linter) report(at token.Token, severity string, rule string, format string, args ...any) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Line:     at.Line,
+		Column:   at.Column,
+		Severity: severity,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Opens the scope of node, the program, a function, a loop, a catch block or a match or select arm
+func (l *linter) push(node ast.Node, function bool) {
+	resolved := &Scope{Node: node}
+	if l.scope != nil {
+		resolved.Parent = l.scope.resolved
+	}
+	l.resolution.Scopes = append(l.resolution.Scopes, resolved)
+
+	l.scope = &scope{parent: l.scope, names: make(map[string]*binding), function: function, resolved: resolved}
+}
+
+func (l *linter) pop() {
+	l.scope = l.scope.parent
+}
+
+/* ================================ Walking ================================ */
+
+// Implements ast.Visitor. Nodes that bind names or open scopes are walked by hand, the rest is left to ast.Walk
+func (l *linter) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+	case nil:
+		return nil
+
+	case *ast.Identifier:
+		l.reference(node)
+
+	case *ast.LetStatement:
+		l.let(node, false)
+
+	case *ast.ExportStatement:
+		l.let(node.Statement, true)
+
+	case *ast.ImportStatement:
+		l.declare(node.Alias, "import")
+
+	case *ast.StructStatement:
+		l.declare(node.Name, "struct")
+
+	case *ast.EnumStatement:
+		l.declare(node.Name, "enum")
+
+		for _, variant := range node.Variants {
+			if binding := l.declare(variant.Name, "variant"); binding != nil {
+				binding.unit = len(variant.Fields) == 0
+			}
+		}
+
+	case *ast.BlockStatement:
+		l.statements(node.Statements)
+
+	case *ast.FunctionLiteral:
+		l.push(node, true)
+		l.parameters(node.Parameters)
+		l.walk(node.Body)
+		l.pop()
+
+	case *ast.MacroLiteral:
+		l.push(node, true)
+		for _, param := range node.Parameters {
+			l.declare(param, "parameter")
+		}
+		l.walk(node.Body)
+		l.pop()
+
+	case *ast.ForExpression:
+		l.walk(node.Iterable)
+
+		l.push(node, false)
+		l.bind(node.Target, "loop variable")
+		l.walk(node.Body)
+		l.pop()
+
+	case *ast.TryExpression:
+		l.walk(node.Block)
+
+		if node.CatchBlock != nil {
+			l.push(node.CatchBlock, false)
+			l.bind(node.CatchParameter, "catch parameter")
+			l.walk(node.CatchBlock)
+			l.pop()
+		}
+
+		l.walk(node.FinallyBlock)
+
+	case *ast.MatchExpression:
+		l.walk(node.Subject)
+
+		for _, arm := range node.Arms {
+			l.push(arm.Body, false)
+			l.matchPattern(arm.Pattern)
+			l.walk(arm.Body)
+			l.pop()
+		}
+
+	case *ast.SelectExpression:
+		for _, arm := range node.Arms {
+			l.walk(arm.Operation)
+
+			l.push(arm.Body, false)
+			l.bind(arm.Binding, "select binding")
+			l.walk(arm.Body)
+			l.pop()
+		}
+
+	case *ast.IfExpression:
+		l.condition(node)
+		return l
+
+	case *ast.MemberExpression:
+		// The property is a name looked up on the value, not in scope
+		l.walk(node.Ref)
+
+	case *ast.CallExpression:
+		if identifier, ok := node.Function.(*ast.Identifier); ok && identifier.Value == "quote" {
+			l.quoted(node.Arguments)
+			return nil
+		}
+
+		return l
+
+	default:
+		return l
+	}
+
+	return nil
+}
+
+// Walks an optional child, such as the finally block of a try
+func (l *linter) walk(node ast.Node) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+
+	ast.Walk(l, node)
+}
+
+// Walks the statements of a program or block, reporting the first statement after a return or throw
+func (l *linter) statements(statements []ast.Statement) {
+	for idx, statement := range statements {
+		l.walk(statement)
+
+		switch statement.(type) {
+		case *ast.ReturnStatement, *ast.ThrowStatement:
+			if idx < len(statements)-1 {
+				next := statements[idx+1]
+				l.report(statementToken(next), WARNING, UNREACHABLE, "unreachable code")
+
+				for _, statement := range statements[idx+1:] {
+					l.walk(statement)
+				}
+
+				return
+			}
+		}
+	}
+}
+
+func (l *linter) let(statement *ast.LetStatement, exported bool) {
+	l.walk(statement.Value)
+
+	kind := "let"
+	if exported {
+		kind = "export"
+	}
+
+	if statement.Pattern != nil {
+		l.bind(statement.Pattern, kind)
+	} else {
+		l.bind(statement.Name, kind)
+	}
+}
+
+func (l *linter) parameters(params []ast.Expression) {
+	for _, param := range params {
+		l.bind(param, "parameter")
+	}
+}
+
+// Only unquoted parts of quoted code are evaluated where they are written
+func (l *linter) quoted(args []ast.Expression) {
+	for _, arg := range args {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpression)
+			if !ok {
+				return true
+			}
+
+			if identifier, ok := call.Function.(*ast.Identifier); ok && identifier.Value == "unquote" {
+				for _, arg := range call.Arguments {
+					l.walk(arg)
+				}
+
+				return false
+			}
+
+			return true
+		})
+	}
+}
+
+/* ================================ Bindings =============================== */
+
+// Binds the identifiers of a let target, parameter or pattern
+func (l *linter) bind(target ast.Expression, kind string) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		if target != nil {
+			l.declare(target, kind)
+		}
+
+	case *ast.ArrayPattern:
+		for _, element := range target.Elements {
+			l.bind(element, kind)
+		}
+
+		if target.Rest != nil {
+			l.bind(target.Rest, kind)
+		}
+
+	case *ast.HashPattern:
+		for _, property := range target.Properties {
+			l.bind(property.Value, kind)
+		}
+
+		if target.Rest != nil {
+			l.bind(target.Rest, kind)
+		}
+
+	case *ast.RestElement:
+		l.declare(target.Target, kind)
+
+	case *ast.DefaultParameter:
+		l.walk(target.Value)
+		l.bind(target.Target, kind)
+	}
+}
+
+// Identifiers in a match pattern bind the value, unless they name a variant without fields
+func (l *linter) matchPattern(pattern ast.Expression) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if binding := l.lookup(pattern.Value); binding != nil && binding.unit {
+			binding.used = true
+			l.resolution.Declarations[pattern] = binding.identifier
+			return
+		}
+
+		l.declare(pattern, "pattern")
+
+	case *ast.VariantPattern:
+		l.walk(pattern.Variant)
+
+		for _, field := range pattern.Fields {
+			l.matchPattern(field)
+		}
+
+	case *ast.MemberExpression:
+		l.walk(pattern)
+	}
+}
+
+func (l *linter) declare(identifier *ast.Identifier, kind string) *binding {
+	if identifier.Value == "_" {
+		return nil
+	}
+
+	if l.builtins[identifier.Value] {
+		l.report(identifier.Token, WARNING, SHADOWED_BUILTIN, "%s %s shadows the builtin %s", kind, identifier.Value, identifier.Value)
+	}
+
+	binding := &binding{identifier: identifier, kind: kind}
+	l.scope.names[identifier.Value] = binding
+
+	// Top level test functions are called by fungo test
+	if kind == "let" && l.scope.parent == nil && strings.HasPrefix(identifier.Value, "test_") {
+		binding.used = true
+	}
+	l.bindings = append(l.bindings, binding)
+
+	l.scope.resolved.Names = append(l.scope.resolved.Names, identifier)
+	l.resolution.Declarations[identifier] = identifier
+	l.resolution.Kinds[identifier] = kind
+
+	return binding
+}
+
+func (l *linter) lookup(name string) *binding {
+	for scope := l.scope; scope != nil; scope = scope.parent {
+		if binding, ok := scope.names[name]; ok {
+			return binding
+		}
+	}
+
+	return nil
+}
+
+func (l *linter) reference(identifier *ast.Identifier) {
+	if binding := l.lookup(identifier.Value); binding != nil {
+		binding.used = true
+		l.resolution.Declarations[identifier] = binding.identifier
+		return
+	}
+
+	if l.builtins[identifier.Value] || identifier.Value == "quote" || identifier.Value == "unquote" {
+		return
+	}
+
+	for scope := l.scope; scope != nil; scope = scope.parent {
+		if scope.function {
+			l.deferred = append(l.deferred, reference{identifier: identifier, scope: l.scope})
+			return
+		}
+	}
+
+	l.report(identifier.Token, ERROR, UNDEFINED, "undefined: %s", identifier.Value)
+}
+
+// Resolves the references deferred to the end, in the scopes enclosing a function around them, then reports the
+// bindings that were never used
+func (l *linter) finish() {
+	for _, reference := range l.deferred {
+		var found *binding
+		crossed := false
+
+		for scope := reference.scope; scope != nil && found == nil; scope = scope.parent {
+			if crossed {
+				found = scope.names[reference.identifier.Value]
+			}
+
+			crossed = crossed || scope.function
+		}
+
+		if found == nil {
+			l.report(reference.identifier.Token, ERROR, UNDEFINED, "undefined: %s", reference.identifier.Value)
+			continue
+		}
+
+		found.used = true
+		l.resolution.Declarations[reference.identifier] = found.identifier
+	}
+
+	for _, binding := range l.bindings {
+		if binding.used || strings.HasPrefix(binding.identifier.Value, "_") {
+			continue
+		}
+
+		if binding.kind == "let" || binding.kind == "parameter" {
+			l.report(binding.identifier.Token, WARNING, UNUSED, "%s %s is never used", binding.kind, binding.identifier.Value)
+		}
+	}
+}
+
+/* =============================== Conditions ============================== */
+
+func (l *linter) condition(expression *ast.IfExpression) {
+	if value, ok := constant(expression.Condition); ok {
+		l.report(expression.Token, WARNING, CONSTANT_CONDITION, "condition %s is always %t", expression.Condition.String(), value)
+	}
+}
+
+// The truthiness of an expression when it does not depend on anything evaluated at runtime
+func constant(expression ast.Expression) (bool, bool) {
+	switch expression := expression.(type) {
+	case *ast.Boolean:
+		return expression.Value, true
+
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.ArrayLiteral, *ast.HashLiteral, *ast.FunctionLiteral:
+		return true, true
+
+	case *ast.PrefixExpression:
+		if value, ok := constant(expression.Right); ok && expression.Operator == "!" {
+			return !value, true
+		}
+
+	case *ast.InfixExpression:
+		return constantComparison(expression)
+	}
+
+	return false, false
+}
+
+func constantComparison(expression *ast.InfixExpression) (bool, bool) {
+	// x == x and x != x do not depend on x
+	if left, ok := expression.Left.(*ast.Identifier); ok {
+		if right, ok := expression.Right.(*ast.Identifier); ok && left.Value == right.Value {
+			switch expression.Operator {
+			case "==":
+				return true, true
+			case "!=", "<", ">":
+				return false, true
+			}
+		}
+	}
+
+	left, ok := literal(expression.Left)
+	if !ok {
+		return false, false
+	}
+
+	right, ok := literal(expression.Right)
+	if !ok {
+		return false, false
+	}
+
+	switch expression.Operator {
+	case "==":
+		return left == right, true
+	case "!=":
+		return left != right, true
+	}
+
+	leftInt, leftOk := left.(int64)
+	rightInt, rightOk := right.(int64)
+
+	if leftOk && rightOk {
+		switch expression.Operator {
+		case "<":
+			return leftInt < rightInt, true
+		case ">":
+			return leftInt > rightInt, true
+		}
+	}
+
+	return false, false
+}
+
+func literal(expression ast.Expression) (any, bool) {
+	switch expression := expression.(type) {
+	case *ast.IntegerLiteral:
+		return expression.Value, true
+	case *ast.StringLiteral:
+		return expression.Value, true
+	case *ast.Boolean:
+		return expression.Value, true
+	}
+
+	return nil, false
+}
+
+func statementToken(statement ast.Statement) token.Token {
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		return statement.Token
+	case *ast.ReturnStatement:
+		return statement.Token
+	case *ast.ThrowStatement:
+		return statement.Token
+	case *ast.ImportStatement:
+		return statement.Token
+	case *ast.ExportStatement:
+		return statement.Token
+	case *ast.StructStatement:
+		return statement.Token
+	case *ast.EnumStatement:
+		return statement.Token
+	case *ast.ExpressionStatement:
+		return statement.Token
+	case *ast.BlockStatement:
+		return statement.Token
+	}
+
+	return token.Token{}
+}
@@ -0,0 +1,212 @@
+package lint
+
+import (
+	"fmt"
+	"fungo/ast"
+	"fungo/lexer"
+	"fungo/parser"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LintTestSuite struct {
+	suite.Suite
+}
+
+func TestLintTestSuite(t *testing.T) {
+	suite.Run(t, &LintTestSuite{})
+}
+
+func (t *LintTestSuite) lint(input string) []string {
+	p := parser.NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	t.Empty(p.Errors(), input)
+
+	diagnostics := []string{}
+	for _, diagnostic := range Lint(program) {
+		diagnostics = append(diagnostics, diagnostic.String())
+	}
+
+	return diagnostics
+}
+
+func (t *LintTestSuite) TestUndefinedIdentifiers() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; x + y", []string{"1:16: error: undefined: y (undefined)"}},
+		{"x; let x = 1; x", []string{"1:1: error: undefined: x (undefined)"}},
+		{"len([1]); print(quote(a + b))", []string{}},
+		// Functions may use names bound after them, their bodies only run once they are called
+		{"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { even(n) }; odd(1)", []string{}},
+		{"let f = fn() { g() }; f()", []string{"1:16: error: undefined: g (undefined)"}},
+		{"let f = fn(x) { let y = z; y + x }; f(1)", []string{"1:25: error: undefined: z (undefined)"}},
+		{"for (x in [1]) { x }; x", []string{"1:23: error: undefined: x (undefined)"}},
+		{"if (true) { let y = 1 }; y", []string{"1:1: warning: condition true is always true (constant-condition)"}},
+		{"try { 1 } catch (e) { e }; e", []string{"1:28: error: undefined: e (undefined)"}},
+		{"let h = {}; h.missing", []string{}},
+		{"let m = macro(a) { quote(unquote(a) + b) }; m(1)", []string{}},
+		{"let m = macro(a) { quote(unquote(b)) }; m(1)", []string{
+			"1:15: warning: parameter a is never used (unused)",
+			"1:34: error: undefined: b (undefined)",
+		}},
+		{"select { recv(c) as v => v, _ => w }", []string{
+			"1:15: error: undefined: c (undefined)",
+			"1:34: error: undefined: w (undefined)",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, t.lint(test.input), test.input)
+	}
+}
+
+func (t *LintTestSuite) TestMatchPatterns() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"enum Shape { Circle(r), Empty }; let area = fn(s) { match (s) { Circle(r) => r, Empty => 0, other => other } }; area(Empty)",
+			[]string{},
+		},
+		{"match (1) { x => y }", []string{"1:18: error: undefined: y (undefined)"}},
+		{"match (1) { Ok(v) => v }", []string{"1:13: error: undefined: Ok (undefined)"}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, t.lint(test.input), test.input)
+	}
+}
+
+func (t *LintTestSuite) TestUnused() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1;", []string{"1:5: warning: let x is never used (unused)"}},
+		{"let [a, ...b] = [1]; a", []string{"1:12: warning: let b is never used (unused)"}},
+		{"let f = fn(a, b, _c) { a }; f(1)", []string{"1:15: warning: parameter b is never used (unused)"}},
+		{"let _ = 1; let _ignored = 2; export let api = 3;", []string{}},
+		{"let test_sum = fn() { let test_inner = 1; 2 };", []string{"1:27: warning: let test_inner is never used (unused)"}},
+		{"let x = 1; let x = 2; x", []string{"1:5: warning: let x is never used (unused)"}},
+		{"let f = fn({a, b: c}, d = a) { c + d }; f({})", []string{}},
+		{"let n = 1; for (x in [1]) { n }", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, t.lint(test.input), test.input)
+	}
+}
+
+func (t *LintTestSuite) TestUnreachableCode() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let f = fn() { return 1; print(2); 3 }; f()", []string{"1:26: warning: unreachable code (unreachable)"}},
+		{"let f = fn() { throw \"x\"; missing }; f()", []string{
+			"1:27: warning: unreachable code (unreachable)",
+			"1:27: error: undefined: missing (undefined)",
+		}},
+		{"let f = fn() { if (f) { return 1; } 2 }; f()", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, t.lint(test.input), test.input)
+	}
+}
+
+func (t *LintTestSuite) TestShadowedBuiltins() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let len = fn(x) { 1 }; len", []string{
+			"1:5: warning: let len shadows the builtin len (shadowed-builtin)",
+			"1:14: warning: parameter x is never used (unused)",
+		}},
+		{"let f = fn(print) { print }; f(1)", []string{"1:12: warning: parameter print shadows the builtin print (shadowed-builtin)"}},
+		{"for (first in [1]) { first }", []string{"1:6: warning: loop variable first shadows the builtin first (shadowed-builtin)"}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, t.lint(test.input), test.input)
+	}
+}
+
+func (t *LintTestSuite) TestConstantConditions() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"if (false) { 1 }", []string{"1:1: warning: condition false is always false (constant-condition)"}},
+		{"if (!true) { 1 }", []string{"1:1: warning: condition (!true) is always false (constant-condition)"}},
+		{"if (1 < 2) { 1 }", []string{"1:1: warning: condition (1 < 2) is always true (constant-condition)"}},
+		{"if (\"a\" == \"b\") { 1 }", []string{"1:1: warning: condition (a == b) is always false (constant-condition)"}},
+		{"if ([]) { 1 }", []string{"1:1: warning: condition [] is always true (constant-condition)"}},
+		{"let x = 1; if (x == x) { 1 }", []string{"1:12: warning: condition (x == x) is always true (constant-condition)"}},
+		{"let x = 1; if (x == 1) { 1 }", []string{}},
+		{"let f = fn() { 1 }; if (f()) { 1 }", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Equal(test.expected, t.lint(test.input), test.input)
+	}
+}
+
+func (t *LintTestSuite) TestSource() {
+	diagnostics := Source([]byte("let = 1"))
+
+	t.NotEmpty(diagnostics)
+	t.Equal(SYNTAX, diagnostics[0].Rule)
+	t.Equal(ERROR, diagnostics[0].Severity)
+	t.Equal(0, diagnostics[0].Line)
+
+	t.Equal([]Diagnostic{{Line: 1, Column: 9, Severity: ERROR, Rule: UNDEFINED, Message: "undefined: y"}}, Source([]byte("let x = y; x")))
+
+	t.Equal([]Diagnostic{
+		{Line: 2, Column: 1, Severity: WARNING, Rule: NON_EXHAUSTIVE, Message: "non-exhaustive match on R, missing B"},
+	}, Source([]byte("enum R { A, B }\nmatch (A) { A => 1 }")))
+}
+
+func (t *LintTestSuite) TestResolve() {
+	input := "let x = 1; let f = fn(x) { x + y }; let y = x; enum E { A }; match (f(y)) { A => x }"
+	p := parser.NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	t.Empty(p.Errors())
+
+	resolution := Resolve(program)
+
+	positions := func(identifiers []*ast.Identifier) []int {
+		columns := []int{}
+		for _, identifier := range identifiers {
+			columns = append(columns, identifier.Token.Column)
+		}
+
+		return columns
+	}
+
+	declarations := map[string]*ast.Identifier{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if identifier, ok := node.(*ast.Identifier); ok && resolution.Declarations[identifier] == identifier {
+			declarations[fmt.Sprintf("%s@%d", identifier.Value, identifier.Token.Column)] = identifier
+		}
+
+		return true
+	})
+
+	t.Equal([]int{5, 45, 82}, positions(resolution.References(declarations["x@5"])))
+	t.Equal([]int{23, 28}, positions(resolution.References(declarations["x@23"])))
+	t.Equal([]int{32, 41, 71}, positions(resolution.References(declarations["y@41"])))
+	t.Equal([]int{57, 77}, positions(resolution.References(declarations["A@57"])))
+	t.Equal("parameter", resolution.Kinds[declarations["x@23"]])
+	t.Equal("variant", resolution.Kinds[declarations["A@57"]])
+
+	t.Len(resolution.Scopes, 3)
+	t.Equal(program, resolution.Scopes[0].Node)
+	t.Equal(resolution.Scopes[0], resolution.Scopes[1].Parent)
+	t.Equal([]int{5, 16, 41, 53, 57}, positions(resolution.Scopes[0].Names))
+}
@@ -0,0 +1,45 @@
+package lint
+
+import (
+	"fungo/ast"
+	"sort"
+)
+
+// The bindings the identifiers of a program refer to, resolved with the same scoping rules Lint uses
+type Resolution struct {
+	// Every identifier that names a binding, mapped to the identifier declaring it. Declarations map to themselves,
+	// builtins and undefined names are left out
+	Declarations map[*ast.Identifier]*ast.Identifier
+	// What each declaring identifier binds: let, export, parameter, loop variable, struct, variant...
+	Kinds map[*ast.Identifier]string
+	// The scopes of the program, enclosing ones before those they enclose
+	Scopes []*Scope
+}
+
+type Scope struct {
+	Node   ast.Node // the program, function, loop, catch block or arm body the scope belongs to
+	Parent *Scope
+	Names  []*ast.Identifier // declared in this scope, in order
+}
+
+// Resolves the identifiers of a program, which may be one that did not parse cleanly
+func Resolve(program *ast.Program) *Resolution {
+	return run(program).resolution
+}
+
+// The identifiers referring to a declaration, including itself, sorted by position
+func (r *Resolution) References(declaration *ast.Identifier) []*ast.Identifier {
+	references := []*ast.Identifier{}
+	for identifier, declared := range r.Declarations {
+		if declared == declaration {
+			references = append(references, identifier)
+		}
+	}
+
+	sort.Slice(references, func(i, j int) bool {
+		a, b := references[i].Token, references[j].Token
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+
+	return references
+}
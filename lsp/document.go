@@ -0,0 +1,463 @@
+package lsp
+
+import (
+	"fmt"
+	"fungo/ast"
+	"fungo/checker"
+	"fungo/evaluator"
+	"fungo/lexer"
+	"fungo/lint"
+	"fungo/parser"
+	"fungo/printer"
+	"fungo/token"
+	"reflect"
+	"strings"
+)
+
+// An open document. Navigation, hover and completion use the last version of it that parsed, so they keep working
+// while an edit is in progress
+type document struct {
+	text        string
+	errors      []string
+	errorTokens []token.Token
+	warnings    []parser.Warning
+	*analysis
+}
+
+type analysis struct {
+	program     *ast.Program
+	resolution  *lint.Resolution
+	identifiers []*ast.Identifier
+	types       map[*ast.Identifier]*ast.TypeAnnotation // declared by annotated lets and parameters
+	functions   map[*ast.Identifier]*ast.FunctionLiteral
+}
+
+func newDocument(text string, previous *document) *document {
+	p := parser.NewParser(lexer.NewLexer(text))
+	program := p.ParseProgram()
+
+	doc := &document{text: text, errors: p.Errors(), errorTokens: p.ErrorTokens(), warnings: p.Warnings()}
+
+	switch {
+	case len(doc.errors) == 0:
+		doc.analysis = analyze(program)
+	case previous != nil:
+		doc.analysis = previous.analysis
+	default:
+		doc.analysis = analyze(&ast.Program{})
+	}
+
+	return doc
+}
+
+func analyze(program *ast.Program) *analysis {
+	a := &analysis{
+		program:    program,
+		resolution: lint.Resolve(program),
+		types:      make(map[*ast.Identifier]*ast.TypeAnnotation),
+		functions:  make(map[*ast.Identifier]*ast.FunctionLiteral),
+	}
+
+	seen := make(map[*ast.Identifier]bool)
+	for identifier := range a.resolution.Declarations {
+		seen[identifier] = true
+		a.identifiers = append(a.identifiers, identifier)
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			if !seen[node] {
+				seen[node] = true
+				a.identifiers = append(a.identifiers, node)
+			}
+
+		case *ast.LetStatement:
+			if node.Name == nil {
+				break
+			}
+
+			if node.Type != nil {
+				a.types[node.Name] = node.Type
+			}
+
+			if function, ok := node.Value.(*ast.FunctionLiteral); ok {
+				a.functions[node.Name] = function
+			}
+
+		case *ast.FunctionLiteral:
+			for idx, param := range node.Parameters {
+				if annotation := node.ParameterType(idx); annotation != nil {
+					if identifier := parameterName(param); identifier != nil {
+						a.types[identifier] = annotation
+					}
+				}
+			}
+		}
+
+		return true
+	})
+
+	return a
+}
+
+func parameterName(param ast.Expression) *ast.Identifier {
+	switch param := param.(type) {
+	case *ast.Identifier:
+		return param
+	case *ast.RestElement:
+		return param.Target
+	case *ast.DefaultParameter:
+		return parameterName(param.Target)
+	}
+
+	return nil
+}
+
+/* ============================== Diagnostics ============================== */
+
+// Parse errors when the document does not parse, otherwise the parser's warnings and what lint and the type checker
+// report
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	if len(d.errors) != 0 {
+		for idx, msg := range d.errors {
+			diagnostics = append(diagnostics, d.diagnostic(d.errorTokens[idx].Line, d.errorTokens[idx].Column, SEVERITY_ERROR, lint.SYNTAX, msg))
+		}
+
+		return diagnostics
+	}
+
+	for _, diagnostic := range append(lint.Lint(d.program), lint.ParserWarnings(d.warnings)...) {
+		severity := SEVERITY_WARNING
+		if diagnostic.Severity == lint.ERROR {
+			severity = SEVERITY_ERROR
+		}
+
+		diagnostics = append(diagnostics, d.diagnostic(diagnostic.Line, diagnostic.Column, severity, diagnostic.Rule, diagnostic.Message))
+	}
+
+	for _, err := range checker.Check(d.program) {
+		diagnostics = append(diagnostics, d.diagnostic(err.Line, err.Column, SEVERITY_ERROR, "type", err.Message))
+	}
+
+	return diagnostics
+}
+
+// Diagnostics cover the word at their position, or a single character when there is none
+func (d *document) diagnostic(line, column int, severity int, code string, msg string) Diagnostic {
+	start := position(line, column)
+	end := start
+
+	lines := strings.Split(d.text, "\n")
+	if start.Line < len(lines) {
+		text := lines[start.Line]
+		for end.Character < len(text) && isWordChar(text[end.Character]) {
+			end.Character += 1
+		}
+
+		if end == start && end.Character < len(text) {
+			end.Character += 1
+		}
+	}
+
+	return Diagnostic{Range: Range{Start: start, End: end}, Severity: severity, Code: code, Source: "fungo", Message: msg}
+}
+
+func isWordChar(char byte) bool {
+	return char == '_' || 'a' <= char && char <= 'z' || 'A' <= char && char <= 'Z' || '0' <= char && char <= '9'
+}
+
+/* =============================== Navigation ============================== */
+
+func (d *document) identifierAt(at Position) *ast.Identifier {
+	for _, identifier := range d.identifiers {
+		start := position(identifier.Token.Line, identifier.Token.Column)
+		if start.Line == at.Line && start.Character <= at.Character && at.Character <= start.Character+len(identifier.Value) {
+			return identifier
+		}
+	}
+
+	return nil
+}
+
+func (d *document) definition(uri string, at Position) []Location {
+	identifier := d.identifierAt(at)
+	if identifier == nil {
+		return []Location{}
+	}
+
+	declaration, ok := d.resolution.Declarations[identifier]
+	if !ok {
+		return []Location{}
+	}
+
+	return []Location{location(uri, declaration)}
+}
+
+func (d *document) references(uri string, at Position, includeDeclaration bool) []Location {
+	identifier := d.identifierAt(at)
+	if identifier == nil {
+		return []Location{}
+	}
+
+	declaration, ok := d.resolution.Declarations[identifier]
+	if !ok {
+		return []Location{}
+	}
+
+	locations := []Location{}
+	for _, reference := range d.resolution.References(declaration) {
+		if reference != declaration || includeDeclaration {
+			locations = append(locations, location(uri, reference))
+		}
+	}
+
+	return locations
+}
+
+/* ================================= Hover ================================= */
+
+func (d *document) hover(at Position) *Hover {
+	identifier := d.identifierAt(at)
+	if identifier == nil {
+		return nil
+	}
+
+	var description string
+
+	if declaration, ok := d.resolution.Declarations[identifier]; ok {
+		description = d.describe(declaration)
+	} else if signature, ok := evaluator.BuiltInSignature(identifier.Value); ok {
+		description = fmt.Sprintf("%s: %s", identifier.Value, signature)
+	} else {
+		return nil
+	}
+
+	span := identifierRange(identifier)
+
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: "```fungo\n" + description + "\n```"}, Range: &span}
+}
+
+// A line such as `let add = fn(a: int, b: int): int` or `parameter x: int` describing what a declaration binds
+func (d *document) describe(declaration *ast.Identifier) string {
+	kind := d.resolution.Kinds[declaration]
+
+	if function, ok := d.functions[declaration]; ok {
+		return fmt.Sprintf("%s %s = %s", kind, declaration.Value, signature(function))
+	}
+
+	if annotation, ok := d.types[declaration]; ok {
+		return fmt.Sprintf("%s %s: %s", kind, declaration.Value, annotation.String())
+	}
+
+	return fmt.Sprintf("%s %s", kind, declaration.Value)
+}
+
+// The head of a function literal, without its body
+func signature(function *ast.FunctionLiteral) string {
+	params := []string{}
+
+	for idx, param := range function.Parameters {
+		annotation := function.ParameterType(idx)
+
+		switch param := param.(type) {
+		case *ast.DefaultParameter:
+			target := param.Target.String()
+			if annotation != nil {
+				target += ": " + annotation.String()
+			}
+
+			params = append(params, target+" = "+param.Value.String())
+
+		default:
+			if annotation != nil {
+				params = append(params, param.String()+": "+annotation.String())
+			} else {
+				params = append(params, param.String())
+			}
+		}
+	}
+
+	head := "fn(" + strings.Join(params, ", ") + ")"
+	if function.ReturnType != nil {
+		head += ": " + function.ReturnType.String()
+	}
+
+	return head
+}
+
+/* =============================== Completion ============================== */
+
+// The names bound in the scopes around a position, innermost first, then the builtins and keywords
+func (d *document) completion(at Position) []CompletionItem {
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+
+	for idx := len(d.resolution.Scopes) - 1; idx >= 0; idx-- {
+		scope := d.resolution.Scopes[idx]
+		if !contains(scope.Node, at) {
+			continue
+		}
+
+		for _, name := range scope.Names {
+			if seen[name.Value] {
+				continue
+			}
+			seen[name.Value] = true
+
+			items = append(items, CompletionItem{Label: name.Value, Kind: d.completionKind(name), Detail: d.describe(name)})
+		}
+	}
+
+	for _, name := range evaluator.BuiltInNames() {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		signature, _ := evaluator.BuiltInSignature(name)
+		items = append(items, CompletionItem{Label: name, Kind: COMPLETION_FUNCTION, Detail: signature})
+	}
+
+	for _, keyword := range token.Keywords() {
+		items = append(items, CompletionItem{Label: keyword, Kind: COMPLETION_KEYWORD})
+	}
+
+	return items
+}
+
+func (d *document) completionKind(declaration *ast.Identifier) int {
+	if _, ok := d.functions[declaration]; ok {
+		return COMPLETION_FUNCTION
+	}
+
+	switch d.resolution.Kinds[declaration] {
+	case "struct":
+		return COMPLETION_STRUCT
+	case "enum":
+		return COMPLETION_ENUM
+	case "variant":
+		return COMPLETION_ENUM_MEMBER
+	}
+
+	return COMPLETION_VARIABLE
+}
+
+// Whether a position falls between the first and the last token of a node. The program contains every position
+func contains(node ast.Node, at Position) bool {
+	if _, ok := node.(*ast.Program); ok {
+		return true
+	}
+
+	var first, last *token.Token
+
+	ast.Inspect(node, func(node ast.Node) bool {
+		tokens := []token.Token{tokenOf(node)}
+		if block, ok := node.(*ast.BlockStatement); ok && block != nil {
+			tokens = append(tokens, block.Rbrace)
+		}
+
+		for _, tok := range tokens {
+			if tok.Line == 0 {
+				continue
+			}
+
+			if first == nil || before(tok, *first) {
+				first = &tok
+			}
+
+			if last == nil || before(*last, tok) {
+				last = &tok
+			}
+		}
+
+		return true
+	})
+
+	if first == nil {
+		return false
+	}
+
+	start := position(first.Line, first.Column)
+	end := position(last.Line, last.Column)
+
+	return !less(at, start) && !less(end, at)
+}
+
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+func less(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+// Every node has its position in a Token field
+func tokenOf(node ast.Node) token.Token {
+	value := reflect.ValueOf(node)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return token.Token{}
+	}
+
+	if field := value.FieldByName("Token"); field.IsValid() {
+		tok, _ := field.Interface().(token.Token)
+		return tok
+	}
+
+	return token.Token{}
+}
+
+/* =============================== Formatting ============================== */
+
+// Replaces the whole document with its canonical form, or with nothing when it is already formatted
+func (d *document) formatting() ([]TextEdit, *ResponseError) {
+	formatted, err := printer.Format([]byte(d.text))
+	if err != nil {
+		return nil, &ResponseError{Code: REQUEST_FAILED, Message: err.Error()}
+	}
+
+	if string(formatted) == d.text {
+		return []TextEdit{}, nil
+	}
+
+	lines := strings.Split(d.text, "\n")
+	end := Position{Line: len(lines) - 1, Character: len(lines[len(lines)-1])}
+
+	return []TextEdit{{Range: Range{End: end}, NewText: string(formatted)}}, nil
+}
+
+/* =============================== Positions =============================== */
+
+// Tokens have 1-based lines and columns
+func position(line, column int) Position {
+	if line == 0 {
+		return Position{}
+	}
+
+	return Position{Line: line - 1, Character: column - 1}
+}
+
+// The text of a 0-based line, without its line ending
+func (d *document) line(line int) (string, bool) {
+	lines := strings.Split(d.text, "\n")
+	if line < 0 || line >= len(lines) {
+		return "", false
+	}
+
+	return strings.TrimSuffix(lines[line], "\r"), true
+}
+
+func identifierRange(identifier *ast.Identifier) Range {
+	start := position(identifier.Token.Line, identifier.Token.Column)
+	return Range{Start: start, End: Position{Line: start.Line, Character: start.Character + len(identifier.Value)}}
+}
+
+func location(uri string, identifier *ast.Identifier) Location {
+	return Location{URI: uri, Range: identifierRange(identifier)}
+}
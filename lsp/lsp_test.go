@@ -0,0 +1,313 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LspTestSuite struct {
+	suite.Suite
+	client *client
+}
+
+func TestLspTestSuite(t *testing.T) {
+	suite.Run(t, &LspTestSuite{})
+}
+
+// Talks to a server running in process, over pipes
+type client struct {
+	in            *bufio.Reader
+	out           io.WriteCloser
+	id            int
+	notifications []message
+	done          chan error
+}
+
+func (t *LspTestSuite) SetupTest() {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	t.client = &client{in: bufio.NewReader(clientIn), out: clientOut, done: make(chan error, 1)}
+
+	go func() {
+		err := NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+		t.client.done <- err
+	}()
+
+	t.request("initialize", map[string]any{"capabilities": map[string]any{}}, nil)
+	t.notify("initialized", map[string]any{})
+}
+
+func (t *LspTestSuite) TearDownTest() {
+	t.request("shutdown", nil, nil)
+	t.notify("exit", nil)
+	t.NoError(<-t.client.done)
+}
+
+func (t *LspTestSuite) notify(method string, params any) {
+	t.Require().NoError(writeMessage(t.client.out, notification{JSONRPC: "2.0", Method: method, Params: params}))
+}
+
+// Sends a request and decodes its result into result, collecting the notifications sent before the response
+func (t *LspTestSuite) request(method string, params any, result any) *ResponseError {
+	t.client.id += 1
+	id := json.RawMessage(fmt.Sprint(t.client.id))
+
+	t.Require().NoError(writeMessage(t.client.out, struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id"`
+		Method  string           `json:"method"`
+		Params  any              `json:"params"`
+	}{"2.0", &id, method, params}))
+
+	for {
+		msg := t.receive()
+		if msg.Method != "" {
+			t.client.notifications = append(t.client.notifications, msg)
+			continue
+		}
+
+		t.Require().Equal(string(id), string(*msg.ID))
+		if msg.Error != nil {
+			return msg.Error
+		}
+
+		if result != nil {
+			t.Require().NoError(json.Unmarshal(msg.Result, result))
+		}
+
+		return nil
+	}
+}
+
+func (t *LspTestSuite) receive() message {
+	content, err := readMessage(t.client.in)
+	t.Require().NoError(err)
+
+	msg := message{}
+	t.Require().NoError(json.Unmarshal(content, &msg))
+
+	return msg
+}
+
+// Opens a document and returns the diagnostics published for it
+func (t *LspTestSuite) open(uri string, text string) []Diagnostic {
+	t.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "fungo", Text: text}})
+
+	return t.diagnostics()
+}
+
+func (t *LspTestSuite) change(uri string, text string) []Diagnostic {
+	t.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: uri},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: text}},
+	})
+
+	return t.diagnostics()
+}
+
+func (t *LspTestSuite) diagnostics() []Diagnostic {
+	msg := t.receive()
+	t.Require().Equal("textDocument/publishDiagnostics", msg.Method)
+
+	params := PublishDiagnosticsParams{}
+	t.Require().NoError(json.Unmarshal(msg.Params, &params))
+
+	return params.Diagnostics
+}
+
+func at(uri string, line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: line, Character: character}}
+}
+
+func span(line, start, end int) Range {
+	return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}
+}
+
+func (t *LspTestSuite) TestInitialize() {
+	result := InitializeResult{}
+	t.Nil(t.request("initialize", map[string]any{}, &result))
+
+	t.Equal("fungo", result.ServerInfo.Name)
+	t.Equal(1, result.Capabilities.TextDocumentSync)
+	t.True(result.Capabilities.DefinitionProvider)
+	t.True(result.Capabilities.DocumentFormattingProvider)
+
+	err := t.request("workspace/symbol", map[string]any{}, nil)
+	t.Equal(METHOD_NOT_FOUND, err.Code)
+}
+
+// Clients that do not take utf-8 count characters in utf-16 code units, the é takes one and the 😀 two
+func (t *LspTestSuite) TestPositionEncoding() {
+	uri := "file:///main.fg"
+
+	result := InitializeResult{}
+	initialize := map[string]any{"capabilities": map[string]any{"general": map[string]any{"positionEncodings": []string{"utf-16"}}}}
+	t.Nil(t.request("initialize", initialize, &result))
+	t.Equal("utf-16", result.Capabilities.PositionEncoding)
+
+	t.open(uri, "let s = \"é😀\"; let x = s;\nx + len(s)")
+
+	locations := []Location{}
+	t.Nil(t.request("textDocument/definition", at(uri, 1, 0), &locations))
+	t.Equal([]Location{{URI: uri, Range: span(0, 19, 20)}}, locations)
+
+	t.Nil(t.request("textDocument/definition", at(uri, 0, 23), &locations))
+	t.Equal([]Location{{URI: uri, Range: span(0, 4, 5)}}, locations)
+
+	params := ReferenceParams{TextDocumentPositionParams: at(uri, 0, 4)}
+	t.Nil(t.request("textDocument/references", params, &locations))
+	t.Equal([]Location{{URI: uri, Range: span(0, 23, 24)}, {URI: uri, Range: span(1, 8, 9)}}, locations)
+
+	diagnostics := t.change(uri, "let s = \"😀\"; len(s) + missing")
+	t.Require().Len(diagnostics, 1)
+	t.Equal(span(0, 23, 30), diagnostics[0].Range)
+
+	initialize = map[string]any{"capabilities": map[string]any{"general": map[string]any{"positionEncodings": []string{"utf-32", "utf-8"}}}}
+	t.Nil(t.request("initialize", initialize, &result))
+	t.Equal("utf-8", result.Capabilities.PositionEncoding)
+
+	t.change(uri, "let s = \"é😀\"; let x = s;\nx + len(s)")
+	t.Nil(t.request("textDocument/definition", at(uri, 1, 0), &locations))
+	t.Equal([]Location{{URI: uri, Range: span(0, 22, 23)}}, locations)
+}
+
+func (t *LspTestSuite) TestDiagnostics() {
+	uri := "file:///main.fg"
+
+	diagnostics := t.open(uri, "let x = 1;\nlet = 2;")
+	t.Require().NotEmpty(diagnostics)
+	t.Equal(Diagnostic{
+		Range:    span(1, 4, 5),
+		Severity: SEVERITY_ERROR,
+		Code:     "syntax",
+		Source:   "fungo",
+		Message:  `expected next token to be "IDENT", got "=" instead`,
+	}, diagnostics[0])
+
+	diagnostics = t.change(uri, "let f = fn(a: int) { a };\nf(\"a\") + missing")
+	t.Equal([]Diagnostic{
+		{Range: span(1, 9, 16), Severity: SEVERITY_ERROR, Code: "undefined", Source: "fungo", Message: "undefined: missing"},
+		{Range: span(1, 2, 3), Severity: SEVERITY_ERROR, Code: "type", Source: "fungo", Message: "cannot use string as int in argument 1 to f"},
+	}, diagnostics)
+
+	t.Empty(t.change(uri, "let f = fn(a) { a }; f(1)"))
+
+	t.Equal([]Diagnostic{{
+		Range:    span(1, 0, 5),
+		Severity: SEVERITY_WARNING,
+		Code:     "non-exhaustive",
+		Source:   "fungo",
+		Message:  "non-exhaustive match on R, missing B",
+	}}, t.change(uri, "enum R { A, B }\nmatch (A) { A => 1 }"))
+
+	t.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	t.Empty(t.diagnostics())
+
+	err := t.request("textDocument/hover", at(uri, 0, 4), nil)
+	t.Equal(INVALID_PARAMS, err.Code)
+}
+
+func (t *LspTestSuite) TestDefinitionAndReferences() {
+	uri := "file:///main.fg"
+	t.open(uri, "let total = 0;\nlet add = fn(total, n) { total + n };\nadd(total, 1)")
+
+	locations := []Location{}
+	t.Nil(t.request("textDocument/definition", at(uri, 2, 6), &locations))
+	t.Equal([]Location{{URI: uri, Range: span(0, 4, 9)}}, locations)
+
+	// The parameter shadows the outer total inside the function
+	t.Nil(t.request("textDocument/definition", at(uri, 1, 27), &locations))
+	t.Equal([]Location{{URI: uri, Range: span(1, 13, 18)}}, locations)
+
+	t.Nil(t.request("textDocument/definition", at(uri, 2, 0), &locations))
+	t.Equal([]Location{{URI: uri, Range: span(1, 4, 7)}}, locations)
+
+	t.Nil(t.request("textDocument/definition", at(uri, 0, 12), &locations))
+	t.Empty(locations)
+
+	params := ReferenceParams{TextDocumentPositionParams: at(uri, 0, 5)}
+	t.Nil(t.request("textDocument/references", params, &locations))
+	t.Equal([]Location{{URI: uri, Range: span(2, 4, 9)}}, locations)
+
+	params.Context.IncludeDeclaration = true
+	t.Nil(t.request("textDocument/references", params, &locations))
+	t.Equal([]Location{{URI: uri, Range: span(0, 4, 9)}, {URI: uri, Range: span(2, 4, 9)}}, locations)
+}
+
+func (t *LspTestSuite) TestHover() {
+	uri := "file:///main.fg"
+	t.open(uri, "let add = fn(a: int, b = 2): int { a + b };\nlen(\"abc\") + add(1)")
+
+	hover := &Hover{}
+	t.Nil(t.request("textDocument/hover", at(uri, 1, 1), &hover))
+	t.Equal("```fungo\nlen: fn(any): int\n```", hover.Contents.Value)
+	t.Equal("markdown", hover.Contents.Kind)
+	t.Equal(span(1, 0, 3), *hover.Range)
+
+	t.Nil(t.request("textDocument/hover", at(uri, 1, 14), &hover))
+	t.Equal("```fungo\nlet add = fn(a: int, b = 2): int\n```", hover.Contents.Value)
+
+	t.Nil(t.request("textDocument/hover", at(uri, 0, 35), &hover))
+	t.Equal("```fungo\nparameter a: int\n```", hover.Contents.Value)
+
+	t.Nil(t.request("textDocument/hover", at(uri, 0, 9), &hover))
+	t.Nil(hover)
+}
+
+func (t *LspTestSuite) TestCompletion() {
+	uri := "file:///main.fg"
+	t.open(uri, "let top = 1;\nlet f = fn(param) {\n  let local = param;\n  local\n};\nstruct Point { x, y }")
+
+	labels := func(line, character int) map[string]CompletionItem {
+		items := []CompletionItem{}
+		t.Nil(t.request("textDocument/completion", at(uri, line, character), &items))
+
+		labels := map[string]CompletionItem{}
+		for _, item := range items {
+			labels[item.Label] = item
+		}
+
+		return labels
+	}
+
+	inside := labels(3, 2)
+	t.Equal(CompletionItem{Label: "local", Kind: COMPLETION_VARIABLE, Detail: "let local"}, inside["local"])
+	t.Equal(CompletionItem{Label: "param", Kind: COMPLETION_VARIABLE, Detail: "parameter param"}, inside["param"])
+	t.Equal(CompletionItem{Label: "f", Kind: COMPLETION_FUNCTION, Detail: "let f = fn(param)"}, inside["f"])
+	t.Equal(CompletionItem{Label: "Point", Kind: COMPLETION_STRUCT, Detail: "struct Point"}, inside["Point"])
+	t.Equal(CompletionItem{Label: "len", Kind: COMPLETION_FUNCTION, Detail: "fn(any): int"}, inside["len"])
+	t.Equal(CompletionItem{Label: "match", Kind: COMPLETION_KEYWORD}, inside["match"])
+
+	outside := labels(5, 0)
+	t.Contains(outside, "top")
+	t.NotContains(outside, "local")
+	t.NotContains(outside, "param")
+
+	// While an edit does not parse, completion uses the last version that did
+	t.change(uri, "let top = 1;\nlet f = fn(param) {\n  let local = param;\n  local\n};\nstruct Point { x, y }\nlet")
+	t.Contains(labels(3, 2), "local")
+}
+
+func (t *LspTestSuite) TestFormatting() {
+	uri := "file:///main.fg"
+	t.open(uri, "let   x=1\nx")
+
+	edits := []TextEdit{}
+	t.Nil(t.request("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &edits))
+	t.Equal([]TextEdit{{Range: Range{End: Position{Line: 1, Character: 1}}, NewText: "let x = 1;\nx\n"}}, edits)
+
+	t.change(uri, "let x = 1;\nx\n")
+	t.Nil(t.request("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &edits))
+	t.Empty(edits)
+
+	t.change(uri, "let = 1")
+	err := t.request("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &edits)
+	t.Equal(REQUEST_FAILED, err.Code)
+}
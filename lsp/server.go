@@ -0,0 +1,321 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// A language server speaking JSON-RPC over a pair of streams, usually standard input and output. Requests are handled
+// one at a time in the order they arrive
+type Server struct {
+	in        *bufio.Reader
+	out       io.Writer
+	documents map[string]*document
+	shutdown  bool
+	utf8      bool // whether the client takes positions in bytes, rather than in utf-16 code units
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, documents: make(map[string]*document)}
+}
+
+// Serves requests until the client sends exit. Exiting, or closing the input, without asking the server to shut down
+// first is an error
+func (s *Server) Serve() error {
+	for {
+		content, err := readMessage(s.in)
+		if err == io.EOF && s.shutdown {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		msg := message{}
+		if err := json.Unmarshal(content, &msg); err != nil {
+			if err := s.respondError(nil, &ResponseError{Code: PARSE_ERROR, Message: err.Error()}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+
+			return nil
+		}
+
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg message) error {
+	// Responses to requests the server never sends
+	if msg.Method == "" {
+		return nil
+	}
+
+	result, err := s.dispatch(msg)
+
+	if msg.ID == nil {
+		// Notifications get no response, not even when they fail
+		return nil
+	}
+
+	if err != nil {
+		return s.respondError(msg.ID, err)
+	}
+
+	return writeMessage(s.out, response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *Server) respondError(id *json.RawMessage, err *ResponseError) error {
+	return writeMessage(s.out, errorResponse{JSONRPC: "2.0", ID: id, Error: err})
+}
+
+func (s *Server) notify(method string, params any) error {
+	return writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) dispatch(msg message) (any, *ResponseError) {
+	if s.shutdown && msg.Method != "exit" {
+		return nil, &ResponseError{Code: INVALID_REQUEST, Message: "server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		params := InitializeParams{}
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+
+		// Documents are indexed in bytes, utf-16 is what every client supports
+		s.utf8 = false
+		for _, encoding := range params.Capabilities.General.PositionEncodings {
+			s.utf8 = s.utf8 || encoding == "utf-8"
+		}
+
+		result := InitializeResult{}
+		result.ServerInfo.Name = "fungo"
+		result.Capabilities = ServerCapabilities{
+			PositionEncoding:           "utf-16",
+			TextDocumentSync:           1,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			HoverProvider:              true,
+			DocumentFormattingProvider: true,
+		}
+		if s.utf8 {
+			result.Capabilities.PositionEncoding = "utf-8"
+		}
+
+		return result, nil
+
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		params := DidOpenTextDocumentParams{}
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return nil, s.open(params.TextDocument.URI, params.TextDocument.Text)
+
+	case "textDocument/didChange":
+		params := DidChangeTextDocumentParams{}
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+
+		// With full sync the last change holds the whole document
+		return nil, s.open(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+
+	case "textDocument/didClose":
+		params := DidCloseTextDocumentParams{}
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+
+		delete(s.documents, params.TextDocument.URI)
+
+		return nil, s.publish(params.TextDocument.URI, []Diagnostic{})
+
+	case "textDocument/definition":
+		params := TextDocumentPositionParams{}
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.locations(doc, doc.definition(params.TextDocument.URI, s.fromClient(doc, params.Position))), nil
+
+	case "textDocument/references":
+		params := ReferenceParams{}
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+
+		at := s.fromClient(doc, params.Position)
+		return s.locations(doc, doc.references(params.TextDocument.URI, at, params.Context.IncludeDeclaration)), nil
+
+	case "textDocument/hover":
+		params := TextDocumentPositionParams{}
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+
+		hover := doc.hover(s.fromClient(doc, params.Position))
+		if hover != nil && hover.Range != nil {
+			*hover.Range = s.toClient(doc, *hover.Range)
+		}
+
+		return hover, nil
+
+	case "textDocument/completion":
+		params := TextDocumentPositionParams{}
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+
+		return doc.completion(s.fromClient(doc, params.Position)), nil
+
+	case "textDocument/formatting":
+		params := DocumentFormattingParams{}
+		doc, err := s.document(msg.Params, &params, &params.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+
+		edits, err := doc.formatting()
+		for idx := range edits {
+			edits[idx].Range = s.toClient(doc, edits[idx].Range)
+		}
+
+		return edits, err
+	}
+
+	return nil, &ResponseError{Code: METHOD_NOT_FOUND, Message: fmt.Sprintf("method not found: %s", msg.Method)}
+}
+
+func decode(params json.RawMessage, value any) *ResponseError {
+	if err := json.Unmarshal(params, value); err != nil {
+		return &ResponseError{Code: INVALID_PARAMS, Message: err.Error()}
+	}
+
+	return nil
+}
+
+// Decodes the params of a request about an open document and returns the document
+func (s *Server) document(params json.RawMessage, value any, identifier *TextDocumentIdentifier) (*document, *ResponseError) {
+	if err := decode(params, value); err != nil {
+		return nil, err
+	}
+
+	doc, ok := s.documents[identifier.URI]
+	if !ok {
+		return nil, &ResponseError{Code: INVALID_PARAMS, Message: fmt.Sprintf("document not open: %s", identifier.URI)}
+	}
+
+	return doc, nil
+}
+
+func (s *Server) open(uri string, text string) *ResponseError {
+	doc := newDocument(text, s.documents[uri])
+	s.documents[uri] = doc
+
+	diagnostics := doc.diagnostics()
+	for idx := range diagnostics {
+		diagnostics[idx].Range = s.toClient(doc, diagnostics[idx].Range)
+	}
+
+	return s.publish(uri, diagnostics)
+}
+
+func (s *Server) publish(uri string, diagnostics []Diagnostic) *ResponseError {
+	if err := s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics}); err != nil {
+		return &ResponseError{Code: REQUEST_FAILED, Message: err.Error()}
+	}
+
+	return nil
+}
+
+/* =============================== Positions =============================== */
+
+// Locations are all in the document asked about
+func (s *Server) locations(doc *document, locations []Location) []Location {
+	for idx := range locations {
+		locations[idx].Range = s.toClient(doc, locations[idx].Range)
+	}
+
+	return locations
+}
+
+// Converts a position of the client into one counting bytes, positions past the end of their line stay past it
+func (s *Server) fromClient(doc *document, at Position) Position {
+	line, ok := doc.line(at.Line)
+	if s.utf8 || !ok {
+		return at
+	}
+
+	units, offset := 0, 0
+	for offset < len(line) && units < at.Character {
+		char, size := utf8.DecodeRuneInString(line[offset:])
+		units += utf16Length(char)
+		offset += size
+	}
+
+	return Position{Line: at.Line, Character: offset + at.Character - units}
+}
+
+func (s *Server) toClient(doc *document, r Range) Range {
+	return Range{Start: s.positionToClient(doc, r.Start), End: s.positionToClient(doc, r.End)}
+}
+
+func (s *Server) positionToClient(doc *document, at Position) Position {
+	line, ok := doc.line(at.Line)
+	if s.utf8 || !ok {
+		return at
+	}
+
+	end := at.Character
+	if end > len(line) {
+		end = len(line)
+	}
+
+	units := 0
+	for _, char := range line[:end] {
+		units += utf16Length(char)
+	}
+
+	return Position{Line: at.Line, Character: units + at.Character - end}
+}
+
+// Characters outside the basic multilingual plane take a surrogate pair
+func utf16Length(char rune) int {
+	if char >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
@@ -1,10 +1,10 @@
 package main
 
 import (
-	"fungo/repl"
+	"fungo/cli"
 	"os"
 )
 
 func main() {
-	repl.Start(os.Stdin, os.Stdout)
+	os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -1,8 +1,18 @@
 package object
 
+import (
+	"sort"
+	"sync"
+)
+
+// Environments are safe for concurrent use. A name is looked up every time it is read, so code running on another
+// goroutine sees the latest value bound to it in a shared scope. Values themselves are immutable, a `let` inside a
+// function only ever binds in that call's own scope, which is why spawned functions cannot race on their locals
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	mu      sync.RWMutex
+	store   map[string]Object
+	outer   *Environment
+	context any
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
@@ -21,8 +31,11 @@ func NewEnvironment() *Environment {
 	}
 }
 
-func (e Environment) Get(name string) (Object, bool) {
+func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
 	value, ok := e.store[name]
+	e.mu.RUnlock()
+
 	if !ok && e.outer != nil {
 		value, ok = e.outer.Get(name)
 	}
@@ -30,8 +43,71 @@ func (e Environment) Get(name string) (Object, bool) {
 	return value, ok
 }
 
+// Looks a name up in this environment only, not in the ones enclosing it
+func (e *Environment) GetLocal(name string) (Object, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	value, ok := e.store[name]
+
+	return value, ok
+}
+
 func (e *Environment) Set(name string, value Object) Object {
+	e.mu.Lock()
 	e.store[name] = value
+	e.mu.Unlock()
 
 	return value
 }
+
+// Returns the environment this one is enclosed by, nil for the outermost one
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Returns the names bound in this environment itself, sorted
+func (e *Environment) Names() []string {
+	e.mu.RLock()
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	e.mu.RUnlock()
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Returns the value attached with SetContext to this environment or the nearest enclosing one. The object package
+// does not interpret it, it is how the evaluator shares per program state (module loader, ...) between environments
+func (e *Environment) Context() any {
+	for env := e; env != nil; env = env.outer {
+		env.mu.RLock()
+		context := env.context
+		env.mu.RUnlock()
+
+		if context != nil {
+			return context
+		}
+	}
+
+	return nil
+}
+
+func (e *Environment) SetContext(context any) {
+	e.mu.Lock()
+	e.context = context
+	e.mu.Unlock()
+}
+
+// Returns the outermost environment this one is enclosed by
+func (e *Environment) Root() *Environment {
+	env := e
+	for env.outer != nil {
+		env = env.outer
+	}
+
+	return env
+}
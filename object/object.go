@@ -6,6 +6,7 @@ import (
 	"fungo/ast"
 	"hash/fnv"
 	"strings"
+	"sync"
 )
 
 type ObjectType string
@@ -22,6 +23,17 @@ const (
 	BUILTIN_OBJ    = "BUILTIN"
 	ARRAY_OBJ      = "ARRAY"
 	HASH_OBJ       = "HASH"
+	MODULE_OBJ     = "MODULE"
+	STRUCT_DEF_OBJ = "STRUCT_DEFINITION"
+	STRUCT_OBJ     = "STRUCT"
+	ENUM_DEF_OBJ   = "ENUM_DEFINITION"
+	VARIANT_OBJ    = "ENUM_VARIANT"
+	ENUM_OBJ       = "ENUM"
+	ITERATOR_OBJ   = "ITERATOR"
+	CHANNEL_OBJ    = "CHANNEL"
+	PROMISE_OBJ    = "PROMISE"
+	QUOTE_OBJ      = "QUOTE"
+	MACRO_OBJ      = "MACRO"
 )
 
 type Object interface {
@@ -159,9 +171,16 @@ func (n Noop) String() string {
 /* ================================ Function ================================ */
 type Function struct {
 	Object
-	Parameters []*ast.Identifier
+	Name       string // Name of the binding the function literal was assigned to, empty for anonymous functions
+	Parameters []ast.Expression
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Generator  bool
+	Async      bool
+
+	// Annotations of the parameters and result, only enforced when the program runs with type checks enabled
+	ParameterTypes []*ast.TypeAnnotation
+	ReturnType     *ast.TypeAnnotation
 }
 
 func (f Function) Type() ObjectType {
@@ -244,9 +263,22 @@ func (a Array) String() string {
 }
 
 /* ================================== Error ================================= */
+const (
+	RUNTIME_ERROR   = "RuntimeError"
+	ARGUMENT_ERROR  = "ArgumentError"
+	TYPE_ERROR      = "TypeError"
+	THROWN_ERROR    = "Error"
+	ASSERTION_ERROR = "AssertionError"
+)
+
+// Kind classifies the error (e.g. "RuntimeError", "ArgumentError" or a user defined kind), Trace lists the calls it
+// unwound through starting from the innermost one, and Value holds whatever was passed to `throw`
 type Error struct {
 	Object
 	Message string
+	Kind    string
+	Trace   []string
+	Value   Object
 }
 
 func (e Error) Type() ObjectType {
@@ -256,3 +288,296 @@ func (e Error) Type() ObjectType {
 func (e Error) String() string {
 	return "⛔️ ERROR: " + e.Message
 }
+
+/* ================================= Module ================================= */
+// Env is the environment the module was evaluated in, only the names in Exports can be read from the outside
+type Module struct {
+	Object
+	Name    string
+	Path    string
+	Env     *Environment
+	Exports map[string]bool
+}
+
+func (m Module) Type() ObjectType {
+	return MODULE_OBJ
+}
+
+func (m Module) String() string {
+	return fmt.Sprintf("<module %s>", m.Name)
+}
+
+// Returns the current value of an exported binding
+func (m Module) Get(name string) (Object, bool) {
+	if !m.Exports[name] {
+		return nil, false
+	}
+
+	return m.Env.Get(name)
+}
+
+/* ============================ StructDefinition ============================ */
+// Declared with `struct <Name> { <fields> }`, calling it constructs a Struct
+type StructDefinition struct {
+	Object
+	Name   string
+	Fields []string
+}
+
+func (s StructDefinition) Type() ObjectType {
+	return STRUCT_DEF_OBJ
+}
+
+func (s StructDefinition) String() string {
+	return "struct " + s.Name + " { " + strings.Join(s.Fields, ", ") + " }"
+}
+
+/* ================================= Struct ================================= */
+// Instances of every definition share one object type, so that a struct named like a built in type such as STRING or
+// ERROR is not mistaken for it. Their declared type is the name of their definition
+type Struct struct {
+	Object
+	Definition *StructDefinition
+	Values     []Object
+}
+
+func (s Struct) Type() ObjectType {
+	return STRUCT_OBJ
+}
+
+func (s Struct) String() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for idx, field := range s.Definition.Fields {
+		fields = append(fields, field+": "+s.Values[idx].String())
+	}
+
+	out.WriteString(s.Definition.Name + "{" + strings.Join(fields, ", ") + "}")
+
+	return out.String()
+}
+
+func (s Struct) Field(name string) (Object, bool) {
+	for idx, field := range s.Definition.Fields {
+		if field == name {
+			return s.Values[idx], true
+		}
+	}
+
+	return nil, false
+}
+
+/* ============================= EnumDefinition ============================= */
+// Declared with `enum <Name> { <Variant>(<fields>), ... }`, its variants are reachable as members: Result.Ok
+type EnumDefinition struct {
+	Object
+	Name     string
+	Variants []*EnumVariant
+}
+
+func (e EnumDefinition) Type() ObjectType {
+	return ENUM_DEF_OBJ
+}
+
+func (e EnumDefinition) String() string {
+	variants := []string{}
+	for _, variant := range e.Variants {
+		variants = append(variants, variant.signature())
+	}
+
+	return "enum " + e.Name + " { " + strings.Join(variants, ", ") + " }"
+}
+
+func (e EnumDefinition) Variant(name string) (*EnumVariant, bool) {
+	for _, variant := range e.Variants {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+
+	return nil, false
+}
+
+/* =============================== EnumVariant ============================== */
+// Variants with fields are constructors, called with one argument per field. Variants without fields are not
+// called, Unit is their only value
+type EnumVariant struct {
+	Object
+	Enum   *EnumDefinition
+	Name   string
+	Fields []string
+	Unit   *EnumValue
+}
+
+func (e EnumVariant) Type() ObjectType {
+	return VARIANT_OBJ
+}
+
+func (e EnumVariant) String() string {
+	return e.Enum.Name + "." + e.signature()
+}
+
+func (e EnumVariant) signature() string {
+	if len(e.Fields) == 0 {
+		return e.Name
+	}
+
+	return e.Name + "(" + strings.Join(e.Fields, ", ") + ")"
+}
+
+/* ================================ EnumValue =============================== */
+// Values of every enum share one object type, like structs do. Their declared type is the name of their enum
+type EnumValue struct {
+	Object
+	Variant *EnumVariant
+	Values  []Object
+}
+
+func (e EnumValue) Type() ObjectType {
+	return ENUM_OBJ
+}
+
+func (e EnumValue) String() string {
+	if len(e.Values) == 0 {
+		return e.Variant.Name
+	}
+
+	values := []string{}
+	for _, value := range e.Values {
+		values = append(values, value.String())
+	}
+
+	return e.Variant.Name + "(" + strings.Join(values, ", ") + ")"
+}
+
+/* ================================ Iterator ================================ */
+// Next returns the next value, or done once the iterator is exhausted. An error returned by Next ends the
+// iteration. Iterators are lazy, can only be walked once and must not be shared between goroutines
+type Iterator struct {
+	Object
+	Next func() (value Object, done bool)
+}
+
+func (i Iterator) Type() ObjectType {
+	return ITERATOR_OBJ
+}
+
+func (i Iterator) String() string {
+	return "<iterator>"
+}
+
+/* ================================= Channel ================================ */
+// Value is the underlying Go channel, hosts can send to and receive from it directly
+type Channel struct {
+	Object
+	Value chan Object
+}
+
+func (c Channel) Type() ObjectType {
+	return CHANNEL_OBJ
+}
+
+func (c Channel) String() string {
+	return "<channel>"
+}
+
+/* ================================= Promise ================================ */
+// A promise settles once, either with a value or, when rejected, with an *Error. It is safe for concurrent use
+type Promise struct {
+	Object
+	mu        sync.Mutex
+	settled   bool
+	value     Object
+	callbacks []func(Object)
+}
+
+func (p *Promise) Type() ObjectType {
+	return PROMISE_OBJ
+}
+
+func (p *Promise) String() string {
+	value, settled := p.Result()
+	if !settled {
+		return "<promise pending>"
+	}
+
+	return "<promise " + value.String() + ">"
+}
+
+// Returns the value the promise settled with, settled is false while it is pending
+func (p *Promise) Result() (value Object, settled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.value, p.settled
+}
+
+// Settles the promise and runs the callbacks registered so far, settling it again has no effect
+func (p *Promise) Settle(value Object) {
+	p.mu.Lock()
+	if p.settled {
+		p.mu.Unlock()
+		return
+	}
+
+	p.settled = true
+	p.value = value
+	callbacks := p.callbacks
+	p.callbacks = nil
+	p.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(value)
+	}
+}
+
+// Registers a callback run once the promise settles, right away if it already has
+func (p *Promise) OnSettled(callback func(Object)) {
+	p.mu.Lock()
+	if !p.settled {
+		p.callbacks = append(p.callbacks, callback)
+		p.mu.Unlock()
+		return
+	}
+
+	value := p.value
+	p.mu.Unlock()
+
+	callback(value)
+}
+
+/* ================================== Quote ================================= */
+type Quote struct {
+	Object
+	Node ast.Node
+}
+
+func (q Quote) Type() ObjectType {
+	return QUOTE_OBJ
+}
+
+func (q Quote) String() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+/* ================================== Macro ================================= */
+type Macro struct {
+	Object
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m Macro) Type() ObjectType {
+	return MACRO_OBJ
+}
+
+func (m Macro) String() string {
+	params := []string{}
+	for _, param := range m.Parameters {
+		params = append(params, param.String())
+	}
+
+	return "macro(" + strings.Join(params, ", ") + ") {\n" + m.Body.String() + "\n}"
+}
@@ -26,3 +26,16 @@ func (t *ObjectTestSuite) TestStringHashKey() {
 	t.NotEqual(test1a.HashKey(), test2a.HashKey())
 	t.NotEqual(test1b.HashKey(), test2b.HashKey())
 }
+
+func (t *ObjectTestSuite) TestEnvironmentNames() {
+	outer := NewEnvironment()
+	outer.Set("b", &Integer{Value: 1})
+	outer.Set("a", &Integer{Value: 2})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("c", &Integer{Value: 3})
+
+	t.Equal([]string{"c"}, inner.Names())
+	t.Equal([]string{"a", "b"}, inner.Outer().Names())
+	t.Nil(outer.Outer())
+}
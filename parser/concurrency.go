@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"fmt"
+	"fungo/ast"
+	"fungo/token"
+)
+
+// spawn <call expression>
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	defer untrace(trace("parseSpawnExpression"))
+
+	expression := &ast.SpawnExpression{Token: p.currToken}
+
+	p.nextToken()
+
+	call, ok := p.parseExpression(PREFIX).(*ast.CallExpression)
+	if !ok {
+		p.addError("spawn expects a function call")
+		return nil
+	}
+
+	expression.Call = call
+
+	return expression
+}
+
+// select { recv(<channel>) as <name> => <body>, send(<channel>, <value>) => <body>, _ => <body> }
+func (p *Parser) parseSelectExpression() ast.Expression {
+	defer untrace(trace("parseSelectExpression"))
+
+	expression := &ast.SelectExpression{Token: p.currToken, Arms: []*ast.SelectArm{}}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	hasDefault := false
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		arm := &ast.SelectArm{}
+
+		if p.currTokenIs(token.IDENT) && p.currToken.Literal == "_" && p.peekTokenIs(token.ARROW) {
+			if hasDefault {
+				p.addError("duplicate default arm in select")
+				return nil
+			}
+
+			hasDefault = true
+		} else {
+			arm.Operation = p.parseSelectOperation()
+			if arm.Operation == nil {
+				return nil
+			}
+
+			if p.peekTokenIs(token.AS) {
+				if arm.Operation.Function.String() != "recv" {
+					p.addError("only recv arms of a select can bind a value")
+					return nil
+				}
+
+				p.nextToken()
+
+				if !p.expectPeek(token.IDENT) {
+					return nil
+				}
+
+				arm.Binding = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+			}
+		}
+
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+
+		arm.Body = p.parseArmBody()
+		expression.Arms = append(expression.Arms, arm)
+
+		if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return expression
+}
+
+func (p *Parser) parseSelectOperation() *ast.CallExpression {
+	expression := p.parseExpression(LOWEST)
+	if expression == nil {
+		return nil
+	}
+
+	call, ok := expression.(*ast.CallExpression)
+	if ok {
+		switch call.Function.String() {
+		case "recv":
+			ok = len(call.Arguments) == 1
+		case "send":
+			ok = len(call.Arguments) == 2
+		default:
+			ok = false
+		}
+	}
+
+	if !ok {
+		p.addError(fmt.Sprintf("select arms must be recv(<channel>) or send(<channel>, <value>), got %s", expression.String()))
+		return nil
+	}
+
+	return call
+}
+
+// async fn(<params>) { <body> }
+func (p *Parser) parseAsyncFunction() ast.Expression {
+	defer untrace(trace("parseAsyncFunction"))
+
+	if !p.expectPeek(token.FUNCTION) {
+		return nil
+	}
+
+	literal, ok := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+	if !ok {
+		return nil
+	}
+
+	if literal.IsGenerator {
+		p.addError("async functions cannot yield")
+		return nil
+	}
+
+	literal.IsAsync = true
+
+	return literal
+}
+
+// await <promise>
+func (p *Parser) parseAwaitExpression() ast.Expression {
+	defer untrace(trace("parseAwaitExpression"))
+
+	expression := &ast.AwaitExpression{Token: p.currToken}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(PREFIX)
+
+	return expression
+}
@@ -0,0 +1,287 @@
+package parser
+
+import (
+	"fmt"
+	"fungo/ast"
+	"fungo/token"
+	"strings"
+)
+
+func (p *Parser) parseEnumStatement() *ast.EnumStatement {
+	defer untrace(trace("parseEnumStatement"))
+
+	statement := &ast.EnumStatement{Token: p.currToken, Variants: []*ast.EnumVariant{}}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	statement.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	names := []string{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		variant := &ast.EnumVariant{
+			Name:   &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal},
+			Fields: []*ast.Identifier{},
+		}
+
+		for _, name := range names {
+			if name == variant.Name.Value {
+				p.addError(fmt.Sprintf("duplicate variant %s in enum %s", name, statement.Name.Value))
+				return nil
+			}
+		}
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+
+			for !p.peekTokenIs(token.RPAREN) {
+				if !p.expectPeek(token.IDENT) {
+					return nil
+				}
+
+				variant.Fields = append(variant.Fields, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+
+				if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+					return nil
+				}
+			}
+
+			p.nextToken()
+		}
+
+		statement.Variants = append(statement.Variants, variant)
+		names = append(names, variant.Name.Value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	p.enums[statement.Name.Value] = names
+	for _, name := range names {
+		p.variantEnums[name] = statement.Name.Value
+	}
+
+	return statement
+}
+
+// match (<subject>) { <pattern> => <expression or block>, ... }
+func (p *Parser) parseMatchExpression() ast.Expression {
+	defer untrace(trace("parseMatchExpression"))
+
+	expression := &ast.MatchExpression{Token: p.currToken, Arms: []*ast.MatchArm{}}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		arm := &ast.MatchArm{Pattern: p.parseMatchPattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+
+		arm.Body = p.parseArmBody()
+		expression.Arms = append(expression.Arms, arm)
+
+		if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	p.checkExhaustive(expression)
+
+	return expression
+}
+
+// The body after `=>` is either a block or a single expression, which is wrapped in a block
+func (p *Parser) parseArmBody() *ast.BlockStatement {
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		return p.parseBlockStatement()
+	}
+
+	p.nextToken()
+
+	statement := &ast.ExpressionStatement{Token: p.currToken}
+	statement.Expression = p.parseExpression(LOWEST)
+
+	return &ast.BlockStatement{Token: statement.Token, Statements: []ast.Statement{statement}}
+}
+
+// Patterns are `_`, a binding identifier, a literal or a variant with nested patterns: Ok(v), Result.Err(_), None
+func (p *Parser) parseMatchPattern() ast.Expression {
+	defer untrace(trace("parseMatchPattern"))
+
+	switch p.currToken.Type {
+	case token.INT:
+		return p.parseIntegerLiteral()
+	case token.STRING:
+		return p.parseStringLiteral()
+	case token.TRUE, token.FALSE:
+		return p.parseBoolean()
+	case token.IDENT:
+	default:
+		p.addError(fmt.Sprintf("expected match pattern, got %q instead", p.currToken.Type))
+		return nil
+	}
+
+	start := p.currToken
+	var name ast.Expression = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	for p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		member := &ast.MemberExpression{Token: p.currToken, Ref: name}
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		member.Property = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		name = member
+	}
+
+	if !p.peekTokenIs(token.LPAREN) {
+		return name
+	}
+
+	p.nextToken()
+
+	pattern := &ast.VariantPattern{Token: start, Variant: name, Fields: []ast.Expression{}}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+
+		field := p.parseMatchPattern()
+		if field == nil {
+			return nil
+		}
+		pattern.Fields = append(pattern.Fields, field)
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+
+	return pattern
+}
+
+// Returns the enum qualifier (empty when unqualified) and the variant a pattern refers to by name, if any
+func patternVariant(pattern ast.Expression) (string, string, bool) {
+	switch pattern := pattern.(type) {
+	case *ast.VariantPattern:
+		return patternVariant(pattern.Variant)
+	case *ast.MemberExpression:
+		return pattern.Ref.String(), pattern.Property.Value, true
+	case *ast.Identifier:
+		return "", pattern.Value, true
+	default:
+		return "", "", false
+	}
+}
+
+// Whether a pattern matches every value: `_` or an identifier that is not one of the known variants
+func (p *Parser) isIrrefutable(pattern ast.Expression) bool {
+	identifier, ok := pattern.(*ast.Identifier)
+	if !ok {
+		return false
+	}
+
+	_, isVariant := p.variantEnums[identifier.Value]
+
+	return !isVariant
+}
+
+// Warns when every arm matches variants of the same known enum but some of its variants are never matched
+func (p *Parser) checkExhaustive(expression *ast.MatchExpression) {
+	enum := ""
+	covered := make(map[string]bool)
+
+	for _, arm := range expression.Arms {
+		if p.isIrrefutable(arm.Pattern) {
+			return
+		}
+
+		qualifier, name, ok := patternVariant(arm.Pattern)
+		if !ok {
+			return
+		}
+
+		variantEnum, ok := p.variantEnums[name]
+		if !ok || (qualifier != "" && qualifier != variantEnum) || (enum != "" && enum != variantEnum) {
+			return
+		}
+		enum = variantEnum
+
+		// Arms with refutable nested patterns such as Ok(1) only cover part of the variant
+		fullyCovered := true
+		if variant, ok := arm.Pattern.(*ast.VariantPattern); ok {
+			for _, field := range variant.Fields {
+				fullyCovered = fullyCovered && p.isIrrefutable(field)
+			}
+		}
+
+		if fullyCovered {
+			covered[name] = true
+		}
+	}
+
+	if enum == "" {
+		return
+	}
+
+	missing := []string{}
+	for _, variant := range p.enums[enum] {
+		if !covered[variant] {
+			missing = append(missing, variant)
+		}
+	}
+
+	if len(missing) > 0 {
+		p.warnings = append(p.warnings, Warning{
+			Token:   expression.Token,
+			Message: fmt.Sprintf("non-exhaustive match on %s, missing %s", enum, strings.Join(missing, ", ")),
+		})
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"fungo/ast"
 	"fungo/lexer"
 	"fungo/token"
+	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	PIPELINE    // |>
 	EQUALS      // ==
 	LESSGREATER // > or <
 	SUM         // +
@@ -29,6 +32,7 @@ type (
 )
 
 var precendences = map[token.TokenType]int{
+	token.PIPE:     PIPELINE,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
@@ -39,12 +43,27 @@ var precendences = map[token.TokenType]int{
 	token.ASTERISK: PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
 }
 
 type Parser struct {
 	lexer *lexer.Lexer
 
-	errors []string
+	errors   []string
+	warnings []Warning
+
+	// Token the parser was at when it found each error, in the same order as errors
+	errorTokens []token.Token
+
+	// Variants of the enums declared so far, used to check that matches over them are exhaustive
+	enums        map[string][]string
+	variantEnums map[string]string
+
+	// Function literals whose bodies are being parsed, innermost last, so `yield` can mark them as generators
+	functions []*ast.FunctionLiteral
+
+	// Whether the `(` at a position opens the parameter list of an arrow function, for every `(` scanned so far
+	arrows map[position]bool
 
 	currToken token.Token
 	peekToken token.Token
@@ -57,6 +76,10 @@ func NewParser(l *lexer.Lexer) *Parser {
 	parser := &Parser{
 		lexer:          l,
 		errors:         []string{},
+		warnings:       []Warning{},
+		enums:          make(map[string][]string),
+		variantEnums:   make(map[string]string),
+		arrows:         make(map[position]bool),
 		prefixParseFns: make(map[token.TokenType]prefixParseFn),
 		infixParseFns:  make(map[token.TokenType]infixParseFn),
 	}
@@ -70,6 +93,15 @@ func NewParser(l *lexer.Lexer) *Parser {
 	parser.registerPrefix(token.FALSE, parser.parseBoolean)
 	parser.registerPrefix(token.LPAREN, parser.parseGroupExpression)
 	parser.registerPrefix(token.IF, parser.parseIfExpression)
+	parser.registerPrefix(token.TRY, parser.parseTryExpression)
+	parser.registerPrefix(token.MATCH, parser.parseMatchExpression)
+	parser.registerPrefix(token.FOR, parser.parseForExpression)
+	parser.registerPrefix(token.YIELD, parser.parseYieldExpression)
+	parser.registerPrefix(token.SPAWN, parser.parseSpawnExpression)
+	parser.registerPrefix(token.SELECT, parser.parseSelectExpression)
+	parser.registerPrefix(token.ASYNC, parser.parseAsyncFunction)
+	parser.registerPrefix(token.AWAIT, parser.parseAwaitExpression)
+	parser.registerPrefix(token.MACRO, parser.parseMacroLiteral)
 	parser.registerPrefix(token.FUNCTION, parser.parseFunctionLiteral)
 	parser.registerPrefix(token.STRING, parser.parseStringLiteral)
 	parser.registerPrefix(token.LBRACKET, parser.parseArrayLiteral)
@@ -86,6 +118,8 @@ func NewParser(l *lexer.Lexer) *Parser {
 	parser.registerInfix(token.GT, parser.parseInfixExpression)
 	parser.registerInfix(token.LPAREN, parser.parseCallExpression)
 	parser.registerInfix(token.LBRACKET, parser.parseIndexExpression)
+	parser.registerInfix(token.PIPE, parser.parsePipeExpression)
+	parser.registerInfix(token.DOT, parser.parseMemberExpression)
 
 	// Read two tokens, so currToken and peekToken are both set
 	parser.nextToken()
@@ -112,31 +146,83 @@ func (p Parser) Errors() []string {
 	return p.errors
 }
 
+// ErrorTokens returns the token each error was found at, in the same order as Errors, so tools can position them
+func (p Parser) ErrorTokens() []token.Token {
+	return p.errorTokens
+}
+
+func (p *Parser) addError(msg string) {
+	p.addErrorAt(p.currToken, msg)
+}
+
+func (p *Parser) addErrorAt(at token.Token, msg string) {
+	p.errors = append(p.errors, msg)
+	p.errorTokens = append(p.errorTokens, at)
+}
+
+// Warning is a problem that does not stop the program from running, such as a match missing variants of an enum
+type Warning struct {
+	Token   token.Token
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%d:%d: %s", w.Token.Line, w.Token.Column, w.Message)
+}
+
+func (p Parser) Warnings() []Warning {
+	return p.warnings
+}
+
+// Enums returns the variants of the enums declared so far, by the name of their enum
+func (p Parser) Enums() map[string][]string {
+	return p.enums
+}
+
+// DeclareEnums tells the parser about enums declared before its input, such as on the earlier lines of the REPL, so
+// that matches over them are checked too
+func (p *Parser) DeclareEnums(enums map[string][]string) {
+	for name, variants := range enums {
+		p.enums[name] = variants
+		for _, variant := range variants {
+			p.variantEnums[variant] = name
+		}
+	}
+}
+
 func (p *Parser) nextToken() {
 	p.currToken = p.peekToken
 	p.peekToken = p.lexer.NextToken()
 }
 
-func (p Parser) peekPrecedence() int {
-	if p, ok := precendences[p.peekToken.Type]; ok {
+// How tightly an infix operator binds its operands, LOWEST for tokens that are not infix operators
+func Precedence(t token.TokenType) int {
+	if p, ok := precendences[t]; ok {
 		return p
 	}
 
 	return LOWEST
 }
 
-func (p Parser) currPrecedence() int {
-	if p, ok := precendences[p.currToken.Type]; ok {
-		return p
-	}
+// Whether a token continues the expression before it, as an operator, a call or an index does
+func IsInfix(t token.TokenType) bool {
+	_, ok := precendences[t]
 
-	return LOWEST
+	return ok
+}
+
+func (p Parser) peekPrecedence() int {
+	return Precedence(p.peekToken.Type)
+}
+
+func (p Parser) currPrecedence() int {
+	return Precedence(p.currToken.Type)
 }
 
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %q, got %q instead", t, p.peekToken.Type)
 
-	p.errors = append(p.errors, msg)
+	p.addErrorAt(p.peekToken, msg)
 }
 
 func (p *Parser) peekTokenIs(t token.TokenType) bool {
@@ -167,16 +253,28 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %q found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(msg)
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
 	defer untrace(trace("parseIdentifier"))
 
-	return &ast.Identifier{
+	identifier := &ast.Identifier{
 		Token: p.currToken,
 		Value: p.currToken.Literal,
 	}
+
+	// Short lambda with a single parameter: x => x * 2
+	if p.peekTokenIs(token.ARROW) {
+		literal := newArrowFunction(p.currToken)
+		literal.Parameters = []ast.Expression{identifier}
+
+		p.nextToken()
+
+		return p.parseArrowFunctionBody(literal)
+	}
+
+	return identifier
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
@@ -197,19 +295,159 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return statement
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	defer untrace(trace("parseThrowStatement"))
+
+	statement := &ast.ThrowStatement{
+		Token: p.currToken,
+	}
+
+	p.nextToken()
+
+	statement.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	defer untrace(trace("parseImportStatement"))
+
+	statement := &ast.ImportStatement{Token: p.currToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	statement.Path = &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+
+	// Without `as`, the module is bound to its file name: import "lib/list.fg" binds `list`
+	if p.peekTokenIs(token.AS) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		statement.Alias = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	} else {
+		name := ModuleName(statement.Path.Value)
+		if !isIdentifier(name) {
+			p.addError(fmt.Sprintf("%q cannot be bound to %s, name it with `as`", statement.Path.Value, name))
+
+			// The statement is over, what follows is parsed as usual
+			if p.peekTokenIs(token.SEMICOLON) {
+				p.nextToken()
+			}
+
+			return nil
+		}
+
+		statement.Alias = &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseExportStatement() *ast.ExportStatement {
+	defer untrace(trace("parseExportStatement"))
+
+	statement := &ast.ExportStatement{Token: p.currToken}
+
+	if !p.expectPeek(token.LET) {
+		return nil
+	}
+
+	statement.Statement = p.parseLetStatement()
+	if statement.Statement == nil {
+		return nil
+	}
+
+	return statement
+}
+
+func (p *Parser) parseStructStatement() *ast.StructStatement {
+	defer untrace(trace("parseStructStatement"))
+
+	statement := &ast.StructStatement{Token: p.currToken, Fields: []*ast.Identifier{}}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	statement.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		if seen[p.currToken.Literal] {
+			p.addError(fmt.Sprintf("duplicate field %s in struct %s", p.currToken.Literal, statement.Name.Value))
+			return nil
+		}
+		seen[p.currToken.Literal] = true
+
+		statement.Fields = append(statement.Fields, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	defer untrace(trace("parseLetStatement"))
 
 	statement := &ast.LetStatement{Token: p.currToken}
 
-	if !p.expectPeek(token.IDENT) {
+	switch {
+	case p.peekTokenIs(token.LBRACKET), p.peekTokenIs(token.LBRACE):
+		p.nextToken()
+
+		statement.Pattern = p.parseBindingTarget()
+		if statement.Pattern == nil {
+			return nil
+		}
+
+	case p.expectPeek(token.IDENT):
+		statement.Name = &ast.Identifier{
+			Token: p.currToken,
+			Value: p.currToken.Literal,
+		}
+
+	default:
 		return nil
 	}
 
-	statement.Name = &ast.Identifier{
-		Token: p.currToken,
-		Value: p.currToken.Literal,
+	annotation, ok := p.parseOptionalType()
+	if !ok {
+		return nil
 	}
+	statement.Type = annotation
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -234,6 +472,16 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.ENUM:
+		return p.parseEnumStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -244,7 +492,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
 	if err != nil {
-		p.errors = append(p.errors, fmt.Sprintf("could not parse %q as integer", p.currToken.Literal))
+		p.addError(fmt.Sprintf("could not parse %q as integer", p.currToken.Literal))
 		return nil
 	}
 
@@ -334,6 +582,10 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) parseGroupExpression() ast.Expression {
+	if p.isArrowFunctionAhead() {
+		return p.parseArrowFunction()
+	}
+
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
@@ -378,6 +630,117 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// try { <block> } catch (<target>) { <block> } finally { <block> }
+func (p *Parser) parseTryExpression() ast.Expression {
+	defer untrace(trace("parseTryExpression"))
+
+	expression := &ast.TryExpression{Token: p.currToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		// The parameter is optional: `catch { ... }` ignores the error value
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			p.nextToken()
+
+			expression.CatchParameter = p.parseBindingTarget()
+			if expression.CatchParameter == nil {
+				return nil
+			}
+
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.CatchBlock = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.FinallyBlock = p.parseBlockStatement()
+	}
+
+	if expression.CatchBlock == nil && expression.FinallyBlock == nil {
+		p.addError("expected catch or finally after try block")
+		return nil
+	}
+
+	return expression
+}
+
+// for (<target> in <iterable>) { <body> }
+func (p *Parser) parseForExpression() ast.Expression {
+	defer untrace(trace("parseForExpression"))
+
+	expression := &ast.ForExpression{Token: p.currToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	expression.Target = p.parseBindingTarget()
+	if expression.Target == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	return expression
+}
+
+// yield <value>, turning the enclosing function into a generator
+func (p *Parser) parseYieldExpression() ast.Expression {
+	defer untrace(trace("parseYieldExpression"))
+
+	expression := &ast.YieldExpression{Token: p.currToken}
+
+	if len(p.functions) == 0 {
+		p.addError("yield outside of a function")
+		return nil
+	}
+
+	p.functions[len(p.functions)-1].IsGenerator = true
+
+	p.nextToken()
+	expression.Value = p.parseExpression(LOWEST)
+
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.currToken}
 	block.Statements = []ast.Statement{}
@@ -391,6 +754,8 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		p.nextToken()
 	}
 
+	block.Rbrace = p.currToken
+
 	return block
 }
 
@@ -401,7 +766,53 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	literal.Parameters = p.parseFunctionParameters()
+	literal.Parameters, literal.ParameterTypes = p.parseFunctionParameters()
+	if literal.Parameters == nil {
+		return nil
+	}
+
+	result, ok := p.parseOptionalType()
+	if !ok {
+		return nil
+	}
+	literal.ReturnType = result
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.functions = append(p.functions, literal)
+	literal.Body = p.parseBlockStatement()
+	p.functions = p.functions[:len(p.functions)-1]
+
+	return literal
+}
+
+// macro(<identifiers>) { <body> }
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	defer untrace(trace("parseMacroLiteral"))
+
+	literal := &ast.MacroLiteral{Token: p.currToken, Parameters: []*ast.Identifier{}}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	params, types := p.parseFunctionParameters()
+	if types != nil {
+		p.addError("macro parameters cannot have types")
+		return nil
+	}
+
+	for _, param := range params {
+		identifier, ok := param.(*ast.Identifier)
+		if !ok {
+			p.addError(fmt.Sprintf("macro parameters must be identifiers, got %s", param.String()))
+			return nil
+		}
+
+		literal.Parameters = append(literal.Parameters, identifier)
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -412,6 +823,129 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return literal
 }
 
+type position struct {
+	line   int
+	column int
+}
+
+// Scans ahead on a copy of the lexer to find out whether the `(` at currToken opens the parameter list of an
+// arrow function, i.e. whether its matching `)` is followed by `=>`. The answer for every `(` nested in it is
+// remembered along the way, so nested parentheses are only scanned once
+func (p *Parser) isArrowFunctionAhead() bool {
+	start := position{p.currToken.Line, p.currToken.Column}
+	if arrow, ok := p.arrows[start]; ok {
+		return arrow
+	}
+
+	lexer := *p.lexer
+	next := p.peekToken
+	open := []position{start}
+
+	for len(open) > 0 {
+		switch next.Type {
+		case token.LPAREN:
+			open = append(open, position{next.Line, next.Column})
+
+		case token.RPAREN:
+			closed := open[len(open)-1]
+			open = open[:len(open)-1]
+
+			next = lexer.NextToken()
+			p.arrows[closed] = next.Type == token.ARROW
+
+			continue
+
+		case token.EOF:
+			for _, unclosed := range open {
+				p.arrows[unclosed] = false
+			}
+
+			return false
+		}
+
+		next = lexer.NextToken()
+	}
+
+	return p.arrows[start]
+}
+
+// Arrow functions desugar to a regular function literal, so they are given a `fn` token at the position of `start`
+func arrowFunctionToken(start token.Token) token.Token {
+	return token.Token{Type: token.FUNCTION, Literal: "fn", Line: start.Line, Column: start.Column}
+}
+
+func newArrowFunction(start token.Token) *ast.FunctionLiteral {
+	return &ast.FunctionLiteral{Token: arrowFunctionToken(start), IsArrow: true}
+}
+
+// (<parameters>) => <body>
+func (p *Parser) parseArrowFunction() ast.Expression {
+	defer untrace(trace("parseArrowFunction"))
+
+	literal := newArrowFunction(p.currToken)
+
+	literal.Parameters, literal.ParameterTypes = p.parseFunctionParameters()
+	if literal.Parameters == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	return p.parseArrowFunctionBody(literal)
+}
+
+// Parses what follows `=>`, either a block or a single expression that becomes the block's only statement
+func (p *Parser) parseArrowFunctionBody(literal *ast.FunctionLiteral) ast.Expression {
+	p.functions = append(p.functions, literal)
+	defer func() { p.functions = p.functions[:len(p.functions)-1] }()
+
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		literal.Body = p.parseBlockStatement()
+
+		return literal
+	}
+
+	p.nextToken()
+
+	statement := &ast.ExpressionStatement{Token: p.currToken}
+	statement.Expression = p.parseExpression(LOWEST)
+
+	literal.Body = &ast.BlockStatement{Token: p.currToken, Statements: []ast.Statement{statement}}
+
+	return literal
+}
+
+// <expression>.<identifier>
+func (p *Parser) parseMemberExpression(ref ast.Expression) ast.Expression {
+	defer untrace(trace("parseMemberExpression"))
+
+	expression := &ast.MemberExpression{Token: p.currToken, Ref: ref}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	expression.Property = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	return expression
+}
+
+// <expression> |> <call or function>
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	defer untrace(trace("parsePipeExpression"))
+
+	expression := &ast.PipeExpression{Token: p.currToken, Left: left}
+
+	precendence := p.currPrecedence()
+	p.nextToken()
+	expression.Right = p.parseExpression(precendence)
+
+	return expression
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{
 		Token: p.currToken,
@@ -419,36 +953,219 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	}
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// Returns the parameters up to the closing `)`, along with their annotations when at least one has a type
+func (p *Parser) parseFunctionParameters() ([]ast.Expression, []*ast.TypeAnnotation) {
+	params := []ast.Expression{}
+	types := []*ast.TypeAnnotation{}
+	annotated := false
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return params, nil
 	}
 
-	p.nextToken()
+	for {
+		p.nextToken()
 
-	identifiers = append(identifiers, &ast.Identifier{
-		Token: p.currToken,
-		Value: p.currToken.Literal,
-	})
+		param, annotation := p.parseFunctionParameter(params)
+		if param == nil {
+			return nil, nil
+		}
+		params = append(params, param)
+		types = append(types, annotation)
+		annotated = annotated || annotation != nil
 
-	for p.peekTokenIs(token.COMMA) {
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+
+	if !annotated {
+		return params, nil
+	}
+
+	return params, types
+}
+
+// Parses a single parameter: `<target>`, `<target> = <default>` or `...<identifier>`, each optionally annotated
+// with `: <type>` right after the target
+func (p *Parser) parseFunctionParameter(previous []ast.Expression) (ast.Expression, *ast.TypeAnnotation) {
+	defer untrace(trace("parseFunctionParameter"))
+
+	if len(previous) > 0 {
+		if _, ok := previous[len(previous)-1].(*ast.RestElement); ok {
+			p.addError("rest parameter must be the last parameter")
+			return nil, nil
+		}
+	}
+
+	if p.currTokenIs(token.ELLIPSIS) {
+		rest := p.parseRestElement()
+		if rest == nil {
+			return nil, nil
+		}
+
+		annotation, ok := p.parseOptionalType()
+		if !ok {
+			return nil, nil
+		}
+
+		return rest, annotation
+	}
+
+	target := p.parseBindingTarget()
+	if target == nil {
+		return nil, nil
+	}
+
+	annotation, ok := p.parseOptionalType()
+	if !ok {
+		return nil, nil
+	}
+
+	if p.peekTokenIs(token.ASSIGN) {
 		p.nextToken()
 
-		identifiers = append(identifiers, &ast.Identifier{
-			Token: p.currToken,
-			Value: p.currToken.Literal,
-		})
+		param := &ast.DefaultParameter{Token: p.currToken, Target: target}
+
+		p.nextToken()
+		if _, ok := p.prefixParseFns[p.currToken.Type]; !ok {
+			p.addError(fmt.Sprintf("missing default value of parameter %s", target.String()))
+			return nil, nil
+		}
+
+		param.Value = p.parseExpression(LOWEST)
+		if param.Value == nil {
+			return nil, nil
+		}
+
+		return param, annotation
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	for _, prev := range previous {
+		if _, ok := prev.(*ast.DefaultParameter); ok {
+			p.addError(fmt.Sprintf("parameter %s without a default follows a parameter with one", target.String()))
+			return nil, nil
+		}
+	}
+
+	return target, annotation
+}
+
+// Parses the left hand side of a binding: an identifier, `[a, b, ...rest]` or `{a, b: c, ...rest}`
+func (p *Parser) parseBindingTarget() ast.Expression {
+	defer untrace(trace("parseBindingTarget"))
+
+	switch p.currToken.Type {
+	case token.IDENT:
+		return &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	default:
+		p.addError(fmt.Sprintf("expected binding target, got %q instead", p.currToken.Type))
 		return nil
 	}
+}
+
+func (p *Parser) parseRestElement() *ast.RestElement {
+	rest := &ast.RestElement{Token: p.currToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	rest.Target = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 
-	return identifiers
+	return rest
+}
+
+func (p *Parser) parseArrayPattern() ast.Expression {
+	pattern := &ast.ArrayPattern{Token: p.currToken, Elements: []ast.Expression{}}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.currTokenIs(token.ELLIPSIS) {
+			pattern.Rest = p.parseRestElement()
+			if pattern.Rest == nil {
+				return nil
+			}
+
+			// The rest element has to be the last one
+			break
+		}
+
+		element := p.parseBindingTarget()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Expression {
+	pattern := &ast.HashPattern{Token: p.currToken, Properties: []*ast.HashPatternProperty{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		if p.currTokenIs(token.ELLIPSIS) {
+			pattern.Rest = p.parseRestElement()
+			if pattern.Rest == nil {
+				return nil
+			}
+
+			// The rest element has to be the last one
+			break
+		}
+
+		if !p.currTokenIs(token.IDENT) {
+			p.addError(fmt.Sprintf("expected hash pattern key to be %q, got %q instead", token.IDENT, p.currToken.Type))
+			return nil
+		}
+
+		key := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		property := &ast.HashPatternProperty{Key: key, Value: key}
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+
+			property.Value = p.parseBindingTarget()
+			if property.Value == nil {
+				return nil
+			}
+		}
+
+		pattern.Properties = append(pattern.Properties, property)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return pattern
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
@@ -494,12 +1211,12 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseListElement())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseListElement())
 	}
 
 	if !p.expectPeek(end) {
@@ -509,6 +1226,26 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+// Parses an element of an array literal or argument list, which may be spread with `...<expression>`
+func (p *Parser) parseListElement() ast.Expression {
+	if p.currTokenIs(token.ELLIPSIS) {
+		return p.parseSpreadElement()
+	}
+
+	return p.parseExpression(LOWEST)
+}
+
+func (p *Parser) parseSpreadElement() ast.Expression {
+	defer untrace(trace("parseSpreadElement"))
+
+	spread := &ast.SpreadElement{Token: p.currToken}
+
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+
+	return spread
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.currToken}
 
@@ -539,6 +1276,16 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 
+		if p.currTokenIs(token.ELLIPSIS) {
+			hash.Keys = append(hash.Keys, p.parseSpreadElement())
+
+			if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+
+			continue
+		}
+
 		key := p.parseExpression(LOWEST)
 
 		if !p.expectPeek(token.COLON) {
@@ -549,6 +1296,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		value := p.parseExpression(LOWEST)
 
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -561,3 +1309,19 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 	return hash
 }
+
+// The default binding of an imported module: its file name without directories or extension
+func ModuleName(path string) string {
+	name := filepath.Base(path)
+
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// Whether a name lexes as a single identifier, one that is not a keyword
+func isIdentifier(name string) bool {
+	l := lexer.NewLexer(name)
+
+	tok := l.NextToken()
+
+	return tok.Type == token.IDENT && tok.Literal == name && l.NextToken().Type == token.EOF
+}
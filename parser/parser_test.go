@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"fungo/ast"
 	"fungo/lexer"
+	"fungo/token"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -544,3 +546,883 @@ func (t *ParserTestSuite) TestParsingEmptyHashLiteral() {
 
 	t.Len(result.Pairs, 0)
 }
+
+func (t *ParserTestSuite) TestDestructuringLetStatements() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = arr;", "let [a, b] = arr;"},
+		{"let [a, b, ..rest] = arr;", "let [a, b, ...rest] = arr;"},
+		{"let [a, [b, c]] = arr;", "let [a, [b, c]] = arr;"},
+		{"let {name, age} = person;", "let {name, age} = person;"},
+		{"let {name: n, ...others} = person;", "let {name: n, ...others} = person;"},
+		{"let {tags: [first, ...more]} = post;", "let {tags: [first, ...more]} = post;"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+		t.Len(program.Statements, 1)
+
+		statement, ok := program.Statements[0].(*ast.LetStatement)
+		t.True(ok, "*ast.LetStatement")
+
+		t.Nil(statement.Name)
+		t.NotNil(statement.Pattern)
+		t.Equal(test.expected, program.String())
+	}
+}
+
+func (t *ParserTestSuite) TestDestructuringFunctionParameters() {
+	input := `fn([a, b], {name}, c) { a };`
+
+	parser := NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+	t.Empty(parser.Errors())
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	t.True(ok, "*ast.ExpressionStatement")
+
+	function, ok := statement.Expression.(*ast.FunctionLiteral)
+	t.True(ok, "*ast.FunctionLiteral")
+	t.Len(function.Parameters, 3)
+
+	_, ok = function.Parameters[0].(*ast.ArrayPattern)
+	t.True(ok, "*ast.ArrayPattern")
+
+	_, ok = function.Parameters[1].(*ast.HashPattern)
+	t.True(ok, "*ast.HashPattern")
+
+	t.testIdentifier(function.Parameters[2], "c")
+}
+
+func (t *ParserTestSuite) TestDestructuringErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [1] = arr;", `expected binding target, got "INT" instead`},
+		{`let {"name"} = person;`, `expected hash pattern key to be "IDENT", got "STRING" instead`},
+		{"let [...1] = arr;", `expected next token to be "IDENT", got "INT" instead`},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		parser.ParseProgram()
+
+		t.NotEmpty(parser.Errors())
+		t.Equal(test.expected, parser.Errors()[0])
+	}
+}
+
+func (t *ParserTestSuite) TestDefaultAndRestParameters() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x, y = 10) { x }", "fn(x, y = 10)x"},
+		{"fn(first, ...others) { first }", "fn(first, ...others)first"},
+		{"fn(x = 1 + 2, ...rest) { x }", "fn(x = (1 + 2), ...rest)x"},
+		{"fn([a, b] = [1, 2]) { a }", "fn([a, b] = [1, 2])a"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+		t.Equal(test.expected, program.String())
+	}
+}
+
+func (t *ParserTestSuite) TestInvalidParameters() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(...rest, x) { x }", "rest parameter must be the last parameter"},
+		{"fn(x = 1, y) { x }", "parameter y without a default follows a parameter with one"},
+		{"fn(x = ) { x }", "missing default value of parameter x"},
+		{"fn({a, b} = , c) { a }", "missing default value of parameter {a, b}"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		parser.ParseProgram()
+
+		t.NotEmpty(parser.Errors())
+		t.Equal(test.expected, parser.Errors()[0])
+	}
+}
+
+func (t *ParserTestSuite) TestParsingSpreadElements() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[...a, ...b]", "[...a, ...b]"},
+		{"[1, ...a, 2]", "[1, ...a, 2]"},
+		{"f(...args)", "f(...args)"},
+		{"f(x, ...rest(xs))", "f(x, ...rest(xs))"},
+		{`{...defaults, ...overrides}`, "{...defaults, ...overrides}"},
+		{`{"a": 1, ...b, "c": 2}`, "{a:1, ...b, c:2}"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+		t.Equal(test.expected, program.String())
+	}
+}
+
+func (t *ParserTestSuite) TestParsingPipeExpressions() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"xs |> f", "(xs |> f)"},
+		{"xs |> filter(f) |> map(g)", "((xs |> filter(f)) |> map(g))"},
+		{"a + b |> f(c)", "((a + b) |> f(c))"},
+		{"a == b |> f", "((a == b) |> f)"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+		t.Equal(test.expected, program.String())
+	}
+}
+
+func (t *ParserTestSuite) TestParsingArrowFunctions() {
+	tests := []struct {
+		input    string
+		params   []string
+		expected string
+	}{
+		{"x => x * 2", []string{"x"}, "fn(x)(x * 2)"},
+		{"(a, b) => a + b", []string{"a", "b"}, "fn(a, b)(a + b)"},
+		{"() => 1", []string{}, "fn()1"},
+		{"(x, y = 1) => { x + y }", []string{"x", "y = 1"}, "fn(x, y = 1)(x + y)"},
+		{"([a, b]) => a", []string{"[a, b]"}, "fn([a, b])a"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+		t.Empty(parser.Errors())
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		t.True(ok, "*ast.ExpressionStatement")
+
+		function, ok := statement.Expression.(*ast.FunctionLiteral)
+		t.True(ok, "*ast.FunctionLiteral")
+
+		params := []string{}
+		for _, param := range function.Parameters {
+			params = append(params, param.String())
+		}
+
+		t.Equal(test.params, params)
+		t.Equal(test.expected, function.String())
+		t.True(function.IsArrow)
+	}
+}
+
+func (t *ParserTestSuite) TestBlockPositions() {
+	parser := NewParser(lexer.NewLexer("let f = fn(x) {\n  x\n};\nlet g = x => x"))
+	program := parser.ParseProgram()
+	t.Empty(parser.Errors())
+
+	f := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	t.False(f.IsArrow)
+	t.Equal(token.Token{Type: token.RBRACE, Literal: "}", Line: 3, Column: 1}, f.Body.Rbrace)
+
+	// Arrow functions with an expression body have no braces
+	g := program.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	t.Equal(token.Token{}, g.Body.Rbrace)
+}
+
+func (t *ParserTestSuite) TestParsingArrowFunctionsInExpressions() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"map(xs, x => x * 2)", "map(xs, fn(x)(x * 2))"},
+		{"xs |> map((a) => a + 1)", "(xs |> map(fn(a)(a + 1)))"},
+		{"(a + b) * c", "((a + b) * c)"},
+		{"(f(a)) + 1", "(f(a) + 1)"},
+		{"((a) => ((b) => (a + b)))((1))", "fn(a)fn(b)(a + b)(1)"},
+		{"((x) + ((y) => y)(1))", "(x + fn(y)y(1))"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+		t.Equal(test.expected, program.String())
+	}
+}
+
+func (t *ParserTestSuite) TestParsingDeeplyNestedGroups() {
+	// Each ( is scanned once, nested ones are answered from the scan of the outermost
+	depth := 20000
+	input := strings.Repeat("(", depth) + "1" + strings.Repeat(")", depth)
+
+	parser := NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+
+	t.Empty(parser.Errors())
+	t.Equal("1", program.String())
+	t.Len(parser.arrows, depth)
+}
+
+func (t *ParserTestSuite) TestParsingThrowStatement() {
+	parser := NewParser(lexer.NewLexer(`throw "boom";`))
+	program := parser.ParseProgram()
+
+	t.Empty(parser.Errors())
+	t.Len(program.Statements, 1)
+
+	statement, ok := program.Statements[0].(*ast.ThrowStatement)
+	t.True(ok, "*ast.ThrowStatement")
+	t.Equal("boom", statement.Value.String())
+}
+
+func (t *ParserTestSuite) TestParsingTryExpressions() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { a } catch (e) { b }", "try a catch(e) b"},
+		{"try { a } catch { b }", "try a catch b"},
+		{"try { a } finally { c }", "try a finally c"},
+		{"try { a } catch ({message}) { b } finally { c }", "try a catch({message}) b finally c"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+		t.Empty(parser.Errors())
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		t.True(ok, "*ast.ExpressionStatement")
+
+		_, ok = statement.Expression.(*ast.TryExpression)
+		t.True(ok, "*ast.TryExpression")
+
+		t.Equal(test.expected, program.String())
+	}
+}
+
+func (t *ParserTestSuite) TestParsingTryWithoutHandler() {
+	parser := NewParser(lexer.NewLexer("try { a }"))
+	parser.ParseProgram()
+
+	t.Equal([]string{"expected catch or finally after try block"}, parser.Errors())
+}
+
+func (t *ParserTestSuite) TestParsingImportStatements() {
+	tests := []struct {
+		input         string
+		expectedPath  string
+		expectedAlias string
+	}{
+		{`import "path/to/lib.fg" as lib;`, "path/to/lib.fg", "lib"},
+		{`import "./helpers.fg"`, "./helpers.fg", "helpers"},
+		{`import "collections/list"`, "collections/list", "list"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+		t.Len(program.Statements, 1)
+
+		statement, ok := program.Statements[0].(*ast.ImportStatement)
+		t.True(ok, "*ast.ImportStatement")
+
+		t.Equal(test.expectedPath, statement.Path.Value)
+		t.Equal(test.expectedAlias, statement.Alias.Value)
+	}
+}
+
+func (t *ParserTestSuite) TestParsingImportsWithoutName() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "./my-lib.fg"`, "\"./my-lib.fg\" cannot be bound to my-lib, name it with `as`"},
+		{`import "lib/1util"`, "\"lib/1util\" cannot be bound to 1util, name it with `as`"},
+		{`import "./fn.fg"`, "\"./fn.fg\" cannot be bound to fn, name it with `as`"},
+		{`import "./my-lib.fg";`, "\"./my-lib.fg\" cannot be bound to my-lib, name it with `as`"},
+		{`import "./my-lib.fg"; let x = 1; x`, "\"./my-lib.fg\" cannot be bound to my-lib, name it with `as`"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		parser.ParseProgram()
+
+		t.Equal([]string{test.expected}, parser.Errors())
+	}
+
+	parser := NewParser(lexer.NewLexer(`import "./my-lib.fg" as lib`))
+	parser.ParseProgram()
+	t.Empty(parser.Errors())
+}
+
+func (t *ParserTestSuite) TestParsingExportStatements() {
+	parser := NewParser(lexer.NewLexer(`export let double = fn(x) { x * 2 }; export let [a, b] = pair;`))
+	program := parser.ParseProgram()
+
+	t.Empty(parser.Errors())
+	t.Len(program.Statements, 2)
+
+	statement, ok := program.Statements[0].(*ast.ExportStatement)
+	t.True(ok, "*ast.ExportStatement")
+	t.testLetStatement(statement.Statement, "double")
+
+	t.Equal("export let [a, b] = pair;", program.Statements[1].String())
+
+	parser = NewParser(lexer.NewLexer(`export 5;`))
+	parser.ParseProgram()
+	t.Equal([]string{`expected next token to be "LET", got "INT" instead`}, parser.Errors()[:1])
+}
+
+func (t *ParserTestSuite) TestParsingMemberExpressions() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"lib.map", "(lib.map)"},
+		{"lib.map(xs, f)", "(lib.map)(xs, f)"},
+		{"a.b.c", "((a.b).c)"},
+		{"a.b[1] + 2", "(((a.b)[1]) + 2)"},
+		{"-a.b", "(-(a.b))"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+		t.Equal(test.expected, program.String())
+	}
+}
+
+func (t *ParserTestSuite) TestParsingStructStatements() {
+	tests := []struct {
+		input          string
+		expectedName   string
+		expectedFields []string
+	}{
+		{"struct Point { x, y }", "Point", []string{"x", "y"}},
+		{"struct Empty {};", "Empty", []string{}},
+		{"struct User { name, age, }", "User", []string{"name", "age"}},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+		t.Len(program.Statements, 1)
+
+		statement, ok := program.Statements[0].(*ast.StructStatement)
+		t.True(ok, "*ast.StructStatement")
+
+		t.Equal(test.expectedName, statement.Name.Value)
+		t.Equal(len(test.expectedFields), len(statement.Fields))
+
+		for i, field := range test.expectedFields {
+			t.testIdentifier(statement.Fields[i], field)
+		}
+	}
+}
+
+func (t *ParserTestSuite) TestParsingStructErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, x }", "duplicate field x in struct Point"},
+		{"struct { x }", `expected next token to be "IDENT", got "{" instead`},
+		{`struct Point { "x" }`, `expected next token to be "IDENT", got "STRING" instead`},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		parser.ParseProgram()
+
+		t.NotEmpty(parser.Errors())
+		t.Equal(test.expected, parser.Errors()[0])
+	}
+}
+
+func (t *ParserTestSuite) TestParsingEnumStatements() {
+	input := `enum Result { Ok(value), Err(message, code), Pending }`
+
+	parser := NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+
+	t.Empty(parser.Errors())
+	t.Len(program.Statements, 1)
+
+	statement, ok := program.Statements[0].(*ast.EnumStatement)
+	t.True(ok, "*ast.EnumStatement")
+
+	t.Equal("Result", statement.Name.Value)
+	t.Len(statement.Variants, 3)
+	t.Equal("enum Result { Ok(value), Err(message, code), Pending }", statement.String())
+
+	parser = NewParser(lexer.NewLexer(`enum Option { Some(v), Some(w) }`))
+	parser.ParseProgram()
+	t.Equal("duplicate variant Some in enum Option", parser.Errors()[0])
+}
+
+func (t *ParserTestSuite) TestParsingMatchExpressions() {
+	input := `
+match (r) {
+  Ok(v) => v + 1,
+  Result.Err(_, 404) => { "not found" }
+  None => 0,
+  "text" => 1
+  x => x
+}`
+
+	parser := NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+	t.Empty(parser.Errors())
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	t.True(ok, "*ast.ExpressionStatement")
+
+	match, ok := statement.Expression.(*ast.MatchExpression)
+	t.True(ok, "*ast.MatchExpression")
+
+	t.testIdentifier(match.Subject, "r")
+	t.Len(match.Arms, 5)
+
+	variant, ok := match.Arms[0].Pattern.(*ast.VariantPattern)
+	t.True(ok, "*ast.VariantPattern")
+	t.testIdentifier(variant.Variant, "Ok")
+	t.testIdentifier(variant.Fields[0], "v")
+
+	variant, ok = match.Arms[1].Pattern.(*ast.VariantPattern)
+	t.True(ok, "*ast.VariantPattern")
+	t.Equal("(Result.Err)", variant.Variant.String())
+	t.testIntegerLiteral(variant.Fields[1], 404)
+
+	t.testIdentifier(match.Arms[2].Pattern, "None")
+	t.testIdentifier(match.Arms[4].Pattern, "x")
+}
+
+func (t *ParserTestSuite) TestMatchExhaustivenessWarnings() {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"enum Result { Ok(v), Err(m) }\nmatch (r) { Ok(v) => v }",
+			[]string{"2:1: non-exhaustive match on Result, missing Err"},
+		},
+		{
+			"enum Color { Red, Green, Blue }\nlet c = match (x) { Red => 1, Color.Green => 2 };",
+			[]string{"2:9: non-exhaustive match on Color, missing Blue"},
+		},
+		{
+			"enum Result { Ok(v), Err(m) }\nmatch (r) { Ok(1) => 1, Err(m) => 2 }",
+			[]string{"2:1: non-exhaustive match on Result, missing Ok"},
+		},
+		{"enum Result { Ok(v), Err(m) }\nmatch (r) { Ok(v) => v, Err(m) => 0 }", []string{}},
+		{"enum Result { Ok(v), Err(m) }\nmatch (r) { Ok(v) => v, _ => 0 }", []string{}},
+		{"enum Result { Ok(v), Err(m) }\nmatch (r) { Ok(v) => v, other => 0 }", []string{}},
+		{"match (r) { Ok(v) => v }", []string{}},
+		{"enum Result { Ok(v), Err(m) }\nmatch (r) { 1 => 1 }", []string{}},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+
+		warnings := []string{}
+		for _, warning := range parser.Warnings() {
+			warnings = append(warnings, warning.String())
+		}
+		t.Equal(test.expected, warnings)
+	}
+
+	// Enums declared before the input are checked as well
+	first := NewParser(lexer.NewLexer("enum Result { Ok(v), Err(m) }"))
+	first.ParseProgram()
+
+	second := NewParser(lexer.NewLexer("match (r) { Ok(v) => v }"))
+	second.DeclareEnums(first.Enums())
+	second.ParseProgram()
+
+	t.Require().Len(second.Warnings(), 1)
+	t.Equal("non-exhaustive match on Result, missing Err", second.Warnings()[0].Message)
+	t.Equal(1, second.Warnings()[0].Token.Column)
+}
+
+func (t *ParserTestSuite) TestParsingForExpressions() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (x in xs) { x }", "for (x in xs) x"},
+		{"for ([k, v] in zip(a, b)) { k + v }", "for ([k, v] in zip(a, b)) (k + v)"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+		t.Len(program.Statements, 1)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		_, ok := statement.Expression.(*ast.ForExpression)
+		t.True(ok, "*ast.ForExpression")
+		t.Equal(test.expected, statement.String())
+	}
+}
+
+func (t *ParserTestSuite) TestParsingGenerators() {
+	tests := []struct {
+		input     string
+		generator bool
+	}{
+		{"fn() { yield 1; yield 2 }", true},
+		{"fn(xs) { for (x in xs) { yield x * 2 } }", true},
+		{"x => yield x", true},
+		{"fn() { 1 }", false},
+		{"fn() { fn() { yield 1 } }", false},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := statement.Expression.(*ast.FunctionLiteral)
+		t.True(ok, "*ast.FunctionLiteral")
+		t.Equal(test.generator, function.IsGenerator, test.input)
+	}
+
+	parser := NewParser(lexer.NewLexer("yield 1"))
+	parser.ParseProgram()
+	t.Equal("yield outside of a function", parser.Errors()[0])
+}
+
+func (t *ParserTestSuite) TestParsingSpawnExpressions() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"spawn worker(1, c)", "(spawn worker(1, c))"},
+		{"spawn c.send(1)", "(spawn (c.send)(1))"},
+		{"spawn fn(x) { x }(2)", "(spawn fn(x)x(2))"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		_, ok := statement.Expression.(*ast.SpawnExpression)
+		t.True(ok, "*ast.SpawnExpression")
+		t.Equal(test.expected, statement.String())
+	}
+
+	parser := NewParser(lexer.NewLexer("spawn f"))
+	parser.ParseProgram()
+	t.Equal("spawn expects a function call", parser.Errors()[0])
+}
+
+func (t *ParserTestSuite) TestParsingSelectExpressions() {
+	input := `
+select {
+  recv(a) as v => v,
+  recv(b) => { 2 },
+  send(c, 3) => 3,
+  _ => 0
+}`
+
+	parser := NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+
+	t.Empty(parser.Errors())
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	selectExp, ok := statement.Expression.(*ast.SelectExpression)
+	t.True(ok, "*ast.SelectExpression")
+	t.Len(selectExp.Arms, 4)
+
+	t.Equal("recv(a)", selectExp.Arms[0].Operation.String())
+	t.testIdentifier(selectExp.Arms[0].Binding, "v")
+	t.Nil(selectExp.Arms[1].Binding)
+	t.Equal("send(c, 3)", selectExp.Arms[2].Operation.String())
+	t.Nil(selectExp.Arms[3].Operation)
+	t.Equal("select { recv(a) as v => v, recv(b) => 2, send(c, 3) => 3, _ => 0 }", selectExp.String())
+}
+
+func (t *ParserTestSuite) TestParsingSelectErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"select { f(a) => 1 }", "select arms must be recv(<channel>) or send(<channel>, <value>), got f(a)"},
+		{"select { recv(a, b) => 1 }", "select arms must be recv(<channel>) or send(<channel>, <value>), got recv(a, b)"},
+		{"select { send(a, 1) as x => 1 }", "only recv arms of a select can bind a value"},
+		{"select { _ => 1, _ => 2 }", "duplicate default arm in select"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		parser.ParseProgram()
+
+		t.NotEmpty(parser.Errors())
+		t.Equal(test.expected, parser.Errors()[0])
+	}
+}
+
+func (t *ParserTestSuite) TestParsingAsyncFunctions() {
+	tests := []struct {
+		input    string
+		expected string
+		async    bool
+	}{
+		{"async fn(x) { await f(x) }", "async fn(x)(await f(x))", true},
+		{"fn(x) { await x }", "fn(x)(await x)", false},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors())
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := statement.Expression.(*ast.FunctionLiteral)
+		t.True(ok, "*ast.FunctionLiteral")
+		t.Equal(test.async, function.IsAsync)
+		t.Equal(test.expected, function.String())
+	}
+
+	parser := NewParser(lexer.NewLexer("await sleep(1) + 1"))
+	program := parser.ParseProgram()
+	t.Empty(parser.Errors())
+	t.Equal("((await sleep(1)) + 1)", program.String())
+
+	parser = NewParser(lexer.NewLexer("async fn() { yield 1 }"))
+	parser.ParseProgram()
+	t.Equal("async functions cannot yield", parser.Errors()[0])
+
+	parser = NewParser(lexer.NewLexer("async x"))
+	parser.ParseProgram()
+	t.Equal(`expected next token to be "FUNCTION", got "IDENT" instead`, parser.Errors()[0])
+}
+
+func (t *ParserTestSuite) TestParsingMacroLiterals() {
+	input := `macro(x, y) { x + y; }`
+
+	parser := NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+
+	t.Empty(parser.Errors())
+	t.Len(program.Statements, 1)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	macro, ok := statement.Expression.(*ast.MacroLiteral)
+	t.True(ok, "*ast.MacroLiteral")
+
+	t.Len(macro.Parameters, 2)
+	t.testIdentifier(macro.Parameters[0], "x")
+	t.testIdentifier(macro.Parameters[1], "y")
+	t.Equal("(x + y)", macro.Body.String())
+
+	parser = NewParser(lexer.NewLexer(`macro(x = 1) { x }`))
+	parser.ParseProgram()
+	t.Equal("macro parameters must be identifiers, got x = 1", parser.Errors()[0])
+}
+
+func (t *ParserTestSuite) TestParsingTypeAnnotations() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5", "let x: int = 5;"},
+		{"let [a, b]: array[string] = xs", "let [a, b]: array[string] = xs;"},
+		{"fn(x: int, y: string): bool { true }", "fn(x: int, y: string): booltrue"},
+		{"fn(x, y: int = 1, ...rest: array[any]) { x }", "fn(x, y: int = 1, ...rest: array[any])x"},
+		{"fn({a, b}: hash[string, int]) { a }", "fn({a, b}: hash[string, int])a"},
+		{"fn(f: fn(int, ...string): fn(): int) { f }", "fn(f: fn(int, ...string): fn(): int)f"},
+		{"fn(f: fn, p: Point): array { f }", "fn(f: fn, p: Point): arrayf"},
+		{"async fn(): promise { 1 }", "async fn(): promise1"},
+		{"(x: int, y) => x + y", "fn(x: int, y)(x + y)"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		program := parser.ParseProgram()
+
+		t.Empty(parser.Errors(), test.input)
+		t.Equal(test.expected, program.String())
+	}
+
+	parser := NewParser(lexer.NewLexer("fn(a, b: int) { a }"))
+	program := parser.ParseProgram()
+	function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	t.Len(function.ParameterTypes, 2)
+	t.Nil(function.ParameterType(0))
+	t.Equal("int", function.ParameterType(1).Name)
+	t.Nil(function.ReturnType)
+
+	parser = NewParser(lexer.NewLexer("fn(a, b) { a }"))
+	program = parser.ParseProgram()
+	function = program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	t.Nil(function.ParameterTypes)
+	t.Nil(function.ParameterType(1))
+}
+
+func (t *ParserTestSuite) TestParsingTypeAnnotationErrors() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: = 5", `expected type, got "=" instead`},
+		{"fn(x: 5) { x }", `expected type, got "5" instead`},
+		{"fn(x: array[int) { x }", `expected next token to be "]", got ")" instead`},
+		{"fn(f: fn(...int, int)) { f }", "variadic parameter must be the last parameter"},
+		{"macro(x: int) { x }", "macro parameters cannot have types"},
+	}
+
+	for _, test := range tests {
+		parser := NewParser(lexer.NewLexer(test.input))
+		parser.ParseProgram()
+
+		t.NotEmpty(parser.Errors(), test.input)
+		t.Equal(test.expected, parser.Errors()[0])
+	}
+}
+
+func (t *ParserTestSuite) TestParseType() {
+	annotation, err := ParseType("fn(array[int], ...any): hash[string, int]")
+	t.NoError(err)
+	t.Equal("fn(array[int], ...any): hash[string, int]", annotation.String())
+	t.True(annotation.Variadic)
+	t.Equal("hash", annotation.Return.Name)
+
+	_, err = ParseType("int string")
+	t.EqualError(err, `unexpected "string" after type int`)
+}
+
+func (t *ParserTestSuite) TestErrorTokens() {
+	parser := NewParser(lexer.NewLexer("let x = 1;\nlet = 2;\nfn(x: 5) { x }"))
+	parser.ParseProgram()
+
+	t.Len(parser.ErrorTokens(), len(parser.Errors()))
+	t.Equal(`expected next token to be "IDENT", got "=" instead`, parser.Errors()[0])
+	t.Equal(2, parser.ErrorTokens()[0].Line)
+	t.Equal(5, parser.ErrorTokens()[0].Column)
+
+	t.Equal(`expected type, got "5" instead`, parser.Errors()[2])
+	t.Equal(3, parser.ErrorTokens()[2].Line)
+	t.Equal(7, parser.ErrorTokens()[2].Column)
+}
+
+func (t *ParserTestSuite) TestJSONRoundTrip() {
+	input := `
+import "lib.fg" as lib;
+export let add = fn(a: int, b: int = 1, ...rest): int { a + b };
+let [x, y, ...others] = [1, ...lib.values, 3];
+let {name: n, ...props} = {"name": "fungo", ...defaults, 1: true};
+struct Point { x, y }
+enum Shape { Circle(r), Square }
+let area = match (s) { Circle(r) => r * r, Square => 1 };
+let f = async fn(p) { await p |> g };
+let h = (q) => q;
+let gen = fn() { for (v in xs) { yield v; } };
+let m = macro(a) { quote(unquote(a) + 1) };
+try { throw "oops"; } catch (e) { e.message } finally { !done };
+select { recv(ch) as v => v, _ => 0 };
+spawn work(-1, items[0]);
+if (a < b) { a } else { return b; }
+`
+
+	parser := NewParser(lexer.NewLexer(input))
+	program := parser.ParseProgram()
+	t.Empty(parser.Errors())
+
+	encoded, err := ast.EncodeJSON(program)
+	t.NoError(err)
+
+	decoded, err := ast.DecodeProgramJSON(encoded)
+	t.NoError(err)
+	t.Equal(program.String(), decoded.String())
+
+	reencoded, err := ast.EncodeJSON(decoded)
+	t.NoError(err)
+	t.Equal(string(encoded), string(reencoded))
+
+	// Decoding checks the kinds of the nodes against the fields holding them
+	_, err = ast.DecodeProgramJSON([]byte(`{"kind":"Program","statements":[{"kind":"Identifier"}]}`))
+	t.EqualError(err, "Program.statements: a Identifier cannot be used as ast.Statement")
+
+	_, err = ast.DecodeJSON([]byte(`{"kind":"Nope"}`))
+	t.EqualError(err, `unknown node kind "Nope"`)
+}
+
+// Trees missing a child their nodes need are rejected rather than decoded into nodes that panic once printed or run
+func (t *ParserTestSuite) TestJSONMissingChildren() {
+	one := `{"kind":"IntegerLiteral","value":1}`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind":"InfixExpression","operator":"+","left":null,"right":null}`, "InfixExpression.right: expected a node, got null"},
+		{`{"kind":"InfixExpression","operator":"+","right":` + one + `}`, "InfixExpression.left: expected a node, got null"},
+		{`{"kind":"HashLiteral","pairs":[{"key":` + one + `}]}`, "HashLiteral.pairs: 0: expected a value, got null"},
+		{`{"kind":"HashLiteral","pairs":[{"key":null,"value":` + one + `}]}`, "HashLiteral.pairs: 0: expected a key, got null"},
+		{`{"kind":"FunctionLiteral","parameters":[],"body":null}`, "FunctionLiteral.body: expected a node, got null"},
+		{`{"kind":"ArrayLiteral","elements":[` + one + `,null]}`, "ArrayLiteral.elements: 1: expected a node, got null"},
+		{`{"kind":"LetStatement","value":` + one + `}`, "LetStatement: expected a name or a pattern, got neither"},
+		{`{"kind":"Program","statements":[{"kind":"ExpressionStatement","expression":{"kind":"PrefixExpression","operator":"-"}}]}`,
+			"Program.statements: ExpressionStatement.expression: PrefixExpression.right: expected a node, got null"},
+	}
+
+	for _, test := range tests {
+		_, err := ast.DecodeJSON([]byte(test.input))
+		t.EqualError(err, test.expected, test.input)
+	}
+
+	// Spread elements are the pairs without a value, optional children may be null
+	valid := []string{
+		`{"kind":"HashLiteral","pairs":[{"key":{"kind":"SpreadElement","value":{"kind":"Identifier","value":"xs"}}}]}`,
+		`{"kind":"IfExpression","condition":{"kind":"Boolean","value":true},"ifCondition":{"kind":"BlockStatement","statements":[]},"elseCondition":null}`,
+	}
+
+	for _, input := range valid {
+		node, err := ast.DecodeJSON([]byte(input))
+		t.NoError(err, input)
+		t.NotPanics(func() { _ = node.String() }, input)
+	}
+}
@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"fungo/ast"
+	"fungo/lexer"
+	"fungo/token"
+	"strings"
+)
+
+// ParseType parses a type annotation on its own, such as the signatures of the built in functions
+func ParseType(source string) (*ast.TypeAnnotation, error) {
+	p := NewParser(lexer.NewLexer(source))
+
+	annotation := p.parseTypeAnnotation()
+	if annotation != nil && !p.peekTokenIs(token.EOF) {
+		p.addErrorAt(p.peekToken, fmt.Sprintf("unexpected %q after type %s", p.peekToken.Literal, annotation.String()))
+	}
+
+	if len(p.errors) > 0 {
+		return nil, errors.New(strings.Join(p.errors, "\n"))
+	}
+
+	return annotation, nil
+}
+
+// Parses the `: <type>` following a parameter, a let target or a parameter list when there is one
+func (p *Parser) parseOptionalType() (*ast.TypeAnnotation, bool) {
+	if !p.peekTokenIs(token.COLON) {
+		return nil, true
+	}
+
+	p.nextToken()
+	p.nextToken()
+
+	annotation := p.parseTypeAnnotation()
+
+	return annotation, annotation != nil
+}
+
+// <name>, <name>[<type>, ...], fn or fn(<type>, ..., ...<type>): <type>
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	defer untrace(trace("parseTypeAnnotation"))
+
+	annotation := &ast.TypeAnnotation{Token: p.currToken, Name: p.currToken.Literal}
+
+	switch {
+	case p.currTokenIs(token.FUNCTION) && p.peekTokenIs(token.LPAREN):
+		return p.parseFunctionType(annotation)
+	case p.currTokenIs(token.FUNCTION):
+		// Any function, whatever its parameters and result
+		return annotation
+	case p.currTokenIs(token.IDENT):
+	default:
+		p.addError(fmt.Sprintf("expected type, got %q instead", p.currToken.Literal))
+		return nil
+	}
+
+	if !p.peekTokenIs(token.LBRACKET) {
+		return annotation
+	}
+
+	p.nextToken()
+
+	for {
+		p.nextToken()
+
+		parameter := p.parseTypeAnnotation()
+		if parameter == nil {
+			return nil
+		}
+		annotation.Parameters = append(annotation.Parameters, parameter)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return annotation
+}
+
+func (p *Parser) parseFunctionType(annotation *ast.TypeAnnotation) *ast.TypeAnnotation {
+	annotation.Signature = true
+	p.nextToken()
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if annotation.Variadic {
+			p.addError("variadic parameter must be the last parameter")
+			return nil
+		}
+
+		p.nextToken()
+
+		if p.currTokenIs(token.ELLIPSIS) {
+			annotation.Variadic = true
+			p.nextToken()
+		}
+
+		parameter := p.parseTypeAnnotation()
+		if parameter == nil {
+			return nil
+		}
+		annotation.Parameters = append(annotation.Parameters, parameter)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	result, ok := p.parseOptionalType()
+	if !ok {
+		return nil
+	}
+	annotation.Return = result
+
+	return annotation
+}
@@ -0,0 +1,712 @@
+package printer
+
+import (
+	"errors"
+	"fungo/ast"
+	"fungo/lexer"
+	"fungo/parser"
+	"fungo/token"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Lists that would make a line longer than this are broken one element per line
+const MaxWidth = 80
+
+const indentation = "  "
+
+// The canonical style: two space indentation, one statement per line, spaces around binary operators, only the
+// parentheses precedence requires. Blocks holding a single short expression stay on one line, blocks never do
+type printer struct {
+	// Comments of the source and its lines, used to put comments back and keep blank lines between statements.
+	// Both are empty when printing a tree that was not parsed from source
+	comments []token.Token
+	lines    []string
+}
+
+// Parses source and prints it back in the canonical style, keeping its comments and single blank lines
+func Format(source []byte) ([]byte, error) {
+	l := lexer.NewLexer(string(source))
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	printer := &printer{comments: l.Comments(), lines: strings.Split(string(source), "\n")}
+
+	return []byte(printer.program(program)), nil
+}
+
+// Prints a program, statement or expression in the canonical style. There are no comments in the tree, so none are
+// printed
+func Fprint(w io.Writer, node ast.Node) error {
+	printer := &printer{}
+
+	var out string
+	switch node := node.(type) {
+	case *ast.Program:
+		out = printer.program(node)
+	case ast.Statement:
+		out = printer.statement(node, nil, 0)
+	case ast.Expression:
+		out = printer.expression(node, 0, 0)
+	}
+
+	_, err := io.WriteString(w, out)
+
+	return err
+}
+
+func (p *printer) program(program *ast.Program) string {
+	start := token.Token{}
+	end := token.Token{Line: math.MaxInt}
+
+	return p.statements(program.Statements, start, end, 0)
+}
+
+/* ============================== Statements =============================== */
+
+// Prints statements written between start and end one per line, along with the comments among them that are not
+// inside one of their blocks. A comment following code on its line stays at the end of the statement it follows
+func (p *printer) statements(statements []ast.Statement, start, end token.Token, indent int) string {
+	var out strings.Builder
+
+	prefix := strings.Repeat(indentation, indent)
+	comments := p.ownComments(statements, start, end)
+	first := true
+
+	emit := func(line int, text string) {
+		if !first && p.isBlank(line-1) {
+			out.WriteString("\n")
+		}
+		first = false
+
+		out.WriteString(prefix + text + "\n")
+	}
+
+	for idx, statement := range statements {
+		position := statementToken(statement)
+
+		for len(comments) > 0 && before(comments[0], position) {
+			emit(comments[0].Line, comments[0].Literal)
+			comments = comments[1:]
+		}
+
+		var next ast.Statement
+		nextPosition := end
+
+		if idx < len(statements)-1 {
+			next = statements[idx+1]
+			nextPosition = statementToken(next)
+		}
+
+		text := p.statement(statement, next, indent)
+
+		if len(comments) > 0 && before(comments[0], nextPosition) && p.followsCode(comments[0]) {
+			text += " " + comments[0].Literal
+			comments = comments[1:]
+		}
+
+		emit(position.Line, text)
+	}
+
+	for _, comment := range comments {
+		emit(comment.Line, comment.Literal)
+	}
+
+	return out.String()
+}
+
+// A statement starting at the column of the given indentation, next is the one following it in its block if any.
+// Expression statements are terminated with `;`, except the last of a block, whose value stands out without it, and
+// those ending with a block which the next statement cannot be mistaken to continue
+func (p *printer) statement(statement ast.Statement, next ast.Statement, indent int) string {
+	col := len(indentation) * indent
+
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		return p.let(statement, indent, col) + ";"
+
+	case *ast.ReturnStatement:
+		return "return " + p.expression(statement.ReturnValue, indent, col+len("return ")) + ";"
+
+	case *ast.ThrowStatement:
+		return "throw " + p.expression(statement.Value, indent, col+len("throw ")) + ";"
+
+	case *ast.ImportStatement:
+		out := "import " + quote(statement.Path.Value)
+		if statement.Alias.Value != parser.ModuleName(statement.Path.Value) {
+			out += " as " + statement.Alias.Value
+		}
+
+		return out + ";"
+
+	case *ast.ExportStatement:
+		return "export " + p.let(statement.Statement, indent, col+len("export ")) + ";"
+
+	case *ast.StructStatement:
+		head := "struct " + statement.Name.Value + " "
+
+		return head + p.list("{ ", " }", len(statement.Fields), func(idx, _, _ int) string {
+			return statement.Fields[idx].Value
+		}, indent, col+len(head))
+
+	case *ast.EnumStatement:
+		head := "enum " + statement.Name.Value + " "
+
+		return head + p.list("{ ", " }", len(statement.Variants), func(idx, indent, col int) string {
+			variant := statement.Variants[idx]
+			if len(variant.Fields) == 0 {
+				return variant.Name.Value
+			}
+
+			return variant.Name.Value + p.list("(", ")", len(variant.Fields), func(idx, _, _ int) string {
+				return variant.Fields[idx].Value
+			}, indent, col+len(variant.Name.Value))
+		}, indent, col+len(head))
+
+	case *ast.ExpressionStatement:
+		out := p.expression(statement.Expression, indent, col)
+		if next != nil && !(endsWithBlock(statement.Expression) && startsStatement(next)) {
+			out += ";"
+		}
+
+		return out
+
+	case *ast.BlockStatement:
+		return p.block(statement, indent, col)
+	}
+
+	return ""
+}
+
+func (p *printer) let(statement *ast.LetStatement, indent, col int) string {
+	var target string
+	if statement.Pattern != nil {
+		target = p.expression(statement.Pattern, indent, col+len("let "))
+	} else {
+		target = statement.Name.Value
+	}
+
+	head := "let " + annotated(target, statement.Type) + " = "
+
+	return head + p.expression(statement.Value, indent, advance(col, head))
+}
+
+// `{ <expression> }` when the block is a single short expression without comments, the statements indented on
+// their own lines otherwise
+func (p *printer) block(block *ast.BlockStatement, indent, col int) string {
+	if out, ok := p.flatBlock(block, indent, col); ok {
+		return out
+	}
+
+	return p.brokenBlock(block, indent)
+}
+
+// Blocks following each other in an if or try are either all on one line or all broken
+func (p *printer) blocks(heads []string, blocks []*ast.BlockStatement, indent, col int) string {
+	out := ""
+	for idx, block := range blocks {
+		out += heads[idx]
+
+		flat, ok := p.flatBlock(block, indent, advance(col, out))
+		if !ok {
+			out = ""
+			for idx, block := range blocks {
+				out += heads[idx] + p.brokenBlock(block, indent)
+			}
+
+			return out
+		}
+
+		out += flat
+	}
+
+	return out
+}
+
+func (p *printer) flatBlock(block *ast.BlockStatement, indent, col int) (string, bool) {
+	if len(p.commentsBetween(block.Token, block.Rbrace)) != 0 {
+		return "", false
+	}
+
+	if len(block.Statements) == 0 {
+		return "{}", true
+	}
+
+	statement, ok := block.Statements[0].(*ast.ExpressionStatement)
+	if !ok || len(block.Statements) != 1 {
+		return "", false
+	}
+
+	expression := p.expression(statement.Expression, indent, col+2)
+	if strings.Contains(expression, "\n") || col+len(expression)+4 > MaxWidth {
+		return "", false
+	}
+
+	return "{ " + expression + " }", true
+}
+
+func (p *printer) brokenBlock(block *ast.BlockStatement, indent int) string {
+	return "{\n" + p.statements(block.Statements, block.Token, block.Rbrace, indent+1) + strings.Repeat(indentation, indent) + "}"
+}
+
+/* ============================== Expressions ============================== */
+
+// An expression starting at column col of a line indented indent levels deep
+func (p *printer) expression(expression ast.Expression, indent, col int) string {
+	switch expression := expression.(type) {
+	case *ast.Identifier:
+		return expression.Value
+
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(expression.Value, 10)
+
+	case *ast.Boolean:
+		return strconv.FormatBool(expression.Value)
+
+	case *ast.StringLiteral:
+		return quote(expression.Value)
+
+	case *ast.PrefixExpression:
+		return expression.Operator + p.operand(expression.Right, parser.PREFIX, indent, col+len(expression.Operator))
+
+	case *ast.InfixExpression:
+		precedence := parser.Precedence(token.TokenType(expression.Operator))
+
+		left := p.operand(expression.Left, precedence, indent, col)
+		operator := " " + expression.Operator + " "
+
+		return left + operator + p.operand(expression.Right, precedence+1, indent, advance(col, left+operator))
+
+	case *ast.PipeExpression:
+		left := p.operand(expression.Left, parser.PIPELINE, indent, col)
+
+		return left + " |> " + p.operand(expression.Right, parser.PIPELINE+1, indent, advance(col, left+" |> "))
+
+	case *ast.CallExpression:
+		function := p.operand(expression.Function, parser.CALL, indent, col)
+
+		return function + p.expressions("(", ")", expression.Arguments, indent, advance(col, function))
+
+	case *ast.IndexExpression:
+		ref := p.operand(expression.Ref, parser.CALL, indent, col)
+
+		return ref + "[" + p.expression(expression.Index, indent, advance(col, ref)+1) + "]"
+
+	case *ast.MemberExpression:
+		return p.operand(expression.Ref, parser.CALL, indent, col) + "." + expression.Property.Value
+
+	case *ast.ArrayLiteral:
+		return p.expressions("[", "]", expression.Elements, indent, col)
+
+	case *ast.HashLiteral:
+		return p.list("{", "}", len(expression.Keys), func(idx, indent, col int) string {
+			key := expression.Keys[idx]
+			if spread, ok := key.(*ast.SpreadElement); ok {
+				return p.expression(spread, indent, col)
+			}
+
+			out := p.expression(key, indent, col) + ": "
+
+			return out + p.expression(expression.Pairs[key], indent, advance(col, out))
+		}, indent, col)
+
+	case *ast.SpreadElement:
+		return "..." + p.expression(expression.Value, indent, col+3)
+
+	case *ast.RestElement:
+		return "..." + expression.Target.Value
+
+	case *ast.DefaultParameter:
+		target := p.expression(expression.Target, indent, col) + " = "
+
+		return target + p.expression(expression.Value, indent, advance(col, target))
+
+	case *ast.ArrayPattern:
+		elements := expression.Elements
+		if expression.Rest != nil {
+			elements = append(append([]ast.Expression{}, elements...), expression.Rest)
+		}
+
+		return p.expressions("[", "]", elements, indent, col)
+
+	case *ast.HashPattern:
+		count := len(expression.Properties)
+		if expression.Rest != nil {
+			count += 1
+		}
+
+		return p.list("{", "}", count, func(idx, indent, col int) string {
+			if idx == len(expression.Properties) {
+				return p.expression(expression.Rest, indent, col)
+			}
+
+			property := expression.Properties[idx]
+			if identifier, ok := property.Value.(*ast.Identifier); ok && identifier.Value == property.Key.Value {
+				return property.Key.Value
+			}
+
+			return property.Key.Value + ": " + p.expression(property.Value, indent, col+len(property.Key.Value)+2)
+		}, indent, col)
+
+	case *ast.VariantPattern:
+		variant := p.expression(expression.Variant, indent, col)
+
+		return variant + p.expressions("(", ")", expression.Fields, indent, advance(col, variant))
+
+	case *ast.FunctionLiteral:
+		return p.function(expression, indent, col)
+
+	case *ast.MacroLiteral:
+		params := make([]ast.Expression, len(expression.Parameters))
+		for idx, param := range expression.Parameters {
+			params[idx] = param
+		}
+
+		head := "macro" + p.expressions("(", ")", params, indent, col+len("macro")) + " "
+
+		return head + p.block(expression.Body, indent, advance(col, head))
+
+	case *ast.IfExpression:
+		head := "if (" + p.expression(expression.Condition, indent, col+len("if (")) + ") "
+
+		if expression.ElseCondition == nil {
+			return head + p.block(expression.IfCondition, indent, advance(col, head))
+		}
+
+		return p.blocks([]string{head, " else "}, []*ast.BlockStatement{expression.IfCondition, expression.ElseCondition}, indent, col)
+
+	case *ast.TryExpression:
+		heads := []string{"try "}
+		blocks := []*ast.BlockStatement{expression.Block}
+
+		if expression.CatchBlock != nil {
+			head := " catch "
+
+			if expression.CatchParameter != nil {
+				head += "(" + p.expression(expression.CatchParameter, indent, 0) + ") "
+			}
+
+			heads = append(heads, head)
+			blocks = append(blocks, expression.CatchBlock)
+		}
+
+		if expression.FinallyBlock != nil {
+			heads = append(heads, " finally ")
+			blocks = append(blocks, expression.FinallyBlock)
+		}
+
+		return p.blocks(heads, blocks, indent, col)
+
+	case *ast.ForExpression:
+		head := "for (" + p.expression(expression.Target, indent, col+len("for ("))
+		head += " in " + p.expression(expression.Iterable, indent, advance(col, head)+len(" in ")) + ") "
+
+		return head + p.block(expression.Body, indent, advance(col, head))
+
+	case *ast.MatchExpression:
+		head := "match (" + p.expression(expression.Subject, indent, col+len("match (")) + ") "
+
+		arms := make([]string, len(expression.Arms))
+		for idx, arm := range expression.Arms {
+			pattern := p.expression(arm.Pattern, indent+1, len(indentation)*(indent+1)) + " => "
+			arms[idx] = pattern + p.armBody(arm.Body, indent+1, advance(len(indentation)*(indent+1), pattern))
+		}
+
+		return head + p.arms(arms, indent)
+
+	case *ast.SelectExpression:
+		arms := make([]string, len(expression.Arms))
+		for idx, arm := range expression.Arms {
+			operation := "_"
+			if arm.Operation != nil {
+				operation = p.expression(arm.Operation, indent+1, len(indentation)*(indent+1))
+			}
+
+			if arm.Binding != nil {
+				operation += " as " + arm.Binding.Value
+			}
+
+			operation += " => "
+			arms[idx] = operation + p.armBody(arm.Body, indent+1, advance(len(indentation)*(indent+1), operation))
+		}
+
+		return "select " + p.arms(arms, indent)
+
+	case *ast.YieldExpression:
+		return "yield " + p.expression(expression.Value, indent, col+len("yield "))
+
+	case *ast.AwaitExpression:
+		return "await " + p.operand(expression.Value, parser.PREFIX, indent, col+len("await "))
+
+	case *ast.SpawnExpression:
+		return "spawn " + p.operand(expression.Call, parser.PREFIX, indent, col+len("spawn "))
+	}
+
+	return ""
+}
+
+// Wraps an operand in parentheses when it binds less tightly than the operator it belongs to requires
+func (p *printer) operand(expression ast.Expression, precedence int, indent, col int) string {
+	if precedenceOf(expression) < precedence {
+		return "(" + p.expression(expression, indent, col+1) + ")"
+	}
+
+	return p.expression(expression, indent, col)
+}
+
+func precedenceOf(expression ast.Expression) int {
+	switch expression := expression.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(token.TokenType(expression.Operator))
+	case *ast.PipeExpression:
+		return parser.PIPELINE
+	case *ast.PrefixExpression, *ast.AwaitExpression, *ast.SpawnExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression, *ast.MemberExpression:
+		return parser.INDEX
+	case *ast.YieldExpression:
+		// Takes everything that follows as its value
+		return parser.LOWEST
+	case *ast.FunctionLiteral:
+		if expression.IsArrow {
+			return parser.LOWEST
+		}
+	}
+
+	return parser.INDEX + 1
+}
+
+func (p *printer) function(function *ast.FunctionLiteral, indent, col int) string {
+	if !function.IsArrow {
+		head := annotated("fn"+p.parameters(function, indent, col+len("fn")), function.ReturnType) + " "
+		if function.IsAsync {
+			head = "async " + head
+		}
+
+		return head + p.block(function.Body, indent, advance(col, head))
+	}
+
+	head := ""
+	if identifier, ok := singleParameter(function); ok && function.ParameterTypes == nil {
+		head = identifier.Value
+	} else {
+		head = p.parameters(function, indent, col)
+	}
+	head += " => "
+
+	return head + p.armBody(function.Body, indent, advance(col, head))
+}
+
+// The parameter list, with the annotation of a parameter between its target and default value
+func (p *printer) parameters(function *ast.FunctionLiteral, indent, col int) string {
+	return p.list("(", ")", len(function.Parameters), func(idx, indent, col int) string {
+		param := function.Parameters[idx]
+
+		if param, ok := param.(*ast.DefaultParameter); ok && function.ParameterType(idx) != nil {
+			target := annotated(p.expression(param.Target, indent, col), function.ParameterType(idx)) + " = "
+
+			return target + p.expression(param.Value, indent, advance(col, target))
+		}
+
+		return annotated(p.expression(param, indent, col), function.ParameterType(idx))
+	}, indent, col)
+}
+
+func annotated(target string, annotation *ast.TypeAnnotation) string {
+	if annotation == nil {
+		return target
+	}
+
+	return target + ": " + annotation.String()
+}
+
+func singleParameter(function *ast.FunctionLiteral) (*ast.Identifier, bool) {
+	if len(function.Parameters) != 1 {
+		return nil, false
+	}
+
+	identifier, ok := function.Parameters[0].(*ast.Identifier)
+
+	return identifier, ok
+}
+
+// What follows `=>` in an arm or arrow function: a block if one was written, the lone expression otherwise
+func (p *printer) armBody(body *ast.BlockStatement, indent, col int) string {
+	if body.Token.Type == token.LBRACE || len(body.Statements) != 1 {
+		return p.block(body, indent, col)
+	}
+
+	statement, ok := body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return p.block(body, indent, col)
+	}
+
+	return p.expression(statement.Expression, indent, col)
+}
+
+// The arms of a match or select, one per line
+func (p *printer) arms(arms []string, indent int) string {
+	if len(arms) == 0 {
+		return "{}"
+	}
+
+	prefix := strings.Repeat(indentation, indent+1)
+
+	return "{\n" + prefix + strings.Join(arms, ",\n"+prefix) + "\n" + strings.Repeat(indentation, indent) + "}"
+}
+
+func (p *printer) expressions(open, close string, expressions []ast.Expression, indent, col int) string {
+	return p.list(open, close, len(expressions), func(idx, indent, col int) string {
+		return p.expression(expressions[idx], indent, col)
+	}, indent, col)
+}
+
+// Prints count items separated by commas between open and close. They stay on one line when it fits or when an item
+// already spans several lines, such as a function passed as the last argument, and go one per line otherwise
+func (p *printer) list(open, close string, count int, item func(idx, indent, col int) string, indent, col int) string {
+	flat := open
+	for idx := 0; idx < count; idx++ {
+		if idx > 0 {
+			flat += ", "
+		}
+
+		flat += item(idx, indent, advance(col, flat))
+	}
+	flat += close
+
+	if count == 0 || strings.Contains(flat, "\n") || col+len(flat) <= MaxWidth {
+		return flat
+	}
+
+	prefix := strings.Repeat(indentation, indent+1)
+
+	items := make([]string, count)
+	for idx := range items {
+		items[idx] = prefix + item(idx, indent+1, len(prefix))
+	}
+
+	return strings.TrimSpace(open) + "\n" + strings.Join(items, ",\n") + "\n" + strings.Repeat(indentation, indent) + strings.TrimSpace(close)
+}
+
+/* =============================== Comments ================================ */
+
+// The comments between start and end that are not inside a nested block, those are printed with the block
+func (p *printer) ownComments(statements []ast.Statement, start, end token.Token) []token.Token {
+	nested := []*ast.BlockStatement{}
+	for _, statement := range statements {
+		ast.Inspect(statement, func(node ast.Node) bool {
+			if block, ok := node.(*ast.BlockStatement); ok && block.Rbrace.Line > 0 {
+				nested = append(nested, block)
+			}
+
+			return true
+		})
+	}
+
+	comments := []token.Token{}
+
+outer:
+	for _, comment := range p.commentsBetween(start, end) {
+		for _, block := range nested {
+			if before(block.Token, comment) && before(comment, block.Rbrace) {
+				continue outer
+			}
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return comments
+}
+
+func (p *printer) commentsBetween(start, end token.Token) []token.Token {
+	comments := []token.Token{}
+
+	for _, comment := range p.comments {
+		if before(start, comment) && before(comment, end) {
+			comments = append(comments, comment)
+		}
+	}
+
+	return comments
+}
+
+// Whether there is code before the comment on its line
+func (p *printer) followsCode(comment token.Token) bool {
+	line := p.lines[comment.Line-1]
+
+	return strings.TrimSpace(line[:comment.Column-1]) != ""
+}
+
+func (p *printer) isBlank(line int) bool {
+	return line >= 1 && line <= len(p.lines) && strings.TrimSpace(p.lines[line-1]) == ""
+}
+
+/* ================================ Helpers ================================ */
+
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+func endsWithBlock(expression ast.Expression) bool {
+	switch expression.(type) {
+	case *ast.IfExpression, *ast.TryExpression, *ast.ForExpression, *ast.MatchExpression, *ast.SelectExpression:
+		return true
+	}
+
+	return false
+}
+
+// Whether a statement starts with a token that cannot continue the expression before it, as an operator, a call or
+// an index would
+func startsStatement(statement ast.Statement) bool {
+	position := statementToken(statement)
+
+	return position.Type != "" && !parser.IsInfix(position.Type)
+}
+
+func statementToken(statement ast.Statement) token.Token {
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		return statement.Token
+	case *ast.ReturnStatement:
+		return statement.Token
+	case *ast.ThrowStatement:
+		return statement.Token
+	case *ast.ImportStatement:
+		return statement.Token
+	case *ast.ExportStatement:
+		return statement.Token
+	case *ast.StructStatement:
+		return statement.Token
+	case *ast.EnumStatement:
+		return statement.Token
+	case *ast.ExpressionStatement:
+		return statement.Token
+	case *ast.BlockStatement:
+		return statement.Token
+	}
+
+	return token.Token{}
+}
+
+// The column following s when it is printed from column col
+func advance(col int, s string) int {
+	if idx := strings.LastIndexByte(s, '\n'); idx >= 0 {
+		return len(s) - idx - 1
+	}
+
+	return col + len(s)
+}
+
+func quote(s string) string {
+	return "\"" + s + "\""
+}
@@ -0,0 +1,170 @@
+package printer
+
+import (
+	"bytes"
+	"fungo/ast"
+	"fungo/lexer"
+	"fungo/parser"
+	"fungo/token"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PrinterTestSuite struct {
+	suite.Suite
+}
+
+func TestPrinterTestSuite(t *testing.T) {
+	suite.Run(t, &PrinterTestSuite{})
+}
+
+// Programs exercising every kind of node, formatting each must be idempotent and keep its meaning
+var programs = []string{
+	`let add = fn(a, b) { a + b }; add(1, 2)`,
+	`let x = (1 + 2) * 3 - (4 - 5) - -x; !(a == b)`,
+	`let f = fn(x) { let y = x * 2; if (y > 10) { y } else { if (y < 0) { 0 } else { y } } };`,
+	`let xs = [1, 2, 3] |> map(x => x * 2) |> filter((x, i) => x > i);`,
+	`let h = {"a": 1, "b": [1, 2], ...rest}; h["a"]; h.b`,
+	`let [a, [b, ...c], ...d] = xs; let {a, b: {c}, ...e} = h;`,
+	`let f = fn(a, b = 2, ...rest) { return a + b; };`,
+	`import "./lib/list.fg"; import "./lib/list.fg" as l; export let x = l.map;`,
+	`struct Point { x, y }; enum Shape { Circle(r), Rect(w, h), Empty }`,
+	`match (s) { Circle(r) => 3 * r * r, Shape.Rect(w, h) => { let a = w * h; a }, Empty => 0, _ => "?" }`,
+	`let r = try { throw "x"; } catch ({message}) { message } finally { print("done") }; try { 1 } catch { 2 }`,
+	`let g = fn() { for (x in range(10)) { yield x * 2 } };`,
+	`let f = async fn(p) { let v = await p; await sleep(v); v }; spawn f(1);`,
+	`select { recv(c) as v => v, send(c, 1) => { 2 }, _ => 3 }`,
+	`let unless = macro(cond, body) { quote(if (!(unquote(cond))) { unquote(body) }) };`,
+	`(x => x)(2); (a + b).c; (-a)[1]; (fn(x) { x })(3)`,
+	`if (x) { print(x) } -1`,
+	`let long = ["aaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbbbbbb", "cccccccccccccccccccc", "dddddddddddd", "eeeeeee"];`,
+	`let z = foo(fn(x) { let y = x; y }, {"first": 1111111111, "second": 2222222222, "third": 3333333333333});`,
+	`let f = fn(a: int, b: array[string] = [], ...c: array[any]): hash[string, fn(int): bool] { a }; let x: Point = p;`,
+	`let g = (x: int) => x + 1; let h = async fn(f: fn, ...g: array[fn(...int)]): promise { f }`,
+}
+
+func (t *PrinterTestSuite) TestFormat() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=1\nx", "let x = 1;\nx\n"},
+		{"1+2*3; (1+2)*3; 1-(2-3); (1-2)-3", "1 + 2 * 3;\n(1 + 2) * 3;\n1 - (2 - 3);\n1 - 2 - 3\n"},
+		{"-(1+2); !!true; a |> (b |> c)", "-(1 + 2);\n!!true;\na |> (b |> c)\n"},
+		{"let double = fn(x) {x*2};", "let double = fn(x) { x * 2 };\n"},
+		{"let f = fn(x) { let y = x; y }", "let f = fn(x) {\n  let y = x;\n  y\n};\n"},
+		{"let f = (a, b) => a + b; let g = x => { x }", "let f = (a, b) => a + b;\nlet g = x => { x };\n"},
+		{"let f = fn(x:int,y :string=\"a\"):bool{true}", "let f = fn(x: int, y: string = \"a\"): bool { true };\n"},
+		{"let g = (x:int) => x; let y:array[ int ]=[]", "let g = (x: int) => x;\nlet y: array[int] = [];\n"},
+		{
+			"if (a) { 1 } else { let b = 2; b }",
+			"if (a) {\n  1\n} else {\n  let b = 2;\n  b\n}\n",
+		},
+		{"if (a) { 1 } else { 2 }\nprint(1)", "if (a) { 1 } else { 2 }\nprint(1)\n"},
+		{"if (a) { 1 } else { 2 };\n-1", "if (a) { 1 } else { 2 };\n-1\n"},
+		{"match (x) { 1 => \"one\", _ => { \"many\" } }", "match (x) {\n  1 => \"one\",\n  _ => { \"many\" }\n}\n"},
+		{"import \"lib/list.fg\" as list\nimport \"lib/list.fg\" as l", "import \"lib/list.fg\";\nimport \"lib/list.fg\" as l;\n"},
+		{"let {a: a, b: c} = h; let [..rest] = xs;", "let {a, b: c} = h;\nlet [...rest] = xs;\n"},
+		{"struct   Point{x,y};", "struct Point { x, y }\n"},
+		{
+			"let xs = [\"aaaaaaaaaaaaaaaaaaaa\", \"bbbbbbbbbbbbbbbbbbbb\", \"cccccccccccccccccccc\", \"dddddd\"];",
+			"let xs = [\n  \"aaaaaaaaaaaaaaaaaaaa\",\n  \"bbbbbbbbbbbbbbbbbbbb\",\n  \"cccccccccccccccccccc\",\n  \"dddddd\"\n];\n",
+		},
+		{
+			"map(xs, fn(x) { let y = x; y })",
+			"map(xs, fn(x) {\n  let y = x;\n  y\n})\n",
+		},
+	}
+
+	for _, test := range tests {
+		formatted, err := Format([]byte(test.input))
+
+		t.Nil(err)
+		t.Equal(test.expected, string(formatted))
+	}
+}
+
+func (t *PrinterTestSuite) TestFormatComments() {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"// header\n\n\n\nlet x = 1 // one\n\nx", "// header\n\nlet x = 1; // one\n\nx\n"},
+		{
+			"let f = fn(x) { // body\n  x // value\n  // end\n};",
+			"let f = fn(x) {\n  // body\n  x // value\n  // end\n};\n",
+		},
+		{"let f = fn() {\n  // nothing\n}", "let f = fn() {\n  // nothing\n};\n"},
+		{
+			"let xs = [\n  1, // one\n  2 // two\n];\nxs",
+			"let xs = [1, 2]; // one\n// two\nxs\n",
+		},
+		{"if (a) {\n  1\n} else {\n  // none\n  2\n}", "if (a) {\n  1\n} else {\n  // none\n  2\n}\n"},
+		{"x // last\n// trailing", "x // last\n// trailing\n"},
+	}
+
+	for _, test := range tests {
+		formatted, err := Format([]byte(test.input))
+
+		t.Nil(err)
+		t.Equal(test.expected, string(formatted))
+	}
+}
+
+func (t *PrinterTestSuite) TestFormatIsIdempotent() {
+	for _, program := range programs {
+		formatted, err := Format([]byte(program))
+		t.Nil(err, program)
+
+		again, err := Format(formatted)
+		t.Nil(err, string(formatted))
+
+		t.Equal(string(formatted), string(again), program)
+	}
+}
+
+func (t *PrinterTestSuite) TestFormatKeepsMeaning() {
+	for _, program := range programs {
+		formatted, err := Format([]byte(program))
+		t.Nil(err, program)
+
+		t.Equal(parse(t, program).String(), parse(t, string(formatted)).String(), string(formatted))
+	}
+}
+
+func (t *PrinterTestSuite) TestFormatErrors() {
+	_, err := Format([]byte("let = 1"))
+
+	t.NotNil(err)
+}
+
+func (t *PrinterTestSuite) TestFprint() {
+	// Trees built by hand or by macros have no positions and no comments
+	node := &ast.InfixExpression{
+		Operator: "*",
+		Left:     &ast.InfixExpression{Operator: "+", Left: &ast.Identifier{Value: "a"}, Right: &ast.IntegerLiteral{Value: 1}},
+		Right:    &ast.CallExpression{Function: &ast.Identifier{Value: "f"}, Arguments: []ast.Expression{&ast.StringLiteral{Value: "s"}}},
+	}
+
+	var out bytes.Buffer
+	t.Nil(Fprint(&out, node))
+	t.Equal(`(a + 1) * f("s")`, out.String())
+
+	out.Reset()
+	t.Nil(Fprint(&out, parse(t, "let x = 1; x")))
+	t.Equal("let x = 1;\nx\n", out.String())
+
+	out.Reset()
+	t.Nil(Fprint(&out, &ast.LetStatement{Token: token.Token{Type: token.LET}, Name: &ast.Identifier{Value: "x"}, Value: node}))
+	t.Equal(`let x = (a + 1) * f("s");`, out.String())
+}
+
+func parse(t *PrinterTestSuite, input string) *ast.Program {
+	p := parser.NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+
+	t.Empty(p.Errors(), input)
+
+	return program
+}
@@ -8,6 +8,7 @@ import (
 	"fungo/object"
 	"fungo/parser"
 	"io"
+	"strings"
 )
 
 func printParserErrors(out io.Writer, errors []string) {
@@ -16,20 +17,34 @@ func printParserErrors(out io.Writer, errors []string) {
 	}
 }
 
+// Reads lines from in and evaluates them, writing their results to out. The programs entered read from in and print
+// to out as well, input() takes the lines that follow the one calling it
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	reader := bufio.NewReader(in)
 	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
+
+	// Enums declared on earlier lines, so that matches over them are checked
+	enums := map[string][]string{}
+
+	ctx := evaluator.NewContext()
+	ctx.Stdin = reader
+	ctx.Stdout = out
+	ctx.Stderr = out
+	evaluator.AttachContext(env, ctx)
+	evaluator.AttachContext(macroEnv, ctx)
 
 	for {
 		fmt.Fprintf(out, "#: ")
-		scanned := scanner.Scan()
 
-		if !scanned {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && line == "" {
 			return
 		}
 
-		line := scanner.Text()
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
 		parser := parser.NewParser(lexer.NewLexer(line))
+		parser.DeclareEnums(enums)
 		program := parser.ParseProgram()
 
 		if len(parser.Errors()) != 0 {
@@ -37,9 +52,26 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
+		for _, warning := range parser.Warnings() {
+			io.WriteString(out, "\twarning: "+warning.String()+"\n")
+		}
+
+		for name, variants := range parser.Enums() {
+			enums[name] = variants
+		}
+
+		evaluator.DefineMacros(program, macroEnv)
+		expanded, err := evaluator.ExpandMacros(program, macroEnv)
+		if err != nil {
+			io.WriteString(out, err.String()+"\n")
+			continue
+		}
+
+		evaluated := evaluator.Eval(expanded, env)
+		if evaluated != nil && evaluated.Type() != object.NOOP_OBJ {
 			io.WriteString(out, evaluated.String()+"\n")
 		}
+
+		evaluator.RunEventLoop(env)
 	}
 }
@@ -0,0 +1,221 @@
+package tester
+
+import (
+	"encoding/xml"
+	"fmt"
+	"fungo/object"
+	"io"
+	"strings"
+	"time"
+)
+
+// The failure of a test as its kind and message followed by the calls it unwound through, one per line
+func failureText(err *object.Error) string {
+	lines := []string{err.Kind + ": " + err.Message}
+	for _, frame := range err.Trace {
+		lines = append(lines, "  "+frame)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func indent(text string, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+func seconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3fs", duration.Seconds())
+}
+
+// Writes the failures of each file followed by a line telling how it went, and every test that passed when verbose
+func WriteText(w io.Writer, results []FileResult, verbose bool) error {
+	out := &strings.Builder{}
+	passed, failed := 0, 0
+
+	for _, file := range results {
+		if file.Err != nil {
+			fmt.Fprintf(out, "FAIL %s\n%s\n", file.Path, indent(file.Err.Error(), "    "))
+			failed += 1
+			continue
+		}
+
+		for _, test := range file.Tests {
+			if test.Passed() {
+				passed += 1
+
+				if verbose {
+					fmt.Fprintf(out, "--- PASS: %s (%s)\n", test.Name, seconds(test.Duration))
+				}
+
+				continue
+			}
+
+			failed += 1
+			fmt.Fprintf(out, "--- FAIL: %s (%s)\n    %s:%d:%d\n%s\n", test.Name, seconds(test.Duration), file.Path, test.Line, test.Column, indent(failureText(test.Failure), "    "))
+		}
+
+		status := "ok  "
+		if file.Failed() > 0 {
+			status = "FAIL"
+		}
+
+		fmt.Fprintf(out, "%s %s (%d tests, %s)\n", status, file.Path, len(file.Tests), seconds(file.Duration))
+	}
+
+	fmt.Fprintf(out, "%d passed, %d failed\n", passed, failed)
+
+	_, err := io.WriteString(w, out.String())
+
+	return err
+}
+
+// Writes the results in the Test Anything Protocol, version 13. Failures come with a YAML block holding their message
+// and where the test is
+func WriteTAP(w io.Writer, results []FileResult) error {
+	out := &strings.Builder{}
+	out.WriteString("TAP version 13\n")
+
+	count := 0
+	for _, file := range results {
+		if file.Err != nil {
+			count += 1
+		}
+		count += len(file.Tests)
+	}
+	fmt.Fprintf(out, "1..%d\n", count)
+
+	number := 0
+	notOk := func(description string, message string, at string) {
+		fmt.Fprintf(out, "not ok %d - %s\n  ---\n  message: |\n%s\n", number, description, indent(message, "    "))
+		if at != "" {
+			fmt.Fprintf(out, "  at: %s\n", at)
+		}
+		out.WriteString("  ...\n")
+	}
+
+	for _, file := range results {
+		if file.Err != nil {
+			number += 1
+			notOk(file.Path, file.Err.Error(), "")
+			continue
+		}
+
+		for _, test := range file.Tests {
+			number += 1
+			description := file.Path + ": " + test.Name
+
+			if test.Passed() {
+				fmt.Fprintf(out, "ok %d - %s\n", number, description)
+			} else {
+				notOk(description, failureText(test.Failure), fmt.Sprintf("%s:%d:%d", file.Path, test.Line, test.Column))
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, out.String())
+
+	return err
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+func junitTime(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
+
+// Writes the results as JUnit XML, a test suite per file. Failed assertions are failures and other errors raised by
+// the tests are errors, a file that could not be parsed is reported as a test case with an error
+func WriteJUnit(w io.Writer, results []FileResult) error {
+	suites := junitSuites{Suites: []junitSuite{}}
+	var total time.Duration
+
+	for _, file := range results {
+		suite := junitSuite{Name: file.Path, Time: junitTime(file.Duration), Cases: []junitCase{}}
+
+		if file.Err != nil {
+			suite.Cases = append(suite.Cases, junitCase{
+				Name:      file.Path,
+				ClassName: file.Path,
+				Time:      junitTime(0),
+				Error:     &junitProblem{Message: firstLine(file.Err.Error()), Type: "ParseError", Text: file.Err.Error()},
+			})
+			suite.Errors += 1
+		}
+
+		for _, test := range file.Tests {
+			testCase := junitCase{Name: test.Name, ClassName: file.Path, Time: junitTime(test.Duration)}
+
+			if !test.Passed() {
+				problem := &junitProblem{Message: firstLine(test.Failure.Message), Type: test.Failure.Kind, Text: failureText(test.Failure)}
+
+				if test.Failure.Kind == object.ASSERTION_ERROR {
+					testCase.Failure = problem
+					suite.Failures += 1
+				} else {
+					testCase.Error = problem
+					suite.Errors += 1
+				}
+			}
+
+			suite.Cases = append(suite.Cases, testCase)
+		}
+
+		suite.Tests = len(suite.Cases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Suites = append(suites.Suites, suite)
+		total += file.Duration
+	}
+
+	suites.Time = junitTime(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
@@ -0,0 +1,264 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"fungo/ast"
+	"fungo/evaluator"
+	"fungo/lexer"
+	"fungo/object"
+	"fungo/parser"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Files holding tests end with TEST_SUFFIX, tests are their top level functions named with TEST_PREFIX
+const (
+	TEST_SUFFIX = "_test.fg"
+	TEST_PREFIX = "test_"
+)
+
+// How tests are run
+type Options struct {
+	// Only the tests whose name matches are run, all of them when nil
+	Run *regexp.Regexp
+
+	// Enforces type annotations, see evaluator.Context
+	CheckTypes bool
+
+	// Records the coverage of the modules the tests import, the test files themselves are not covered
+	Coverage *evaluator.Coverage
+
+	// Where the tests print, standard output when nil. Tests have no input, input() fails and readline() returns null
+	Output io.Writer
+}
+
+// The outcome of a test, Failure is nil when it passed
+type Result struct {
+	Name     string
+	Line     int
+	Column   int
+	Duration time.Duration
+	Failure  *object.Error
+}
+
+func (r Result) Passed() bool {
+	return r.Failure == nil
+}
+
+// The outcome of the tests of a file. Err is set when the file could not be parsed, no test ran then
+type FileResult struct {
+	Path     string
+	Err      error
+	Duration time.Duration
+	Tests    []Result
+}
+
+// Returns how many of the file's tests failed, a file that could not be parsed counts as one failure
+func (f FileResult) Failed() int {
+	if f.Err != nil {
+		return 1
+	}
+
+	failed := 0
+	for _, test := range f.Tests {
+		if !test.Passed() {
+			failed += 1
+		}
+	}
+
+	return failed
+}
+
+// Finds the test files among paths: directories are searched recursively, skipping hidden ones, files are taken as
+// they are. The files are sorted
+func Discover(paths []string) ([]string, error) {
+	files := []string{}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if entry.IsDir() && file != path && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), TEST_SUFFIX) {
+				files = append(files, file)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// A test function as the file defines it
+type test struct {
+	name   string
+	line   int
+	column int
+}
+
+// The test functions of a program, in source order: top level lets, exported or not, binding a function literal to a
+// name starting with TEST_PREFIX
+func tests(program ast.Node) []test {
+	found := []test{}
+
+	statements := []ast.Statement{}
+	if program, ok := program.(*ast.Program); ok {
+		statements = program.Statements
+	}
+
+	for _, statement := range statements {
+		if export, ok := statement.(*ast.ExportStatement); ok {
+			statement = export.Statement
+		}
+
+		let, ok := statement.(*ast.LetStatement)
+		if !ok || let.Name == nil || !strings.HasPrefix(let.Name.Value, TEST_PREFIX) {
+			continue
+		}
+
+		if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+			found = append(found, test{name: let.Name.Value, line: let.Token.Line, column: let.Token.Column})
+		}
+	}
+
+	return found
+}
+
+// Runs the tests of a file. Each test gets an environment of its own: the file is evaluated anew, with fresh copies
+// of the modules it imports, before the test function is called and the timers it set run
+func RunFile(path string, options Options) FileResult {
+	start := time.Now()
+	result := FileResult{Path: path, Tests: []Result{}}
+
+	program, err := load(path, options)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	for _, test := range tests(program) {
+		if options.Run != nil && !options.Run.MatchString(test.name) {
+			continue
+		}
+
+		result.Tests = append(result.Tests, run(path, program, test, options))
+	}
+
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+func run(path string, program ast.Node, test test, options Options) Result {
+	start := time.Now()
+	result := Result{Name: test.name, Line: test.line, Column: test.column}
+
+	env := object.NewEnvironment()
+	evaluator.AttachContext(env, newContext(path, options))
+
+	outcome := evaluator.Eval(program, env)
+
+	if !isError(outcome) {
+		fn, _ := env.Get(test.name)
+		outcome = evaluator.CallFunction(fn)
+	}
+
+	if !isError(outcome) {
+		evaluator.RunEventLoop(env)
+
+		// Async tests pass once their promise resolved
+		if promise, ok := outcome.(*object.Promise); ok {
+			value, settled := promise.Result()
+			if !settled {
+				value = &object.Error{Kind: object.RUNTIME_ERROR, Message: "the promise of the test never settled"}
+			}
+
+			outcome = value
+		}
+	}
+
+	if err, ok := outcome.(*object.Error); ok {
+		result.Failure = err
+	}
+
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+// Each test and the expansion of the file's macros get a context of their own, writing to the output of the options
+func newContext(path string, options Options) *evaluator.Context {
+	ctx := evaluator.NewContext()
+	ctx.Dir = filepath.Dir(path)
+	ctx.CheckTypes = options.CheckTypes
+	ctx.Coverage = options.Coverage
+	ctx.Stdin = strings.NewReader("")
+	if options.Output != nil {
+		ctx.Stdout = options.Output
+		ctx.Stderr = options.Output
+	}
+
+	return ctx
+}
+
+func isError(value object.Object) bool {
+	_, ok := value.(*object.Error)
+	return ok
+}
+
+// Reads and parses a file and expands its macros
+func load(path string, options Options) (ast.Node, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(source)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	// Coverage is of the tests, not of the macros expanding them
+	ctx := newContext(path, options)
+	ctx.Coverage = nil
+
+	macroEnv := object.NewEnvironment()
+	evaluator.AttachContext(macroEnv, ctx)
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, expandErr := evaluator.ExpandMacros(program, macroEnv)
+	if expandErr != nil {
+		return nil, fmt.Errorf("%s: %s", expandErr.Kind, expandErr.Message)
+	}
+
+	return expanded, nil
+}
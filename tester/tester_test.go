@@ -0,0 +1,231 @@
+package tester
+
+import (
+	"encoding/xml"
+	"fungo/evaluator"
+	"fungo/object"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TesterTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestTesterTestSuite(t *testing.T) {
+	suite.Run(t, &TesterTestSuite{})
+}
+
+func (t *TesterTestSuite) SetupTest() {
+	t.dir = t.T().TempDir()
+}
+
+func (t *TesterTestSuite) writeFile(name string, content string) string {
+	path := filepath.Join(t.dir, name)
+	t.Require().NoError(os.MkdirAll(filepath.Dir(path), 0755))
+	t.Require().NoError(os.WriteFile(path, []byte(content), 0644))
+
+	return path
+}
+
+const counter = `let box = chan(1);
+send(box, 0);
+export let bump = fn() {
+  let n = recv(box) + 1;
+  send(box, n);
+  if (n > 1) { "shared" } else { n }
+};`
+
+const counterTests = `import "./counter" as counter;
+
+let test_bump = fn() { assert_eq(counter.bump(), 1) };
+let test_bump_again = fn() { assert_eq(counter.bump(), 1) };
+let test_fails = fn() { assert_eq([1, 2], [1, 3]) };
+let test_raises = fn() { 1 + true };
+export let test_async = async fn() { await sleep(1); assert(false, "later") };
+let helper = fn() { assert(false) };
+let test_value = 1;`
+
+func (t *TesterTestSuite) TestDiscover() {
+	t.writeFile("a_test.fg", "")
+	t.writeFile("lib.fg", "")
+	t.writeFile("nested/b_test.fg", "")
+	t.writeFile(".hidden/c_test.fg", "")
+	explicit := t.writeFile("other.fg", "")
+
+	files, err := Discover([]string{t.dir, explicit})
+	t.NoError(err)
+	t.Equal([]string{
+		filepath.Join(t.dir, "a_test.fg"),
+		filepath.Join(t.dir, "nested", "b_test.fg"),
+		explicit,
+	}, files)
+
+	_, err = Discover([]string{filepath.Join(t.dir, "missing")})
+	t.Error(err)
+}
+
+func (t *TesterTestSuite) TestRunFile() {
+	t.writeFile("counter.fg", counter)
+	path := t.writeFile("counter_test.fg", counterTests)
+
+	coverage := evaluator.NewCoverage()
+	result := RunFile(path, Options{Coverage: coverage})
+	t.NoError(result.Err)
+	t.Equal(3, result.Failed())
+
+	outcomes := map[string]string{}
+	names := []string{}
+
+	for _, test := range result.Tests {
+		names = append(names, test.Name)
+
+		if test.Passed() {
+			outcomes[test.Name] = "ok"
+		} else {
+			outcomes[test.Name] = test.Failure.Kind + ": " + firstLine(test.Failure.Message)
+		}
+	}
+
+	// Every test imports its own copy of the module
+	t.Equal([]string{"test_bump", "test_bump_again", "test_fails", "test_raises", "test_async"}, names)
+	t.Equal(map[string]string{
+		"test_bump":       "ok",
+		"test_bump_again": "ok",
+		"test_fails":      "AssertionError: values are not equal",
+		"test_raises":     "RuntimeError: type mismatch: INTEGER + BOOLEAN",
+		"test_async":      "AssertionError: later",
+	}, outcomes)
+
+	t.Equal(3, result.Tests[0].Line)
+	t.Equal(1, result.Tests[0].Column)
+
+	// Only the imported module is covered, the else arm ran once per test
+	files := coverage.Files()
+	t.Require().Len(files, 1)
+	t.Equal(filepath.Join(t.dir, "counter.fg"), files[0].Path)
+	t.Equal(evaluator.BranchCoverage{Line: 6, Column: 3, Then: 0, Else: 2}, files[0].Branches[0])
+
+	result = RunFile(path, Options{Run: regexp.MustCompile("bump")})
+	t.Len(result.Tests, 2)
+	t.Equal(0, result.Failed())
+}
+
+func (t *TesterTestSuite) TestRunFileErrors() {
+	result := RunFile(t.writeFile("broken_test.fg", "let = 1"), Options{})
+	t.Error(result.Err)
+	t.Empty(result.Tests)
+	t.Equal(1, result.Failed())
+
+	// A file failing to evaluate fails every test
+	result = RunFile(t.writeFile("throws_test.fg", "let test_a = fn() { 1 };\nthrow \"setup\";"), Options{})
+	t.NoError(result.Err)
+	t.Require().Len(result.Tests, 1)
+	t.Equal("setup", result.Tests[0].Failure.Message)
+}
+
+func results() []FileResult {
+	return []FileResult{
+		{Path: "a_test.fg", Tests: []Result{
+			{Name: "test_ok", Line: 1, Column: 1},
+			{Name: "test_eq", Line: 2, Column: 1, Failure: &object.Error{
+				Kind:    object.ASSERTION_ERROR,
+				Message: "values are not equal\n  actual:   1\n  expected: 2",
+				Trace:   []string{"at assert_eq (builtin)", "at test_eq (2:20)"},
+			}},
+			{Name: "test_raises", Line: 3, Column: 1, Failure: &object.Error{Kind: object.RUNTIME_ERROR, Message: "boom"}},
+		}},
+		{Path: "b_test.fg", Err: os.ErrNotExist},
+	}
+}
+
+func (t *TesterTestSuite) TestWriteText() {
+	out := &strings.Builder{}
+	t.NoError(WriteText(out, results(), true))
+
+	t.Equal(strings.Join([]string{
+		"--- PASS: test_ok (0.000s)",
+		"--- FAIL: test_eq (0.000s)",
+		"    a_test.fg:2:1",
+		"    AssertionError: values are not equal",
+		"      actual:   1",
+		"      expected: 2",
+		"      at assert_eq (builtin)",
+		"      at test_eq (2:20)",
+		"--- FAIL: test_raises (0.000s)",
+		"    a_test.fg:3:1",
+		"    RuntimeError: boom",
+		"FAIL a_test.fg (3 tests, 0.000s)",
+		"FAIL b_test.fg",
+		"    file does not exist",
+		"1 passed, 3 failed",
+		"",
+	}, "\n"), out.String())
+}
+
+func (t *TesterTestSuite) TestWriteTAP() {
+	out := &strings.Builder{}
+	t.NoError(WriteTAP(out, results()))
+
+	t.Equal(strings.Join([]string{
+		"TAP version 13",
+		"1..4",
+		"ok 1 - a_test.fg: test_ok",
+		"not ok 2 - a_test.fg: test_eq",
+		"  ---",
+		"  message: |",
+		"    AssertionError: values are not equal",
+		"      actual:   1",
+		"      expected: 2",
+		"      at assert_eq (builtin)",
+		"      at test_eq (2:20)",
+		"  at: a_test.fg:2:1",
+		"  ...",
+		"not ok 3 - a_test.fg: test_raises",
+		"  ---",
+		"  message: |",
+		"    RuntimeError: boom",
+		"  at: a_test.fg:3:1",
+		"  ...",
+		"not ok 4 - b_test.fg",
+		"  ---",
+		"  message: |",
+		"    file does not exist",
+		"  ...",
+		"",
+	}, "\n"), out.String())
+}
+
+func (t *TesterTestSuite) TestWriteJUnit() {
+	out := &strings.Builder{}
+	t.NoError(WriteJUnit(out, results()))
+	t.True(strings.HasPrefix(out.String(), `<?xml version="1.0" encoding="UTF-8"?>`))
+
+	decoded := junitSuites{}
+	t.Require().NoError(xml.Unmarshal([]byte(out.String()), &decoded))
+
+	t.Equal(4, decoded.Tests)
+	t.Equal(1, decoded.Failures)
+	t.Equal(2, decoded.Errors)
+	t.Require().Len(decoded.Suites, 2)
+
+	cases := decoded.Suites[0].Cases
+	t.Require().Len(cases, 3)
+	t.Equal(junitCase{Name: "test_ok", ClassName: "a_test.fg", Time: "0.000"}, cases[0])
+	t.Equal(&junitProblem{
+		Message: "values are not equal",
+		Type:    object.ASSERTION_ERROR,
+		Text:    "AssertionError: values are not equal\n  actual:   1\n  expected: 2\n  at assert_eq (builtin)\n  at test_eq (2:20)",
+	}, cases[1].Failure)
+	t.Nil(cases[1].Error)
+	t.Equal("RuntimeError", cases[2].Error.Type)
+
+	t.Equal("b_test.fg", decoded.Suites[1].Cases[0].Name)
+	t.Equal("file does not exist", decoded.Suites[1].Cases[0].Error.Message)
+}
@@ -1,15 +1,20 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type    TokenType `json:"type"`
+	Literal string    `json:"literal"`
+	Line    int       `json:"line"`   // 1-based line of the token's first character
+	Column  int       `json:"column"` // 1-based column of the token's first character
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT" // never returned by the lexer's NextToken, see Lexer.Comments
 
 	IDENT = "IDENT"
 	INT   = "INT"
@@ -22,6 +27,8 @@ const (
 	SLASH    = "/"
 	EQ       = "=="
 	NOT_EQ   = "!="
+	PIPE     = "|>"
+	ARROW    = "=>"
 
 	LT = "<"
 	GT = ">"
@@ -29,6 +36,8 @@ const (
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
+	ELLIPSIS  = "..."
+	DOT       = "."
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -45,18 +54,65 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	AS       = "AS"
+	STRUCT   = "STRUCT"
+	ENUM     = "ENUM"
+	MATCH    = "MATCH"
+	FOR      = "FOR"
+	IN       = "IN"
+	YIELD    = "YIELD"
+	SPAWN    = "SPAWN"
+	SELECT   = "SELECT"
+	ASYNC    = "ASYNC"
+	AWAIT    = "AWAIT"
+	MACRO    = "MACRO"
 
 	STRING = "STRING"
 )
 
 var keywords = map[string]TokenType{
-	"true":   TRUE,
-	"false":  FALSE,
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"true":    TRUE,
+	"false":   FALSE,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"throw":   THROW,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"import":  IMPORT,
+	"export":  EXPORT,
+	"as":      AS,
+	"struct":  STRUCT,
+	"enum":    ENUM,
+	"match":   MATCH,
+	"for":     FOR,
+	"in":      IN,
+	"yield":   YIELD,
+	"spawn":   SPAWN,
+	"select":  SELECT,
+	"async":   ASYNC,
+	"await":   AWAIT,
+	"macro":   MACRO,
+}
+
+// The words the lexer reads as keywords, sorted
+func Keywords() []string {
+	words := []string{}
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	return words
 }
 
 func LookupIdent(ident string) TokenType {